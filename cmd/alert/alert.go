@@ -0,0 +1,167 @@
+// Package alert evaluates alert rules against captured log entries and
+// delivers the resulting notifications.
+package alert
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+const (
+	deliveryTimeout = 30 * time.Second
+	queueSize       = 64
+)
+
+// Alert is a notification produced by a rule.
+type Alert struct {
+	Rule       string           `json:"rule"`
+	Type       string           `json:"type"`
+	Title      string           `json:"title"`
+	Message    string           `json:"message"`
+	Count      int              `json:"count"`      // Entries that triggered the alert
+	Suppressed int              `json:"suppressed"` // Identical alerts held back by the cooldown since the last one
+	Time       time.Time        `json:"time"`
+	Entry      *store.Entry     `json:"entry,omitempty"` // The entry that triggered the alert
+	Webhooks   []config.Webhook `json:"-"`               // Where this alert is sent
+}
+
+// Notifier delivers alerts somewhere a developer will see them.
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// Engine evaluates rules against entries as they are stored. Notifications
+// are delivered in the background so a slow webhook never holds up ingest.
+type Engine struct {
+	rules     []*rule
+	webhooks  []config.Webhook
+	notifiers []Notifier
+
+	mu         sync.Mutex
+	known      map[string]bool      // Error fingerprints already seen
+	lastSent   map[string]time.Time // Last notification per rule and dedup key
+	suppressed map[string]int       // Notifications held back per rule and dedup key
+
+	queue chan Alert
+	done  chan struct{}
+	now   func() time.Time
+}
+
+// NewEngine compiles the rules in cfg. Alerts are sent to every notifier.
+func NewEngine(cfg config.Alerts, notifiers ...Notifier) (*Engine, error) {
+	rules, err := compileRules(cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Engine{
+		rules:      rules,
+		webhooks:   cfg.Webhooks,
+		notifiers:  notifiers,
+		known:      map[string]bool{},
+		lastSent:   map[string]time.Time{},
+		suppressed: map[string]int{},
+		queue:      make(chan Alert, queueSize),
+		done:       make(chan struct{}),
+		now:        time.Now,
+	}, nil
+}
+
+// Rules returns the number of configured rules.
+func (e *Engine) Rules() int {
+	return len(e.rules)
+}
+
+// LoadFingerprints records the error fingerprints already in db, so only
+// errors never seen before trigger new_error rules.
+func (e *Engine) LoadFingerprints(ctx context.Context, db store.Store) error {
+	groups, err := db.ErrorGroups(ctx, store.Filter{})
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, g := range groups {
+		e.known[g.Fingerprint] = true
+	}
+	return nil
+}
+
+// Observe evaluates every rule against a batch of stored entries. It is
+// meant to be used as ingest.Pipeline.OnInsert.
+func (e *Engine) Observe(entries []store.Entry) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	for i := range entries {
+		entry := &entries[i]
+		newError := entry.Fingerprint != "" && !e.known[entry.Fingerprint]
+		if entry.Fingerprint != "" {
+			e.known[entry.Fingerprint] = true
+		}
+
+		for _, r := range e.rules {
+			a, key, ok := r.evaluate(entry, newError, now)
+			if !ok {
+				continue
+			}
+			e.fire(r, key, a, now)
+		}
+	}
+}
+
+// fire queues a, unless the rule already notified for key within its
+// cooldown, in which case the alert is counted and folded into the next one.
+func (e *Engine) fire(r *rule, key string, a Alert, now time.Time) {
+	id := r.Name + "\x00" + key
+	if last, ok := e.lastSent[id]; ok && now.Sub(last) < r.cooldown {
+		e.suppressed[id]++
+		return
+	}
+
+	e.lastSent[id] = now
+	a.Suppressed = e.suppressed[id]
+	delete(e.suppressed, id)
+	a.Rule = r.Name
+	a.Type = r.Type
+	a.Time = now
+	a.Webhooks = append(append([]config.Webhook{}, e.webhooks...), r.Webhooks...)
+
+	select {
+	case e.queue <- a:
+	default:
+		log.Printf("alert: dropped %q, too many pending notifications", a.Rule)
+	}
+}
+
+// Run delivers queued alerts until Close is called.
+func (e *Engine) Run() {
+	defer close(e.done)
+	for a := range e.queue {
+		e.deliver(a)
+	}
+}
+
+// Close stops accepting alerts and waits for pending ones to be delivered.
+// Observe must not be called after Close.
+func (e *Engine) Close() {
+	close(e.queue)
+	<-e.done
+}
+
+func (e *Engine) deliver(a Alert) {
+	for _, n := range e.notifiers {
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+		if err := n.Notify(ctx, a); err != nil {
+			log.Printf("alert: %s: %v", a.Rule, err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,294 @@
+package alert
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+var start = time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+// clock is a settable Engine.now.
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newTestEngine(t *testing.T, rules ...config.AlertRule) (*Engine, *clock) {
+	t.Helper()
+	e, err := NewEngine(config.Alerts{Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &clock{t: start}
+	e.now = c.now
+	return e, c
+}
+
+// fired drains the alerts queued so far.
+func fired(e *Engine) []Alert {
+	var alerts []Alert
+	for {
+		select {
+		case a := <-e.queue:
+			alerts = append(alerts, a)
+		default:
+			return alerts
+		}
+	}
+}
+
+func errorAt(ts time.Time, message string) store.Entry {
+	return store.Entry{Timestamp: ts, Level: flags.Error, Message: message}
+}
+
+func TestRateRule(t *testing.T) {
+	rule := config.AlertRule{Name: "errors", Type: RateRule, Threshold: 2, Window: "1m", Cooldown: "1ns"}
+
+	tests := []struct {
+		name    string
+		entries []store.Entry
+		want    []int // Count of each alert
+	}{
+		{
+			name:    "burst",
+			entries: []store.Entry{errorAt(start, "a"), errorAt(start.Add(10*time.Second), "b"), errorAt(start.Add(20*time.Second), "c")},
+			want:    []int{3},
+		},
+		{
+			name:    "spread out",
+			entries: []store.Entry{errorAt(start, "a"), errorAt(start.Add(40*time.Second), "b"), errorAt(start.Add(80*time.Second), "c"), errorAt(start.Add(120*time.Second), "d")},
+		},
+		{
+			name:    "window boundary is exclusive",
+			entries: []store.Entry{errorAt(start, "a"), errorAt(start.Add(30*time.Second), "b"), errorAt(start.Add(time.Minute), "c")},
+		},
+		{
+			name:    "out of order",
+			entries: []store.Entry{errorAt(start.Add(50*time.Second), "a"), errorAt(start, "b"), errorAt(start.Add(20*time.Second), "c")},
+			want:    []int{3},
+		},
+		{
+			name:    "older than the window",
+			entries: []store.Entry{errorAt(start.Add(time.Hour), "a"), errorAt(start.Add(time.Hour+time.Second), "b"), errorAt(start, "c")},
+		},
+		{
+			name: "below the level",
+			entries: []store.Entry{
+				errorAt(start, "a"),
+				{Timestamp: start.Add(time.Second), Level: flags.Warn, Message: "b"},
+				{Timestamp: start.Add(2 * time.Second), Level: flags.Info, Message: "c"},
+			},
+		},
+		{
+			name:    "keeps firing while above the threshold",
+			entries: []store.Entry{errorAt(start, "a"), errorAt(start.Add(time.Second), "b"), errorAt(start.Add(2*time.Second), "c"), errorAt(start.Add(3*time.Second), "d")},
+			want:    []int{3, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, c := newTestEngine(t, rule)
+			// Every entry is observed at the same moment, as when a file is
+			// imported: only the entries' timestamps tell them apart.
+			for _, entry := range tt.entries {
+				e.Observe([]store.Entry{entry})
+				c.t = c.t.Add(time.Millisecond)
+			}
+
+			var got []int
+			for _, a := range fired(e) {
+				got = append(got, a.Count)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("alerts with counts %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateRuleWithoutTimestamps(t *testing.T) {
+	e, c := newTestEngine(t, config.AlertRule{Name: "errors", Type: RateRule, Threshold: 1, Window: "1m"})
+
+	// Entries without a timestamp are counted when they are observed.
+	e.Observe([]store.Entry{{Level: flags.Error, Message: "a"}})
+	c.t = c.t.Add(2 * time.Minute)
+	e.Observe([]store.Entry{{Level: flags.Error, Message: "b"}})
+	if alerts := fired(e); len(alerts) != 0 {
+		t.Errorf("fired %d alerts for entries two minutes apart", len(alerts))
+	}
+
+	c.t = c.t.Add(time.Second)
+	e.Observe([]store.Entry{{Level: flags.Error, Message: "c"}})
+	if alerts := fired(e); len(alerts) != 1 || alerts[0].Count != 2 {
+		t.Errorf("alerts = %+v, want one for 2 entries", alerts)
+	}
+}
+
+func TestCooldown(t *testing.T) {
+	e, c := newTestEngine(t, config.AlertRule{Name: "panics", Type: PatternRule, Pattern: "panic", Cooldown: "1m"})
+
+	observe := func(after time.Duration, message string) []Alert {
+		c.t = start.Add(after)
+		e.Observe([]store.Entry{{Timestamp: c.t, Level: flags.Error, Message: message}})
+		return fired(e)
+	}
+
+	if a := observe(0, "panic: nil map 1"); len(a) != 1 || a[0].Suppressed != 0 || a[0].Rule != "panics" || !a[0].Time.Equal(start) {
+		t.Fatalf("first alert = %+v", a)
+	}
+	// The same error, once its number is normalized, is held back...
+	if a := observe(30*time.Second, "panic: nil map 2"); len(a) != 0 {
+		t.Errorf("alert within the cooldown: %+v", a)
+	}
+	if a := observe(40*time.Second, "panic: nil map 3"); len(a) != 0 {
+		t.Errorf("alert within the cooldown: %+v", a)
+	}
+	// ...while a different one isn't.
+	if a := observe(45*time.Second, "panic: index out of range"); len(a) != 1 {
+		t.Errorf("alerts for a different message = %+v, want 1", a)
+	}
+	// After the cooldown, the next alert reports what was held back.
+	a := observe(90*time.Second, "panic: nil map 4")
+	if len(a) != 1 || a[0].Suppressed != 2 || a[0].Message != "panic: nil map 4" {
+		t.Errorf("alert after the cooldown = %+v, want 2 suppressed", a)
+	}
+	if a := observe(100*time.Second, "panic: nil map 5"); len(a) != 0 {
+		t.Errorf("alert within the new cooldown: %+v", a)
+	}
+}
+
+func TestNewErrorRule(t *testing.T) {
+	db, err := store.Open("jotl.db", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []store.Entry{
+		errorAt(start, "db query 17 timed out"),
+		errorAt(start, "db query 18 timed out"),
+		errorAt(start, "cache miss for user 42"),
+		errorAt(start, "cache miss for user 43"),
+		errorAt(start, "config reloaded"),
+	}
+	for i := range entries {
+		entries[i].Fingerprint = store.Fingerprint(entries[i].Message)
+	}
+	// The first error was stored before the engine started.
+	if err := db.Insert(ctx, entries[:1], nil); err != nil {
+		t.Fatal(err)
+	}
+
+	e, c := newTestEngine(t, config.AlertRule{Name: "new", Type: NewErrorRule, Cooldown: "1ns"})
+	if err := e.LoadFingerprints(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	e.Observe(entries[1:])
+
+	var got []string
+	for _, a := range fired(e) {
+		got = append(got, a.Message)
+	}
+	if want := "cache miss for user 42,config reloaded"; strings.Join(got, ",") != want {
+		t.Errorf("alerts for %q, want %q", got, want)
+	}
+
+	// Known errors stay known after any cooldown.
+	c.t = c.t.Add(time.Hour)
+	e.Observe(entries)
+	if a := fired(e); len(a) != 0 {
+		t.Errorf("alerts for known errors: %+v", a)
+	}
+
+	// Entries without a fingerprint never count as new errors.
+	e.Observe([]store.Entry{{Timestamp: start, Level: flags.Warn, Message: "slow"}})
+	if a := fired(e); len(a) != 0 {
+		t.Errorf("alerts for an entry without a fingerprint: %+v", a)
+	}
+}
+
+func TestCompileRules(t *testing.T) {
+	tests := []struct {
+		rule config.AlertRule
+		err  string
+	}{
+		{config.AlertRule{Type: PatternRule, Pattern: "x"}, "name is required"},
+		{config.AlertRule{Name: "r", Type: "spike"}, "unknown type"},
+		{config.AlertRule{Name: "r", Type: PatternRule}, "need a pattern"},
+		{config.AlertRule{Name: "r", Type: PatternRule, Pattern: "("}, "invalid pattern"},
+		{config.AlertRule{Name: "r", Type: RateRule, Window: "1m"}, "threshold"},
+		{config.AlertRule{Name: "r", Type: RateRule, Threshold: 1}, "need a window"},
+		{config.AlertRule{Name: "r", Type: RateRule, Threshold: 1, Window: "-1m"}, "invalid window"},
+		{config.AlertRule{Name: "r", Type: NewErrorRule, Cooldown: "soon"}, "invalid cooldown"},
+		{config.AlertRule{Name: "r", Type: NewErrorRule, Level: "loud"}, "invalid log level"},
+	}
+	for _, tt := range tests {
+		_, err := compileRules([]config.AlertRule{tt.rule})
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("compileRules(%+v) = %v, want an error containing %q", tt.rule, err, tt.err)
+		}
+	}
+
+	_, err := compileRules([]config.AlertRule{
+		{Name: "r", Type: NewErrorRule},
+		{Name: "r", Type: NewErrorRule},
+	})
+	if err == nil || !strings.Contains(err.Error(), "duplicate rule name") {
+		t.Errorf("compileRules with a duplicate name = %v", err)
+	}
+}
+
+// recorder is a Notifier that keeps the alerts it gets.
+type recorder struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *recorder) Notify(ctx context.Context, a Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, a)
+	return nil
+}
+
+func TestEngineDelivers(t *testing.T) {
+	webhook := config.Webhook{URL: "https://example.com/all"}
+	ruleHook := config.Webhook{URL: "https://example.com/rule"}
+	n1, n2 := &recorder{}, &recorder{}
+	e, err := NewEngine(config.Alerts{
+		Webhooks: []config.Webhook{webhook},
+		Rules:    []config.AlertRule{{Name: "panics", Type: PatternRule, Pattern: "panic", Webhooks: []config.Webhook{ruleHook}}},
+	}, n1, n2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go e.Run()
+
+	e.Observe([]store.Entry{{Level: flags.Error, Message: "panic: boom\ngoroutine 1 [running]:"}})
+	e.Close()
+
+	for _, n := range []*recorder{n1, n2} {
+		if len(n.alerts) != 1 {
+			t.Fatalf("notifier got %d alerts, want 1", len(n.alerts))
+		}
+		a := n.alerts[0]
+		if a.Message != "panic: boom" || a.Type != PatternRule || len(a.Webhooks) != 2 || a.Webhooks[0].URL != webhook.URL || a.Webhooks[1].URL != ruleHook.URL {
+			t.Errorf("alert = %+v", a)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+// Rule types.
+const (
+	RateRule     = "rate"      // More than Threshold matching entries within Window
+	PatternRule  = "pattern"   // Any message matching Pattern
+	NewErrorRule = "new_error" // An error whose fingerprint was never seen before
+)
+
+// DefaultCooldown is the minimum time between two notifications of a rule
+// for the same error.
+const DefaultCooldown = 5 * time.Minute
+
+// rule is a compiled config.AlertRule.
+type rule struct {
+	config.AlertRule
+	pattern  *regexp.Regexp
+	level    flags.LogLevel
+	window   time.Duration
+	cooldown time.Duration
+	hits     []time.Time // Timestamps of matching entries within window, oldest first, for rate rules
+}
+
+func compileRules(rules []config.AlertRule) ([]*rule, error) {
+	var compiled []*rule
+	var errs []error
+	names := map[string]bool{}
+
+	for i, r := range rules {
+		c, err := compileRule(r)
+		if err == nil && names[r.Name] {
+			err = fmt.Errorf("duplicate rule name")
+		}
+		if err != nil {
+			name := r.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			errs = append(errs, fmt.Errorf("alert rule %s: %w", name, err))
+			continue
+		}
+		names[r.Name] = true
+		compiled = append(compiled, c)
+	}
+	return compiled, errors.Join(errs...)
+}
+
+func compileRule(r config.AlertRule) (*rule, error) {
+	c := &rule{AlertRule: r, cooldown: DefaultCooldown}
+	if r.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	var err error
+	if r.Pattern != "" {
+		if c.pattern, err = regexp.Compile(r.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+	if r.Level != "" {
+		if err := c.level.Set(string(r.Level)); err != nil {
+			return nil, err
+		}
+	}
+	if r.Cooldown != "" {
+		if c.cooldown, err = time.ParseDuration(r.Cooldown); err != nil {
+			return nil, fmt.Errorf("invalid cooldown: %w", err)
+		}
+	}
+
+	switch r.Type {
+	case RateRule:
+		if r.Threshold < 1 {
+			return nil, errors.New("rate rules need a threshold of at least 1")
+		}
+		if r.Window == "" {
+			return nil, errors.New("rate rules need a window, e.g. 1m")
+		}
+		if c.window, err = time.ParseDuration(r.Window); err != nil || c.window <= 0 {
+			return nil, fmt.Errorf("invalid window %q", r.Window)
+		}
+		if c.level == "" {
+			c.level = flags.Error
+		}
+	case PatternRule:
+		if c.pattern == nil {
+			return nil, errors.New("pattern rules need a pattern")
+		}
+	case NewErrorRule:
+	default:
+		return nil, fmt.Errorf("unknown type %q (want %s, %s or %s)", r.Type, RateRule, PatternRule, NewErrorRule)
+	}
+	return c, nil
+}
+
+// evaluate checks one entry against the rule. It returns the alert to send
+// and the key alerts are deduplicated by.
+func (r *rule) evaluate(e *store.Entry, newError bool, now time.Time) (Alert, string, bool) {
+	if r.Source != "" && e.Source != r.Source {
+		return Alert{}, "", false
+	}
+	if r.level != "" && !e.Level.AtLeast(r.level) {
+		return Alert{}, "", false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(e.Message) {
+		return Alert{}, "", false
+	}
+
+	switch r.Type {
+	case RateRule:
+		// Windows follow the entries' own timestamps, so imported or
+		// replayed logs are counted as they happened rather than all at
+		// once. The window ends at the newest entry seen.
+		at := e.Timestamp
+		if at.IsZero() {
+			at = now
+		}
+		i, _ := slices.BinarySearchFunc(r.hits, at, time.Time.Compare)
+		r.hits = slices.Insert(r.hits, i, at)
+		cutoff := r.hits[len(r.hits)-1].Add(-r.window)
+		drop := 0
+		for drop < len(r.hits) && !r.hits[drop].After(cutoff) {
+			drop++
+		}
+		r.hits = r.hits[drop:]
+		if len(r.hits) <= r.Threshold {
+			return Alert{}, "", false
+		}
+		return Alert{
+			Title:   fmt.Sprintf("%d %s entries in the last %s (threshold %d)", len(r.hits), r.level, r.window, r.Threshold),
+			Message: firstLine(e.Message),
+			Count:   len(r.hits),
+			Entry:   entryCopy(e),
+		}, "", true
+
+	case PatternRule:
+		return Alert{
+			Title:   fmt.Sprintf("Log line matched %s", r.Pattern),
+			Message: firstLine(e.Message),
+			Count:   1,
+			Entry:   entryCopy(e),
+		}, store.Fingerprint(e.Message), true
+
+	case NewErrorRule:
+		if !newError {
+			return Alert{}, "", false
+		}
+		return Alert{
+			Title:   "New error",
+			Message: firstLine(e.Message),
+			Count:   1,
+			Entry:   entryCopy(e),
+		}, e.Fingerprint, true
+	}
+	return Alert{}, "", false
+}
+
+func entryCopy(e *store.Entry) *store.Entry {
+	c := *e
+	return &c
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// Sample returns the alert `jotl alerts test` sends for the rule called
+// name, addressed to the same webhooks as a real one.
+func Sample(cfg config.Alerts, name string) (Alert, error) {
+	for _, r := range cfg.Rules {
+		if r.Name != name {
+			continue
+		}
+		c, err := compileRule(r)
+		if err != nil {
+			return Alert{}, fmt.Errorf("alert rule %s: %w", name, err)
+		}
+		return Alert{
+			Rule:     c.Name,
+			Type:     c.Type,
+			Title:    "Test notification",
+			Message:  fmt.Sprintf("This is a test of the %q alert rule (%s).", c.Name, c.describe()),
+			Count:    1,
+			Time:     time.Now(),
+			Webhooks: append(append([]config.Webhook{}, cfg.Webhooks...), c.Webhooks...),
+		}, nil
+	}
+	return Alert{}, fmt.Errorf("no alert rule named %q", name)
+}
+
+// describe summarizes when the rule fires.
+func (r *rule) describe() string {
+	switch r.Type {
+	case RateRule:
+		return fmt.Sprintf("more than %d %s entries within %s", r.Threshold, r.level, r.window)
+	case PatternRule:
+		return fmt.Sprintf("messages matching %s", r.Pattern)
+	default:
+		return "errors never seen before"
+	}
+}
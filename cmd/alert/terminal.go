@@ -0,0 +1,51 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	bannerStyle  = lipgloss.NewStyle().Background(lipgloss.Color("#FF5F87")).Foreground(lipgloss.Color("#030303")).Bold(true).Padding(0, 1, 0)
+	titleStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5F87")).Bold(true)
+	messageStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FAFAFA"))
+	detailStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#8A8A8A"))
+)
+
+// Terminal prints alerts as a banner and rings the terminal bell, so a crash
+// stands out in a busy terminal.
+type Terminal struct {
+	Out    io.Writer
+	Silent bool // Don't ring the bell
+
+	mu sync.Mutex
+}
+
+func (t *Terminal) Notify(ctx context.Context, a Alert) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.Silent {
+		fmt.Fprint(t.Out, "\a")
+	}
+
+	detail := a.Time.Format("15:04:05")
+	if a.Entry != nil && a.Entry.Source != "" {
+		detail += " · " + a.Entry.Source
+	}
+	if a.Suppressed > 0 {
+		detail += fmt.Sprintf(" · %d similar alerts suppressed", a.Suppressed)
+	}
+
+	_, err := fmt.Fprintf(t.Out, "\n%s %s\n%s\n%s\n\n",
+		bannerStyle.Render("ALERT "+a.Rule),
+		titleStyle.Render(a.Title),
+		messageStyle.Render(a.Message),
+		detailStyle.Render(detail),
+	)
+	return err
+}
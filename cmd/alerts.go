@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/ebarthur/jotl/cmd/alert"
+	"github.com/ebarthur/jotl/cmd/notify"
+	"github.com/spf13/cobra"
+)
+
+const alertsMsg = (`The alerts command inspects and tests the alert rules in jotl/config.yaml.
+
+Rules are evaluated by ` + "`jotl dev`" + ` as logs arrive. There are three types:
+- ` + "`rate`" + `: more than ` + "`threshold`" + ` entries at ` + "`level`" + ` (default error) or above within ` + "`window`" + `
+- ` + "`pattern`" + `: any message matching the regular expression ` + "`pattern`" + `
+- ` + "`new_error`" + `: an error whose fingerprint was never seen before
+
+Webhooks are listed under ` + "`alerts.webhooks`" + ` (every rule) or on a rule, either as a URL or as:
+
+` + "```yaml" + `
+webhooks:
+  - url: https://hooks.slack.com/services/...
+  - url: https://example.com/hooks/jotl
+    headers: { Authorization: "Bearer secret" }
+    template: '{"text": {{json .Title}}, "detail": {{json .Message}}}'
+` + "```" + `
+
+Use ` + "`jotl alerts test <rule>`" + ` to send a sample notification through every channel of a rule.`)
+
+var alertsCommand = &cobra.Command{
+	Use:   "alerts",
+	Short: "List and test alert rules",
+	Long: func() string {
+		out, _ := glamour.Render(alertsMsg, "dark")
+		return out
+	}(),
+}
+
+var alertsListCommand = &cobra.Command{
+	Use:   "list",
+	Short: "List the configured alert rules",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, _, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		_, err = alert.NewEngine(cfg.Alerts)
+		cobra.CheckErr(err)
+
+		if len(cfg.Alerts.Rules) == 0 {
+			fmt.Println(tipMsgStyle.Render("No alert rules configured. Add them under `alerts.rules` in jotl/config.yaml."))
+			return
+		}
+		for _, r := range cfg.Alerts.Rules {
+			fmt.Printf("%-20s %-10s %d webhook(s)\n", r.Name, r.Type, len(cfg.Alerts.Webhooks)+len(r.Webhooks))
+		}
+	},
+}
+
+var alertsTestCommand = &cobra.Command{
+	Use:   "test <rule>",
+	Short: "Send a sample notification for an alert rule",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, _, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		a, err := alert.Sample(cfg.Alerts, args[0])
+		cobra.CheckErr(err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		cobra.CheckErr((&alert.Terminal{Out: os.Stdout, Silent: cfg.Alerts.Silent}).Notify(ctx, a))
+		if len(a.Webhooks) == 0 {
+			fmt.Println(tipMsgStyle.Render("The rule has no webhooks; only the terminal was notified."))
+			return
+		}
+
+		// Send directly rather than through the outbox, which would also
+		// deliver the real alerts waiting there.
+		delivered, err := notify.NewSender(nil).Test(ctx, a)
+		for _, w := range a.Webhooks {
+			fmt.Printf("%s (%s)\n", notify.Host(w.URL), notify.Format(w))
+		}
+		if err != nil {
+			cobra.CheckErr(fmt.Errorf("some test notifications were not delivered:\n%w", err))
+		}
+		fmt.Println(endingMsgStyle.Render(fmt.Sprintf("Delivered %d test notification(s).", delivered)))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(alertsCommand)
+	alertsCommand.AddCommand(alertsListCommand)
+	alertsCommand.AddCommand(alertsTestCommand)
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/ebarthur/jotl/cmd/bundle"
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/spf13/cobra"
+)
+
+const bundleMsg = (`The bundle command packs the logs of one run into a single file you can share,
+e.g. attached to a bug report.
+
+A bundle is a ` + "`.tar.zst`" + ` archive with the run's logs (NDJSON), its error groups, the run
+metadata, and a copy of jotl/config.yaml with passwords, tokens and webhook URLs redacted.
+
+Every ` + "`jotl dev`" + `, ` + "`jotl ingest`" + ` and ` + "`jotl import`" + ` is a run; list them with ` + "`jotl runs`" + `.
+
+Examples:
+jotl bundle create --run latest
+jotl bundle create --run 20261018-153012-3f9a -o checkout-bug.tar.zst
+
+Whoever receives the bundle opens it read-only in the studio:
+jotl studio --bundle checkout-bug.tar.zst`)
+
+var (
+	bundleRun    string
+	bundleOutput string
+)
+
+var bundleCommand = &cobra.Command{
+	Use:   "bundle",
+	Short: "Share the logs of a run as a self-contained archive",
+	Long: func() string {
+		out, _ := glamour.Render(bundleMsg, "dark")
+		return out
+	}(),
+}
+
+var bundleCreateCommand = &cobra.Command{
+	Use:   "create",
+	Short: "Create a bundle of a run",
+	Args:  cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		_, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		db, err := openStore(ctx, paths, cfg)
+		cobra.CheckErr(err)
+		defer db.Close()
+
+		run, err := findRun(ctx, db, bundleRun)
+		cobra.CheckErr(err)
+
+		// The bundle carries jotl/config.yaml as written, not the config
+		// resolved with the environment, .env and profile layers.
+		fileCfg, err := config.LoadConfig(paths.ConfigFile)
+		cobra.CheckErr(err)
+
+		output := bundleOutput
+		if output == "" {
+			output = "jotl-" + run.ID + bundle.Extension
+		}
+		file, err := os.Create(output)
+		cobra.CheckErr(err)
+
+		m, err := bundle.Create(ctx, db, run, fileCfg, getJotlVersion(), file)
+		if err == nil {
+			err = file.Close()
+		} else {
+			file.Close()
+		}
+		if err != nil {
+			os.Remove(output)
+			cobra.CheckErr(err)
+		}
+
+		fmt.Println(endingMsgStyle.Render(fmt.Sprintf("Bundled %d entries and %d error groups of run %s into %s", m.Entries, m.ErrorGroups, run.ID, output)))
+		fmt.Println(tipMsgStyle.Render(fmt.Sprintf("Open it with `jotl studio --bundle %s`.", output)))
+	},
+}
+
+// findRun looks up a run by ID, or the most recent one for "latest".
+func findRun(ctx context.Context, db store.Store, id string) (store.Run, error) {
+	if id == "latest" {
+		runs, err := db.Runs(ctx, 1)
+		if err != nil {
+			return store.Run{}, err
+		}
+		if len(runs) == 0 {
+			return store.Run{}, store.ErrNoRuns
+		}
+		return runs[0], nil
+	}
+
+	run, found, err := db.Run(ctx, id)
+	if err != nil {
+		return store.Run{}, err
+	}
+	if !found {
+		return store.Run{}, fmt.Errorf("no run %q. List runs with `jotl runs`", id)
+	}
+	return run, nil
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCommand)
+	bundleCommand.AddCommand(bundleCreateCommand)
+	bundleCreateCommand.Flags().StringVar(&bundleRun, "run", "", "ID of the run to bundle, or latest")
+	bundleCreateCommand.Flags().StringVarP(&bundleOutput, "output", "o", "", "File to write (default jotl-<run>"+bundle.Extension+")")
+	bundleCreateCommand.MarkFlagRequired("run")
+}
@@ -0,0 +1,159 @@
+// Package bundle packs the logs of one run into a self-contained archive
+// that can be shared and opened read-only in the studio.
+//
+// A bundle is a zstd-compressed tar file holding:
+//
+//	manifest.json  bundle metadata and the run
+//	logs.ndjson    the run's entries, oldest first
+//	errors.json    the run's error groups
+//	config.yaml    the project configuration with secrets redacted
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/export"
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/klauspost/compress/zstd"
+	"gopkg.in/yaml.v3"
+)
+
+// FormatVersion is the version of the bundle layout.
+const FormatVersion = 1
+
+// Extension is the file extension of bundles.
+const Extension = ".tar.zst"
+
+const (
+	manifestFile = "manifest.json"
+	logsFile     = "logs.ndjson"
+	errorsFile   = "errors.json"
+	configFile   = "config.yaml"
+)
+
+// Manifest describes a bundle.
+type Manifest struct {
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	JotlVersion string    `json:"jotl_version,omitempty"`
+	Project     string    `json:"project"`
+	Run         store.Run `json:"run"`
+	Entries     int       `json:"entries"`
+	ErrorGroups int       `json:"error_groups"`
+}
+
+// Create writes a bundle of run to w. cfg is the project's config file as
+// written; it is stored with its secrets redacted.
+func Create(ctx context.Context, db store.Store, run store.Run, cfg *config.JotlConfig, jotlVersion string, w io.Writer) (Manifest, error) {
+	m := Manifest{
+		Version:     FormatVersion,
+		CreatedAt:   time.Now().UTC(),
+		JotlVersion: jotlVersion,
+		Project:     cfg.Project.Name,
+		Run:         run,
+	}
+	f := store.Filter{RunID: run.ID}
+
+	// Tar headers need the size up front, so logs are staged in a temporary
+	// file rather than held in memory.
+	logs, err := os.CreateTemp("", "jotl-bundle-*.ndjson")
+	if err != nil {
+		return m, err
+	}
+	defer os.Remove(logs.Name())
+	defer logs.Close()
+
+	buf := bufio.NewWriter(logs)
+	lw, err := export.NewWriter(buf, export.NDJSON, export.None)
+	if err != nil {
+		return m, err
+	}
+	if m.Entries, err = export.Export(ctx, db, f, lw); err != nil {
+		return m, fmt.Errorf("failed to read logs of run %s: %w", run.ID, err)
+	}
+	if err := buf.Flush(); err != nil {
+		return m, err
+	}
+
+	groups, err := db.ErrorGroups(ctx, f)
+	if err != nil {
+		return m, err
+	}
+	if groups == nil {
+		groups = []store.ErrorGroup{}
+	}
+	m.ErrorGroups = len(groups)
+	errorsJSON, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return m, err
+	}
+
+	configYAML, err := yaml.Marshal(Redact(cfg))
+	if err != nil {
+		return m, err
+	}
+	manifestJSON, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return m, err
+	}
+
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return m, err
+	}
+	tw := tar.NewWriter(zw)
+
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{manifestFile, manifestJSON},
+		{errorsFile, errorsJSON},
+		{configFile, configYAML},
+	} {
+		if err := writeFile(tw, file.name, int64(len(file.data)), m.CreatedAt, bytes.NewReader(file.data)); err != nil {
+			return m, err
+		}
+	}
+
+	size, err := logs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return m, err
+	}
+	if _, err := logs.Seek(0, io.SeekStart); err != nil {
+		return m, err
+	}
+	if err := writeFile(tw, logsFile, size, m.CreatedAt, logs); err != nil {
+		return m, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return m, err
+	}
+	return m, zw.Close()
+}
+
+func writeFile(tw *tar.Writer, name string, size int64, modTime time.Time, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: modTime,
+		Format:  tar.FormatPAX,
+	}); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(tw, r, size); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/klauspost/compress/zstd"
+)
+
+const loadBatchSize = 1000
+
+// Bundle is an opened bundle, loaded into a temporary database.
+type Bundle struct {
+	Manifest Manifest
+	Config   []byte // The redacted config.yaml
+	Store    store.Store
+
+	dir string
+}
+
+// Open unpacks the bundle at path into a temporary SQLite database. Call
+// Close to remove it.
+func Open(ctx context.Context, path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a jotl bundle: %w", path, err)
+	}
+	defer zr.Close()
+
+	dir, err := os.MkdirTemp("", "jotl-bundle-*")
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{dir: dir}
+
+	db, err := store.Open("file:"+filepath.Join(dir, "bundle.db"), dir)
+	if err == nil {
+		b.Store = db
+		err = db.Migrate(ctx)
+	}
+	if err == nil {
+		err = b.load(ctx, tar.NewReader(zr))
+	}
+	if err != nil {
+		b.Close()
+		return nil, fmt.Errorf("failed to open bundle %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// load reads the archive into b.
+func (b *Bundle) load(ctx context.Context, tr *tar.Reader) error {
+	var haveManifest, haveLogs bool
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Name {
+		case manifestFile:
+			if err := json.NewDecoder(tr).Decode(&b.Manifest); err != nil {
+				return fmt.Errorf("invalid %s: %w", manifestFile, err)
+			}
+			if b.Manifest.Version > FormatVersion {
+				return fmt.Errorf("bundle format %d is newer than this jotl supports (%d); upgrade jotl", b.Manifest.Version, FormatVersion)
+			}
+			if b.Manifest.Run.ID != "" {
+				if err := b.Store.StartRun(ctx, b.Manifest.Run); err != nil {
+					return err
+				}
+			}
+			haveManifest = true
+		case configFile:
+			if b.Config, err = io.ReadAll(tr); err != nil {
+				return err
+			}
+		case logsFile:
+			if err := b.loadLogs(ctx, tr); err != nil {
+				return fmt.Errorf("invalid %s: %w", logsFile, err)
+			}
+			haveLogs = true
+		}
+	}
+
+	if !haveManifest || !haveLogs {
+		return fmt.Errorf("not a jotl bundle: missing %s or %s", manifestFile, logsFile)
+	}
+	return nil
+}
+
+func (b *Bundle) loadLogs(ctx context.Context, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+
+	batch := make([]store.Entry, 0, loadBatchSize)
+	for line := 1; scanner.Scan(); line++ {
+		var e store.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		batch = append(batch, e)
+		if len(batch) == loadBatchSize {
+			if err := b.Store.Insert(ctx, batch, nil); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return b.Store.Insert(ctx, batch, nil)
+}
+
+// Close removes the temporary database.
+func (b *Bundle) Close() error {
+	var err error
+	if b.Store != nil {
+		err = b.Store.Close()
+	}
+	return errors.Join(err, os.RemoveAll(b.dir))
+}
@@ -0,0 +1,89 @@
+package bundle
+
+import (
+	"net/url"
+
+	"github.com/ebarthur/jotl/cmd/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces secrets in a redacted configuration.
+const Redacted = "REDACTED"
+
+// Redact returns a copy of cfg without secrets: database passwords, the
+// ingest token, and webhook URLs and headers (webhook URLs usually embed
+// their credentials), in the base configuration and every profile.
+func Redact(cfg *config.JotlConfig) *config.JotlConfig {
+	// Round-trip through YAML for a deep copy.
+	data, _ := yaml.Marshal(cfg)
+	c := &config.JotlConfig{}
+	yaml.Unmarshal(data, c)
+
+	redactSettings(&c.Database, &c.Sources, &c.Alerts)
+	for _, p := range c.Profiles {
+		if p != nil {
+			redactSettings(p.Database, p.Sources, p.Alerts)
+		}
+	}
+	return c
+}
+
+// redactSettings removes the secrets from the sections of the base
+// configuration or a profile; sections a profile doesn't set are nil.
+func redactSettings(db *config.Database, sources *config.Sources, alerts *config.Alerts) {
+	if db != nil {
+		db.Path = redactDSN(db.Path)
+	}
+	if sources != nil && sources.HTTP.Token != "" {
+		sources.HTTP.Token = Redacted
+	}
+	if alerts != nil {
+		redactWebhooks(alerts.Webhooks)
+		for i := range alerts.Rules {
+			redactWebhooks(alerts.Rules[i].Webhooks)
+		}
+	}
+}
+
+// redactDSN replaces the password in a connection URL, whether it is in the
+// userinfo or in the query.
+func redactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return dsn
+	}
+	redacted := false
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), Redacted)
+			redacted = true
+		}
+	}
+	q := u.Query()
+	for _, key := range []string{"password", "sslkey", "sslpassword"} {
+		if q.Has(key) {
+			q.Set(key, Redacted)
+			redacted = true
+		}
+	}
+	if !redacted {
+		// Leave paths and password-less URLs as written.
+		return dsn
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func redactWebhooks(webhooks []config.Webhook) {
+	for i := range webhooks {
+		w := &webhooks[i]
+		if u, err := url.Parse(w.URL); err == nil && u.Host != "" {
+			w.URL = u.Scheme + "://" + u.Host + "/" + Redacted
+		} else {
+			w.URL = Redacted
+		}
+		for k := range w.Headers {
+			w.Headers[k] = Redacted
+		}
+	}
+}
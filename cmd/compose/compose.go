@@ -0,0 +1,216 @@
+// Package compose drives the docker compose file that runs the PostgreSQL
+// container of a Jotl project.
+package compose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/config"
+)
+
+// Compose runs docker compose against a project's compose file.
+type Compose struct {
+	config.ComposeService
+	Dir     string   // Project directory
+	command []string // docker compose or docker-compose
+}
+
+// New finds the compose file of the project in dir and the docker compose
+// command to drive it with.
+func New(dir string) (*Compose, error) {
+	cs, err := config.LocateCompose(dir)
+	if err != nil {
+		return nil, err
+	}
+	command, err := findCommand()
+	if err != nil {
+		return nil, err
+	}
+	return &Compose{ComposeService: cs, Dir: dir, command: command}, nil
+}
+
+// ErrNoDocker is returned by New when neither docker compose nor
+// docker-compose is installed.
+var ErrNoDocker = errors.New("neither `docker compose` nor `docker-compose` was found; install Docker to run PostgreSQL")
+
+// findCommand prefers the compose plugin of docker over the standalone
+// docker-compose.
+func findCommand() ([]string, error) {
+	if _, err := exec.LookPath("docker"); err == nil {
+		if exec.Command("docker", "compose", "version").Run() == nil {
+			return []string{"docker", "compose"}, nil
+		}
+	}
+	if _, err := exec.LookPath("docker-compose"); err == nil {
+		return []string{"docker-compose"}, nil
+	}
+	return nil, ErrNoDocker
+}
+
+// Command returns the command line that runs docker compose with args.
+func (c *Compose) Command(args ...string) string {
+	return strings.Join(append(append(append([]string{}, c.command...), "-f", c.File), args...), " ")
+}
+
+func (c *Compose) cmd(ctx context.Context, args ...string) *exec.Cmd {
+	args = append(append(append([]string{}, c.command[1:]...), "-f", c.File), args...)
+	cmd := exec.CommandContext(ctx, c.command[0], args...)
+	cmd.Dir = c.Dir
+	return cmd
+}
+
+// run runs docker compose with args, passing its output through to out.
+func (c *Compose) run(ctx context.Context, out io.Writer, args ...string) error {
+	cmd := c.cmd(ctx, args...)
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", c.Command(args...), err)
+	}
+	return nil
+}
+
+// Up starts the database containers in the background.
+func (c *Compose) Up(ctx context.Context, out io.Writer) error {
+	return c.run(ctx, out, append([]string{"up", "-d"}, c.Services...)...)
+}
+
+// Down stops and removes the database containers. Their volumes, and so
+// the data, are kept.
+func (c *Compose) Down(ctx context.Context, out io.Writer) error {
+	if c.Services == nil {
+		return c.run(ctx, out, "down")
+	}
+	// Leave the other services of the project's compose file alone.
+	return c.run(ctx, out, append([]string{"rm", "--stop", "--force"}, c.Services...)...)
+}
+
+// Logs prints the logs of the PostgreSQL container, following them until
+// ctx is done if follow is set.
+func (c *Compose) Logs(ctx context.Context, out io.Writer, follow bool, tail string) error {
+	args := []string{"logs", "--tail", tail}
+	if follow {
+		args = append(args, "--follow")
+	}
+	err := c.run(ctx, out, append(args, c.Service)...)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// Container is the state of a service as reported by docker compose ps.
+type Container struct {
+	Name       string      `json:"Name"`
+	Service    string      `json:"Service"`
+	State      string      `json:"State"`  // e.g. running or exited
+	Health     string      `json:"Health"` // healthy, unhealthy, starting, or empty without a healthcheck
+	Publishers []Publisher `json:"Publishers"`
+}
+
+// Publisher is a published port of a container.
+type Publisher struct {
+	URL           string `json:"URL"`
+	TargetPort    int    `json:"TargetPort"`
+	PublishedPort int    `json:"PublishedPort"`
+}
+
+// Ports lists the published ports as host:port->port.
+func (ct Container) Ports() string {
+	var ports []string
+	for _, p := range ct.Publishers {
+		if p.PublishedPort == 0 {
+			continue
+		}
+		port := fmt.Sprintf("%s:%d->%d", p.URL, p.PublishedPort, p.TargetPort)
+		if !slices.Contains(ports, port) {
+			ports = append(ports, port)
+		}
+	}
+	return strings.Join(ports, ", ")
+}
+
+// Status returns the containers of the database services. Services that
+// aren't created are left out.
+func (c *Compose) Status(ctx context.Context) ([]Container, error) {
+	args := []string{"ps", "--all", "--format", "json"}
+	var stderr bytes.Buffer
+	cmd := c.cmd(ctx, append(args, c.Services...)...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", c.Command(args...), err, strings.TrimSpace(stderr.String()))
+	}
+
+	// Older releases print an array, newer ones a container per line.
+	var containers []Container
+	out = bytes.TrimSpace(out)
+	if bytes.HasPrefix(out, []byte("[")) {
+		if err := json.Unmarshal(out, &containers); err != nil {
+			return nil, fmt.Errorf("failed to parse docker compose ps: %w", err)
+		}
+		return containers, nil
+	}
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var ct Container
+		if err := json.Unmarshal(line, &ct); err != nil {
+			return nil, fmt.Errorf("failed to parse docker compose ps: %w", err)
+		}
+		containers = append(containers, ct)
+	}
+	return containers, nil
+}
+
+// Postgres returns the container of the PostgreSQL service, if it exists.
+func (c *Compose) Postgres(ctx context.Context) (Container, bool, error) {
+	containers, err := c.Status(ctx)
+	if err != nil {
+		return Container{}, false, err
+	}
+	for _, ct := range containers {
+		if ct.Service == c.Service {
+			return ct, true, nil
+		}
+	}
+	return Container{}, false, nil
+}
+
+// WaitHealthy waits until the PostgreSQL container passes its healthcheck,
+// or just runs if it has none.
+func (c *Compose) WaitHealthy(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		ct, ok, err := c.Postgres(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			return err
+		case ok && ct.Health == "healthy", ok && ct.Health == "" && ct.State == "running":
+			return nil
+		case ok && ct.Health == "unhealthy":
+			return fmt.Errorf("%s is unhealthy; see `jotl db logs`", ct.Name)
+		case ok && ct.State != "running" && ct.State != "created" && ct.State != "restarting":
+			return fmt.Errorf("%s is %s; see `jotl db logs`", ct.Name, ct.State)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s did not become healthy within %s", c.Service, timeout)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/ebarthur/jotl/cmd/bundle"
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configDryRun      bool
+	configJSON        bool
+	configShowSecrets bool
+)
+
+var configCommand = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and maintain jotl/config.yaml",
+	Long: `The config command reads and changes jotl/config.yaml.
+
+Values are addressed by dotted paths of their YAML keys, with [N] for list
+elements and profile names as keys:
+
+  jotl config get logging.level
+  jotl config set dashboard.port 9090
+  jotl config set sources.files '["logs/*.log"]'
+  jotl config get alerts.rules[0].window
+  jotl config set profiles.ci.database.path file:./db/ci.db
+
+Values are checked against the field's type and the whole config is
+validated before it is saved. get, set, list and edit work on config.yaml
+itself; resolve shows the values after jotl/.env, JOTL_* environment
+variables and flags are applied.`,
+}
+
+var configGetCommand = &cobra.Command{
+	Use:   "get <path>",
+	Short: "Print a config value",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		_, cfg, err := loadProjectFile()
+		cobra.CheckErr(err)
+
+		value, err := cfg.Get(args[0])
+		cobra.CheckErr(err)
+		fmt.Println(config.FormatValue(value))
+	},
+}
+
+var configSetCommand = &cobra.Command{
+	Use:   "set <path> <value>",
+	Short: "Change a config value",
+	Args:  cobra.ExactArgs(2),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		paths, cfg, err := loadProjectFile()
+		cobra.CheckErr(err)
+
+		cobra.CheckErr(cfg.Set(args[0], args[1]))
+		cobra.CheckErr(cfg.Validate())
+		cobra.CheckErr(cfg.SaveConfig(paths.ConfigFile))
+
+		// jotl/.env overrides config.yaml, so keep the keys init wrote there
+		// in step.
+		if key, ok := config.DotEnvKey(args[0]); ok {
+			envPath := filepath.Join(paths.ConfigDir, ".env")
+			if _, err := os.Stat(envPath); err == nil {
+				cobra.CheckErr(config.UpdateDotEnv(envPath, map[string]string{key: args[1]}))
+			}
+		}
+
+		value, _ := cfg.Get(args[0])
+		fmt.Println(endingMsgStyle.Render(fmt.Sprintf("%s = %s", args[0], config.FormatValue(value))))
+	},
+}
+
+var configListCommand = &cobra.Command{
+	Use:   "list",
+	Short: "Print every config value with its path",
+	Args:  cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		_, cfg, err := loadProjectFile()
+		cobra.CheckErr(err)
+
+		for _, s := range cfg.Settings() {
+			fmt.Printf("%s = %s\n", s.Path, s.Value)
+		}
+	},
+}
+
+var configResolveCommand = &cobra.Command{
+	Use:   "resolve",
+	Short: "Print the effective config and where each value came from",
+	Long: `The resolve command prints the configuration commands actually use. Each
+value comes from the first of these that sets it:
+
+  1. flags, e.g. --set logging.level=debug or jotl studio --port
+  2. JOTL_* environment variables, e.g. JOTL_DATABASE_PATH for database.path
+  3. jotl/.env, with DB_CONNECTION_STRING for database.path, APP_NAME for
+     project.name and JOTL_* variables as above
+  4. the profile selected with --profile or JOTL_PROFILE
+  5. jotl/config.yaml
+  6. built-in defaults
+
+Secrets are masked unless --show-secrets is given.`,
+	Args: cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		_, _, resolved, err := resolveProject()
+		cobra.CheckErr(err)
+
+		cfg := resolved.Config
+		if !configShowSecrets {
+			cfg = bundle.Redact(cfg)
+		}
+
+		type resolvedSetting struct {
+			Path   string        `json:"path"`
+			Value  string        `json:"value"`
+			Origin config.Origin `json:"origin"`
+		}
+		var settings []resolvedSetting
+		for _, s := range cfg.Settings() {
+			settings = append(settings, resolvedSetting{s.Path, s.Value, resolved.Origin(s.Path)})
+		}
+
+		if configJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			cobra.CheckErr(enc.Encode(settings))
+			return
+		}
+
+		if resolved.Profile != "" {
+			fmt.Println(tipMsgStyle.Render(fmt.Sprintf("Profile: %s", resolved.Profile)))
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PATH\tVALUE\tSOURCE")
+		for _, s := range settings {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Path, s.Value, s.Origin)
+		}
+		w.Flush()
+	},
+}
+
+var configEditCommand = &cobra.Command{
+	Use:   "edit",
+	Short: "Open jotl/config.yaml in $EDITOR and validate the result",
+	Args:  cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		// The config may be invalid, which is why it is being edited.
+		currentDir, err := os.Getwd()
+		cobra.CheckErr(err)
+		paths := utils.GetConfigPaths(currentDir)
+		if _, err := os.Stat(paths.ConfigFile); os.IsNotExist(err) {
+			cobra.CheckErr(fmt.Sprintf("no Jotl project found in %s. Run `jotl init` first", currentDir))
+		}
+		cobra.CheckErr(editConfig(paths.ConfigFile))
+	},
+}
+
+var configMigrateCommand = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade jotl/config.yaml to the current schema version, keeping comments",
+	Long: fmt.Sprintf(`The migrate command rewrites jotl/config.yaml in the format of config
+version %s. Comments and key order are kept.
+
+Older configs are upgraded in memory whenever Jotl loads them, so migrating
+is only needed to update the file itself.`, config.CurrentVersion),
+	Args: cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		currentDir, err := os.Getwd()
+		cobra.CheckErr(err)
+		path := utils.GetConfigPaths(currentDir).ConfigFile
+
+		if configDryRun {
+			data, err := os.ReadFile(path)
+			cobra.CheckErr(err)
+			out, _, err := config.MigrateBytes(data)
+			cobra.CheckErr(err)
+			os.Stdout.Write(out)
+			return
+		}
+
+		from, err := config.Migrate(path)
+		cobra.CheckErr(err)
+		if from == config.CurrentVersion {
+			fmt.Println(tipMsgStyle.Render(fmt.Sprintf("jotl/config.yaml is already at version %s.", from)))
+			return
+		}
+		fmt.Println(endingMsgStyle.Render(fmt.Sprintf("Upgraded jotl/config.yaml from version %s to %s.", from, config.CurrentVersion)))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCommand)
+	configCommand.AddCommand(configGetCommand, configSetCommand, configListCommand, configResolveCommand, configEditCommand, configMigrateCommand)
+	configResolveCommand.Flags().BoolVar(&configJSON, "json", false, "Print the values as JSON")
+	configResolveCommand.Flags().BoolVar(&configShowSecrets, "show-secrets", false, "Don't mask passwords, tokens and webhook URLs")
+	configMigrateCommand.Flags().BoolVar(&configDryRun, "dry-run", false, "Print the upgraded config instead of writing it")
+}
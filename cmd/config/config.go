@@ -22,59 +22,127 @@ const (
 
 	// Log Formats define how log messages are structured
 	Text LogFormat = "text" // Human-readable text format
+	JSON LogFormat = "json" // One JSON object per line
 
 	// Default configuration values
-	DefaultVersion     = "1.0.0"   // Initial version number
-	DefaultTimeFormat  = "RFC3339" // Standard time format
-	DefaultRefreshRate = 5         // Dashboard refresh rate in seconds
+	DefaultVersion     = "1.0.0"          // Initial version number, assumed for configs without one
+	CurrentVersion     = "1.1.0"          // Config schema version written by this release
+	DefaultTimeFormat  = "RFC3339"        // Standard time format
+	DefaultRefreshRate = 5                // Dashboard refresh rate in seconds
+	DefaultIngestAddr  = "127.0.0.1:8090" // Listen address of the HTTP ingest endpoint
 )
 
 // Project contains basic project identification and description
 type Project struct {
-	Name        string `yaml:"name" json:"name"`               // Project name
-	Description string `yaml:"description" json:"description"` // Project description
+	Name        string `yaml:"name,omitempty" json:"name"`               // Project name
+	Description string `yaml:"description,omitempty" json:"description"` // Project description
 }
 
 // Database contains database connection configuration
 type Database struct {
-	Path string `yaml:"path" json:"path"` // Database connection string or file path
+	Path string `yaml:"path,omitempty" json:"path"` // Database connection string or file path
 }
 
 // Logging contains log handling configuration
 type Logging struct {
-	Level      LogLevel  `yaml:"level" json:"level"`           // Minimum log level to record
-	Format     LogFormat `yaml:"format" json:"format"`         // Output format for logs
-	TimeFormat string    `yaml:"timeFormat" json:"timeFormat"` // Time format string for log entries
+	Level      LogLevel  `yaml:"level,omitempty" json:"level"`           // Minimum log level to record
+	Format     LogFormat `yaml:"format,omitempty" json:"format"`         // Output format for logs
+	TimeFormat string    `yaml:"timeFormat,omitempty" json:"timeFormat"` // Time format string for log entries
 }
 
 // Dashboard contains web interface configuration
 type Dashboard struct {
-	Port        int    `yaml:"port" json:"port"`               // HTTP port for dashboard
-	Theme       string `yaml:"theme" json:"theme"`             // UI theme (system/light/dark)
-	RefreshRate int    `yaml:"refreshRate" json:"refreshRate"` // Data refresh interval in seconds
+	Port        int    `yaml:"port,omitempty" json:"port"`               // HTTP port for dashboard
+	Theme       string `yaml:"theme,omitempty" json:"theme"`             // UI theme (system/light/dark)
+	RefreshRate int    `yaml:"refreshRate,omitempty" json:"refreshRate"` // Data refresh interval in seconds
+}
+
+// HTTPIngest contains settings for the HTTP ingest endpoint
+type HTTPIngest struct {
+	Addr           string   `yaml:"addr,omitempty" json:"addr,omitempty"`                     // Listen address
+	AllowedOrigins []string `yaml:"allowedOrigins,omitempty" json:"allowedOrigins,omitempty"` // CORS origins allowed to push logs
+	Token          string   `yaml:"token,omitempty" json:"token,omitempty"`                   // Shared secret required on requests
+}
+
+// Sources contains additional inputs that `dev` ingests
+type Sources struct {
+	Files  []string   `yaml:"files,omitempty" json:"files,omitempty"`   // Glob patterns of log files to follow
+	Syslog string     `yaml:"syslog,omitempty" json:"syslog,omitempty"` // Address to receive syslog on (UDP and TCP)
+	HTTP   HTTPIngest `yaml:"http,omitempty" json:"http,omitempty"`     // HTTP ingest endpoint
+	OTLP   string     `yaml:"otlp,omitempty" json:"otlp,omitempty"`     // Address to receive OTLP/HTTP log exports on
+	Docker []string   `yaml:"docker,omitempty" json:"docker,omitempty"` // Containers (name, ID or label=key=value) to stream logs from
+}
+
+// AlertRule describes a condition on captured logs that triggers a notification
+type AlertRule struct {
+	Name      string    `yaml:"name" json:"name"`                               // Unique rule name
+	Type      string    `yaml:"type" json:"type"`                               // rate, pattern or new_error
+	Pattern   string    `yaml:"pattern,omitempty" json:"pattern,omitempty"`     // Regular expression matched against messages
+	Level     LogLevel  `yaml:"level,omitempty" json:"level,omitempty"`         // Minimum level counted by rate rules (default error)
+	Source    string    `yaml:"source,omitempty" json:"source,omitempty"`       // Only consider entries from this source
+	Threshold int       `yaml:"threshold,omitempty" json:"threshold,omitempty"` // Rate rules fire when more entries than this are logged within Window
+	Window    string    `yaml:"window,omitempty" json:"window,omitempty"`       // Rate window, e.g. 1m
+	Cooldown  string    `yaml:"cooldown,omitempty" json:"cooldown,omitempty"`   // Minimum time between notifications (default 5m)
+	Webhooks  []Webhook `yaml:"webhooks,omitempty" json:"webhooks,omitempty"`   // Webhooks notified in addition to Alerts.Webhooks
+}
+
+// Webhook is an endpoint alerts are POSTed to. In YAML it may also be
+// written as just the URL.
+type Webhook struct {
+	URL      string            `yaml:"url" json:"url"`                               // Endpoint to POST to
+	Format   string            `yaml:"format,omitempty" json:"format,omitempty"`     // json, slack or discord; inferred from the URL when empty
+	Template string            `yaml:"template,omitempty" json:"template,omitempty"` // Go template producing the JSON body, overrides Format
+	Headers  map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`   // Extra request headers, e.g. Authorization
+}
+
+// UnmarshalYAML accepts either a mapping or a plain URL.
+func (w *Webhook) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		w.URL = node.Value
+		return nil
+	}
+	type plain Webhook
+	return node.Decode((*plain)(w))
+}
+
+// Alerts contains the rules `dev` evaluates against captured logs
+type Alerts struct {
+	Rules    []AlertRule `yaml:"rules,omitempty" json:"rules,omitempty"`       // Alert rules
+	Webhooks []Webhook   `yaml:"webhooks,omitempty" json:"webhooks,omitempty"` // Webhooks notified for every rule
+	Silent   bool        `yaml:"silent,omitempty" json:"silent,omitempty"`     // Don't ring the terminal bell
 }
 
 // JotlConfig is the root configuration structure containing all settings
 type JotlConfig struct {
-	Version   string    `yaml:"version" json:"version"`     // Configuration version
-	Project   Project   `yaml:"project" json:"project"`     // Project settings
-	Database  Database  `yaml:"database" json:"database"`   // Database settings
-	Logging   Logging   `yaml:"logging" json:"logging"`     // Logging settings
-	Dashboard Dashboard `yaml:"dashboard" json:"dashboard"` // Dashboard settings
+	Version   string    `yaml:"version" json:"version"`                     // Configuration version
+	Project   Project   `yaml:"project" json:"project"`                     // Project settings
+	Database  Database  `yaml:"database" json:"database"`                   // Database settings
+	Logging   Logging   `yaml:"logging" json:"logging"`                     // Logging settings
+	Dashboard Dashboard `yaml:"dashboard" json:"dashboard"`                 // Dashboard settings
+	Sources   Sources   `yaml:"sources,omitempty" json:"sources,omitempty"` // Ingest sources
+	Alerts    Alerts    `yaml:"alerts,omitempty" json:"alerts,omitempty"`   // Alert rules
+	// Named variants of the settings above, selected with --profile or JOTL_PROFILE
+	Profiles map[string]*Profile `yaml:"profiles,omitempty" json:"profiles,omitempty"`
 }
 
-// NewConfig creates a new configuration with default values.
-func NewConfig(name, loglevel, dbPath string) *JotlConfig {
+// Profile overrides parts of the base configuration. Only the keys it sets
+// change: nested settings are merged and lists are replaced.
+type Profile struct {
+	Project   *Project   `yaml:"project,omitempty" json:"project,omitempty"`
+	Database  *Database  `yaml:"database,omitempty" json:"database,omitempty"`
+	Logging   *Logging   `yaml:"logging,omitempty" json:"logging,omitempty"`
+	Dashboard *Dashboard `yaml:"dashboard,omitempty" json:"dashboard,omitempty"`
+	Sources   *Sources   `yaml:"sources,omitempty" json:"sources,omitempty"`
+	Alerts    *Alerts    `yaml:"alerts,omitempty" json:"alerts,omitempty"`
+}
+
+// Defaults returns the configuration used for settings that no config
+// file, environment variable or flag sets.
+func Defaults() *JotlConfig {
 	return &JotlConfig{
-		Version: DefaultVersion,
-		Project: Project{
-			Name: name,
-		},
-		Database: Database{
-			Path: dbPath,
-		},
+		Version: CurrentVersion,
 		Logging: Logging{
-			Level:      LogLevel(loglevel),
+			Level:      Info,
 			Format:     Text,
 			TimeFormat: DefaultTimeFormat,
 		},
@@ -86,9 +154,46 @@ func NewConfig(name, loglevel, dbPath string) *JotlConfig {
 	}
 }
 
-// SaveConfig saves the configuration to a YAML file.
-// It creates parent directories if they don't exist.
+// NewConfig creates a new configuration with default values.
+func NewConfig(name, loglevel, dbPath string) *JotlConfig {
+	c := Defaults()
+	c.Project.Name = name
+	c.Database.Path = dbPath
+	c.Logging.Level = LogLevel(loglevel)
+	return c
+}
+
+// ScaffoldProfile returns a starting point for a profile called name:
+// verbose logging for local development, a separate SQLite database for
+// CI, and quieter logging for anything else, such as staging.
+func ScaffoldProfile(name string) *Profile {
+	switch name {
+	case "local", "dev", "development":
+		return &Profile{Logging: &Logging{Level: Debug}}
+	case "ci", "test":
+		return &Profile{
+			Database: &Database{Path: "file:./db/" + name + ".db"},
+			Logging:  &Logging{Level: Info},
+		}
+	default:
+		return &Profile{Logging: &Logging{Level: Warn}}
+	}
+}
+
+// AddProfile adds a scaffolded profile called name, keeping an existing one.
+func (c *JotlConfig) AddProfile(name string) {
+	if c.Profiles == nil {
+		c.Profiles = map[string]*Profile{}
+	}
+	if _, ok := c.Profiles[name]; !ok {
+		c.Profiles[name] = ScaffoldProfile(name)
+	}
+}
 
+// SaveConfig saves the configuration to a YAML file.
+// It creates parent directories if they don't exist. A config holding
+// plaintext secrets is written with mode 0600 from the start, through a
+// temporary file that replaces the old one.
 func (c *JotlConfig) SaveConfig(path string) error {
 	data, err := yaml.Marshal(c)
 	if err != nil {
@@ -99,24 +204,50 @@ func (c *JotlConfig) SaveConfig(path string) error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	if len(c.PlaintextSecrets()) > 0 {
+		return writePrivate(path, data)
+	}
 	if err := os.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
+	return nil
+}
+
+// writePrivate replaces the file at path with data, readable only by the
+// owner. os.CreateTemp creates the file with mode 0600, so the secrets are
+// never readable by others, not even briefly.
+func writePrivate(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
 
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
 	return nil
 }
 
-// LoadConfig reads and parses a YAML configuration file.
-// This may be called every time you run `... dev` to verify user configs
+// LoadConfig reads, validates and parses a YAML configuration file.
+// Configs written by older releases are upgraded in memory; `jotl config
+// migrate` rewrites the file.
 func LoadConfig(path string) (*JotlConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	config := &JotlConfig{}
-	if err := yaml.Unmarshal(data, config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	config, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
 	}
 
 	return config, nil
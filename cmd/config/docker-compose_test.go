@@ -0,0 +1,273 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mergedServices renders the PostgreSQL service, and pgAdmin if pgAdmin is
+// set, as they are merged into a project's compose file.
+func mergedServices(t *testing.T, pgAdmin bool) []byte {
+	t.Helper()
+	pc := DefaultPostgresConfig("shop")
+	pc.Merge, pc.PgAdmin = true, pgAdmin
+	out, err := pc.render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func writeCompose(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "compose.yaml")
+	if err := os.WriteFile(path, []byte(data), 0640); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const postgresEntry = `jotl-postgres:
+  image: postgres:17
+  container_name: jotl-shop-postgres
+  # POSTGRES_PASSWORD is generated by jotl init and kept in jotl/.env.
+  env_file: jotl/.env
+  environment:
+    POSTGRES_DB: jotl
+    POSTGRES_USER: jotl
+  ports:
+    - "5432:5432"
+  volumes:
+    - jotl_postgres_data:/var/lib/postgresql/data
+  healthcheck:
+    test: ["CMD-SHELL", "pg_isready -U jotl -d jotl"]
+    interval: 5s
+    timeout: 5s
+    retries: 10
+  restart: unless-stopped
+`
+
+// indented indents every non-empty line of s by prefix and then nests it
+// by depth further levels of width spaces.
+func indented(s string, width int) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if strings.TrimSpace(line) == "" {
+			b.WriteString(line)
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " ")
+		b.WriteString(strings.Repeat(" ", width+(len(line)-len(trimmed))/2*width) + trimmed)
+	}
+	return b.String()
+}
+
+func TestMergeCompose(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		want     string
+	}{
+		{
+			name: "other services and comments",
+			existing: `# Local stack for the shop
+name: shop
+
+services:
+  # The API server
+  api:
+    build: .
+    ports:
+      - "8080:8080"   # public
+    command: ["./api", "--verbose"]
+    depends_on: [redis]
+
+  redis:
+    image: 'redis:7'    # pinned
+
+# Named volumes
+volumes:
+  cache: {}
+
+# End of file
+`,
+			want: `# Local stack for the shop
+name: shop
+
+services:
+  # The API server
+  api:
+    build: .
+    ports:
+      - "8080:8080"   # public
+    command: ["./api", "--verbose"]
+    depends_on: [redis]
+
+  redis:
+    image: 'redis:7'    # pinned
+
+` + indented(postgresEntry, 2) + `
+# Named volumes
+volumes:
+  cache: {}
+  jotl_postgres_data:
+    driver: local
+
+# End of file
+`,
+		},
+		{
+			name: "four-space indent without volumes or a final newline",
+			existing: `services:
+    web:
+        image: nginx
+        volumes:
+            - ./site:/usr/share/nginx/html`,
+			want: `services:
+    web:
+        image: nginx
+        volumes:
+            - ./site:/usr/share/nginx/html
+` + indented(postgresEntry, 4) + `
+volumes:
+    jotl_postgres_data:
+        driver: local
+`,
+		},
+		{
+			name: "empty sections",
+			existing: `services:
+  app:
+    image: app
+volumes:
+x-note: keep
+`,
+			want: `services:
+  app:
+    image: app
+` + indented(postgresEntry, 2) + `volumes:
+  jotl_postgres_data:
+    driver: local
+x-note: keep
+`,
+		},
+		{
+			name:     "comments only",
+			existing: "# compose file\n",
+			want:     "# compose file\n\nservices:\n" + indented(postgresEntry, 2) + "\nvolumes:\n  jotl_postgres_data:\n    driver: local\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeCompose(t, tt.existing)
+			if err := mergeCompose(path, mergedServices(t, false)); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("merged file:\n%s\nwant:\n%s", data, tt.want)
+			}
+			if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+				t.Errorf("mode = %04o, want 0640", info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestMergeComposeWithPgAdmin(t *testing.T) {
+	path := writeCompose(t, "services:\n  api:\n    image: api\n")
+	if err := mergeCompose(path, mergedServices(t, true)); err != nil {
+		t.Fatal(err)
+	}
+
+	cs, err := LocateCompose(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cs.File != path || cs.Service != "jotl-postgres" || strings.Join(cs.Services, ",") != "jotl-postgres,jotl-pgadmin" {
+		t.Errorf("LocateCompose = %+v", cs)
+	}
+
+	var merged struct {
+		Services map[string]struct {
+			Image     string
+			DependsOn map[string]map[string]string `yaml:"depends_on"`
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if err := yaml.Unmarshal(data, &merged); err != nil {
+		t.Fatal(err)
+	}
+	if merged.Services["api"].Image != "api" || merged.Services["jotl-pgadmin"].DependsOn["jotl-postgres"]["condition"] != "service_healthy" {
+		t.Errorf("services = %+v", merged.Services)
+	}
+}
+
+func TestMergeComposeFlowStyle(t *testing.T) {
+	// Flow-style sections can't be extended line by line; the file is
+	// re-encoded instead.
+	path := writeCompose(t, "services: {api: {image: api}} # the app\n")
+	if err := mergeCompose(path, mergedServices(t, false)); err != nil {
+		t.Fatal(err)
+	}
+
+	var merged struct {
+		Services map[string]any
+		Volumes  map[string]any
+	}
+	data, _ := os.ReadFile(path)
+	if err := yaml.Unmarshal(data, &merged); err != nil {
+		t.Fatal(err)
+	}
+	if merged.Services["api"] == nil || merged.Services["jotl-postgres"] == nil || merged.Volumes["jotl_postgres_data"] == nil {
+		t.Errorf("merged file:\n%s", data)
+	}
+	if !strings.Contains(string(data), "# the app") {
+		t.Errorf("comment lost:\n%s", data)
+	}
+}
+
+func TestMergeComposeTwice(t *testing.T) {
+	path := writeCompose(t, "services:\n  api:\n    image: api\n")
+	if err := mergeCompose(path, mergedServices(t, false)); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(path)
+
+	err := mergeCompose(path, mergedServices(t, true))
+	if err == nil || !strings.Contains(err.Error(), `compose.yaml already defines service "jotl-postgres"`) {
+		t.Errorf("second merge = %v, want an error", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Errorf("second merge changed the file:\n%s", after)
+	}
+}
+
+func TestMergeComposeErrors(t *testing.T) {
+	tests := []struct {
+		existing string
+		err      string
+	}{
+		{"- just\n- a list\n", "is not a compose file"},
+		{"services: [\n", "failed to parse"},
+		{"services:\n  app: {}\nvolumes:\n  jotl_postgres_data: {}\n", `already defines volume "jotl_postgres_data"`},
+	}
+	for _, tt := range tests {
+		path := writeCompose(t, tt.existing)
+		err := mergeCompose(path, mergedServices(t, false))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("merging into %q = %v, want %q", tt.existing, err, tt.err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != tt.existing {
+			t.Errorf("failed merge changed %q to %q", tt.existing, data)
+		}
+	}
+}
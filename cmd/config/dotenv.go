@@ -0,0 +1,73 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ReadDotEnv parses the KEY=VALUE lines of a dotenv file. Blank lines and
+// comments are skipped and surrounding quotes are removed from values.
+func ReadDotEnv(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	env := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := parseDotEnvLine(scanner.Text())
+		if ok {
+			env[key] = value
+		}
+	}
+	return env, scanner.Err()
+}
+
+// UpdateDotEnv sets keys in an existing dotenv file, keeping its comments
+// and other lines. Keys that aren't in the file yet are appended.
+func UpdateDotEnv(path string, values map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	done := map[string]bool{}
+	for i, line := range lines {
+		key, _, ok := parseDotEnvLine(line)
+		if value, set := values[key]; ok && set {
+			lines[i] = key + "=" + value
+			done[key] = true
+		}
+	}
+	for key, value := range values {
+		if !done[key] {
+			lines = append(lines, key+"="+value)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+func parseDotEnvLine(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", "", false
+	}
+	key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+	if !ok {
+		return "", "", false
+	}
+	return strings.TrimSpace(key), strings.Trim(strings.TrimSpace(value), `"'`), true
+}
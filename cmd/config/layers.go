@@ -0,0 +1,334 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables that override config values,
+// e.g. JOTL_DATABASE_PATH for database.path.
+const EnvPrefix = "JOTL_"
+
+// ProfileEnv selects a profile when --profile isn't given.
+const ProfileEnv = EnvPrefix + "PROFILE"
+
+// Layer names where an effective config value came from, from lowest to
+// highest precedence.
+type Layer string
+
+const (
+	LayerDefault Layer = "default"
+	LayerFile    Layer = "file"
+	LayerProfile Layer = "profile"
+	LayerDotEnv  Layer = "dotenv"
+	LayerEnv     Layer = "env"
+	LayerFlag    Layer = "flag"
+)
+
+// dotEnvKeys are the keys `jotl init` writes to jotl/.env, with the config
+// values they set.
+var dotEnvKeys = map[string]string{
+	"DB_CONNECTION_STRING": "database.path",
+	"APP_NAME":             "project.name",
+}
+
+// DotEnvKey returns the jotl/.env key that overrides the config value at
+// path besides its JOTL_* variable, if there is one.
+func DotEnvKey(path string) (string, bool) {
+	for key, p := range dotEnvKeys {
+		if p == path {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// Origin is where an effective config value was set.
+type Origin struct {
+	Layer Layer  `json:"layer"`
+	Name  string `json:"name,omitempty"` // File, variable or flag that set it
+}
+
+func (o Origin) String() string {
+	if o.Name == "" {
+		return string(o.Layer)
+	}
+	return fmt.Sprintf("%s (%s)", o.Layer, o.Name)
+}
+
+// FlagOverride is a config value set by a command-line flag.
+type FlagOverride struct {
+	Flag  string // e.g. --port
+	Path  string
+	Value string
+}
+
+// Layers are the inputs of Resolve.
+type Layers struct {
+	File    string   // config.yaml
+	Profile string   // Entry of profiles applied over File; defaults to $JOTL_PROFILE
+	DotEnv  string   // jotl/.env; ignored if it doesn't exist
+	Environ []string // KEY=VALUE pairs, normally os.Environ()
+	Flags   []FlagOverride
+}
+
+// DefaultLayers returns the layers of the project whose config file is at
+// path: its config.yaml and .env, and the process environment.
+func DefaultLayers(path string) Layers {
+	return Layers{
+		File:    path,
+		DotEnv:  filepath.Join(filepath.Dir(path), ".env"),
+		Environ: os.Environ(),
+	}
+}
+
+// Resolved is the effective config together with where each value came from.
+type Resolved struct {
+	Config  *JotlConfig
+	Profile string // Applied profile, if any
+	origins map[string]Origin
+}
+
+// Origin returns where the value at path was set.
+func (r *Resolved) Origin(path string) Origin {
+	for p := path; p != ""; p = parentPath(p) {
+		if o, ok := r.origins[p]; ok {
+			return o
+		}
+	}
+	return Origin{Layer: LayerDefault}
+}
+
+// Resolve builds the effective config from, in increasing precedence, the
+// defaults, config.yaml, the selected profile, jotl/.env, JOTL_* environment
+// variables and flags. The result is validated as a whole, then the secret
+// references in database.path are expanded.
+func Resolve(l Layers) (*Resolved, error) {
+	data, err := os.ReadFile(l.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := Defaults()
+	v := newValidator()
+	if err := v.decode(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", l.File, err)
+	}
+
+	envVars := envPaths()
+	r := &Resolved{Config: cfg, origins: map[string]Origin{}}
+	fileOrigin := Origin{Layer: LayerFile, Name: filepath.Base(l.File)}
+	for p := range v.nodes {
+		if p != "" {
+			r.origins[p] = fileOrigin
+		}
+	}
+
+	environ := map[string]string{}
+	for _, kv := range l.Environ {
+		if key, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(key, EnvPrefix) {
+			environ[key] = value
+		}
+	}
+
+	dotenv := map[string]string{}
+	if l.DotEnv != "" {
+		dotenv, err = ReadDotEnv(l.DotEnv)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	r.Profile = l.Profile
+	if r.Profile == "" {
+		r.Profile = environ[ProfileEnv]
+	}
+	if r.Profile != "" {
+		if err := r.applyProfile(v); err != nil {
+			return nil, fmt.Errorf("%s: %w", l.File, err)
+		}
+	}
+
+	set := func(path, value string, o Origin) error {
+		if err := cfg.Set(path, value); err != nil {
+			return fmt.Errorf("%s: %w", o.Name, err)
+		}
+		for p := range r.origins {
+			if strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") {
+				delete(r.origins, p)
+			}
+		}
+		r.origins[path] = o
+		v.overridden(path, o.Name)
+		return nil
+	}
+
+	if l.DotEnv != "" {
+		name := filepath.Base(filepath.Dir(l.DotEnv)) + "/" + filepath.Base(l.DotEnv)
+		for _, key := range sortedKeys(dotenv) {
+			path, ok := dotEnvKeys[key]
+			if !ok {
+				path, ok = envVars[key]
+			}
+			if ok {
+				if err := set(path, dotenv[key], Origin{Layer: LayerDotEnv, Name: name + ":" + key}); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(environ) {
+		if path, ok := envVars[key]; ok {
+			if err := set(path, environ[key], Origin{Layer: LayerEnv, Name: key}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, f := range l.Flags {
+		if err := set(f.Path, f.Value, Origin{Layer: LayerFlag, Name: f.Flag}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := v.check(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", l.File, err)
+	}
+
+	// Secrets are looked up in the environment first, then in jotl/.env.
+	lookup := func(name string) (string, bool) {
+		for _, kv := range l.Environ {
+			if key, value, ok := strings.Cut(kv, "="); ok && key == name {
+				return value, true
+			}
+		}
+		value, ok := dotenv[name]
+		return value, ok
+	}
+	cfg.Database.Path, err = ExpandSecrets(cfg.Database.Path, lookup, filepath.Dir(l.File))
+	if err != nil {
+		return nil, fmt.Errorf("%s: database.path: %w", l.File, err)
+	}
+	return r, nil
+}
+
+// applyProfile merges the selected profile over the config decoded by v.
+func (r *Resolved) applyProfile(v *validator) error {
+	node := lookup(lookup(v.root, "profiles"), r.Profile)
+	if node == nil {
+		names := make([]string, 0, len(r.Config.Profiles))
+		for name := range r.Config.Profiles {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		if len(names) == 0 {
+			return fmt.Errorf("unknown profile %q: no profiles are defined", r.Profile)
+		}
+		return fmt.Errorf("unknown profile %q (defined: %s)", r.Profile, strings.Join(names, ", "))
+	}
+	if err := node.Decode(r.Config); err != nil {
+		return fmt.Errorf("profile %s: %w", r.Profile, err)
+	}
+
+	// Values of the profile are reported at their position in the profile.
+	pv := newValidator()
+	pv.walk(node, reflect.TypeOf(JotlConfig{}), "")
+	o := Origin{Layer: LayerProfile, Name: "profiles." + r.Profile}
+	for p, n := range pv.nodes {
+		if p == "" {
+			continue
+		}
+		if n.Kind == yaml.SequenceNode {
+			// Lists are replaced, so drop what the file said about the items.
+			for q := range r.origins {
+				if strings.HasPrefix(q, p+"[") {
+					delete(r.origins, q)
+					delete(v.nodes, q)
+				}
+			}
+		}
+	}
+	for p, n := range pv.nodes {
+		if p != "" {
+			r.origins[p] = o
+			v.nodes[p] = n
+		}
+	}
+	v.profile = r.Profile
+	return nil
+}
+
+// EnvName returns the environment variable that overrides the config value
+// at path, e.g. JOTL_SOURCES_HTTP_ALLOWED_ORIGINS for
+// sources.http.allowedOrigins.
+func EnvName(path string) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	prev := rune(0)
+	for _, r := range path {
+		switch {
+		case r == '.':
+			b.WriteByte('_')
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			b.WriteByte('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		prev = r
+	}
+	return b.String()
+}
+
+// envPaths maps environment variable names to the config values they set.
+// Only scalars and lists of scalars can be set from the environment.
+func envPaths() map[string]string {
+	paths := map[string]string{}
+	var walk func(t reflect.Type, path string)
+	walk = func(t reflect.Type, path string) {
+		switch t.Kind() {
+		case reflect.Struct:
+			for i := 0; i < t.NumField(); i++ {
+				walk(t.Field(i).Type, join(path, yamlName(t.Field(i))))
+			}
+		case reflect.Map:
+		case reflect.Slice:
+			if isScalarList(t) {
+				paths[EnvName(path)] = path
+			}
+		default:
+			if path != "version" {
+				paths[EnvName(path)] = path
+			}
+		}
+	}
+	walk(reflect.TypeOf(JotlConfig{}), "")
+	return paths
+}
+
+// parentPath strips the last key or index from path.
+func parentPath(path string) string {
+	i := strings.LastIndexAny(path, ".[")
+	if i < 0 {
+		return ""
+	}
+	return path[:i]
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Setting is a single value of the config addressed by its dotted path.
+type Setting struct {
+	Path  string
+	Value string
+}
+
+// Get returns the value at a dotted path such as `logging.level` or
+// `alerts.rules[0].window`.
+func (c *JotlConfig) Get(path string) (any, error) {
+	v, err := c.resolve(path, false)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// Set parses value as YAML into the field at a dotted path. The value must
+// fit the field's type, e.g. `dashboard.port 9090` or
+// `sources.files '["logs/*.log"]'`. Set doesn't validate the resulting
+// config; see Validate.
+func (c *JotlConfig) Set(path, value string) error {
+	parent, key, err := splitLast(path)
+	if err != nil {
+		return err
+	}
+
+	// Map entries aren't addressable and are set through their map.
+	if parent != "" {
+		if m, err := c.resolve(parent, true); err == nil && m.Kind() == reflect.Map {
+			elem := reflect.New(m.Type().Elem())
+			if err := decodeValue(value, elem.Interface()); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if m.IsNil() {
+				m.Set(reflect.MakeMap(m.Type()))
+			}
+			m.SetMapIndex(reflect.ValueOf(key), elem.Elem())
+			return nil
+		}
+	}
+
+	field, err := c.resolve(path, true)
+	if err != nil {
+		return err
+	}
+	target := reflect.New(field.Type())
+	if err := decodeValue(value, target.Interface()); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	field.Set(target.Elem())
+	return nil
+}
+
+// Settings lists every scalar value of the config in field order. Lists
+// of scalars are reported as a single YAML flow sequence.
+func (c *JotlConfig) Settings() []Setting {
+	var out []Setting
+	flatten(reflect.ValueOf(c).Elem(), "", &out)
+	return out
+}
+
+// Validate checks the config the way LoadConfig checks a file.
+func (c *JotlConfig) Validate() error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	_, err = Parse(data)
+	return err
+}
+
+// FormatValue renders a value returned by Get for display: scalars as is,
+// lists of scalars as a YAML flow sequence and anything else as YAML.
+func FormatValue(v any) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && isScalarList(rv.Type()) {
+		return flowSequence(v)
+	}
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice:
+		data, _ := yaml.Marshal(v)
+		return strings.TrimRight(string(data), "\n")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// resolve walks a dotted path through the config. Missing slice elements
+// and map entries are errors.
+func (c *JotlConfig) resolve(path string, settable bool) (reflect.Value, error) {
+	if path == "" {
+		return reflect.Value{}, fmt.Errorf("empty config path")
+	}
+
+	v := reflect.ValueOf(c).Elem()
+	walked := ""
+	for _, seg := range splitPath(path) {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !settable || !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("%s is not set", walked)
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := fieldByKey(v.Type(), seg)
+			if !ok {
+				return reflect.Value{}, fmt.Errorf("unknown config key %q", join(walked, seg))
+			}
+			v = v.FieldByIndex(f.Index)
+		case reflect.Slice:
+			i, err := strconv.Atoi(strings.Trim(seg, "[]"))
+			if err != nil || !strings.HasPrefix(seg, "[") {
+				return reflect.Value{}, fmt.Errorf("%s is a list; use %s[N]", walked, walked)
+			}
+			if i < 0 || i >= v.Len() {
+				return reflect.Value{}, fmt.Errorf("%s has no element %d", walked, i)
+			}
+			v = v.Index(i)
+		case reflect.Map:
+			// Only entries held by pointer, like profiles, can be changed
+			// in place; new ones are added empty.
+			pointers := v.Type().Elem().Kind() == reflect.Pointer
+			if settable && !pointers {
+				return reflect.Value{}, fmt.Errorf("%s is a map; set its entries instead", walked)
+			}
+			e := v.MapIndex(reflect.ValueOf(seg))
+			if !e.IsValid() {
+				if !settable {
+					return reflect.Value{}, fmt.Errorf("%s has no entry %q", walked, seg)
+				}
+				if v.IsNil() {
+					v.Set(reflect.MakeMap(v.Type()))
+				}
+				e = reflect.New(v.Type().Elem().Elem())
+				v.SetMapIndex(reflect.ValueOf(seg), e)
+			}
+			v = e
+		default:
+			return reflect.Value{}, fmt.Errorf("%s has no key %q", walked, seg)
+		}
+		if strings.HasPrefix(seg, "[") {
+			walked += seg
+		} else {
+			walked = join(walked, seg)
+		}
+	}
+	return v, nil
+}
+
+// splitPath splits `alerts.rules[0].name` into alerts, rules, [0], name.
+func splitPath(path string) []string {
+	var segs []string
+	for _, part := range strings.Split(path, ".") {
+		for {
+			i := strings.Index(part, "[")
+			if i < 0 {
+				break
+			}
+			if i > 0 {
+				segs = append(segs, part[:i])
+			}
+			j := strings.Index(part, "]")
+			if j < i {
+				segs = append(segs, part[i:])
+				part = ""
+				break
+			}
+			segs = append(segs, part[i:j+1])
+			part = part[j+1:]
+		}
+		if part != "" {
+			segs = append(segs, part)
+		}
+	}
+	return segs
+}
+
+// splitLast splits a path into its parent and its last key.
+func splitLast(path string) (string, string, error) {
+	if path == "" {
+		return "", "", fmt.Errorf("empty config path")
+	}
+	i := strings.LastIndexAny(path, ".[")
+	if i < 0 || path[i] == '[' {
+		return "", path, nil
+	}
+	return path[:i], path[i+1:], nil
+}
+
+// decodeValue parses s as YAML into out, reporting type mismatches.
+// Strings are taken literally so they needn't be quoted.
+func decodeValue(s string, out any) error {
+	if v := reflect.ValueOf(out).Elem(); v.Kind() == reflect.String {
+		v.SetString(s)
+		return nil
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(s), &node); err != nil {
+		return fmt.Errorf("invalid value: %w", err)
+	}
+	if len(node.Content) == 0 {
+		return nil
+	}
+	if err := node.Content[0].Decode(out); err != nil {
+		return fmt.Errorf("expected %s, got %q", kindName(reflect.TypeOf(out).Elem()), s)
+	}
+	return nil
+}
+
+func flatten(v reflect.Value, path string, out *[]Setting) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			flatten(v.Elem(), path, out)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			flatten(v.Field(i), join(path, yamlName(f)), out)
+		}
+	case reflect.Slice:
+		if isScalarList(v.Type()) {
+			*out = append(*out, Setting{Path: path, Value: flowSequence(v.Interface())})
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			flatten(v.Index(i), fmt.Sprintf("%s[%d]", path, i), out)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = k.String()
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			flatten(v.MapIndex(reflect.ValueOf(name)), join(path, name), out)
+		}
+	default:
+		*out = append(*out, Setting{Path: path, Value: fmt.Sprint(v.Interface())})
+	}
+}
+
+func isScalarList(t reflect.Type) bool {
+	k := t.Elem().Kind()
+	return k != reflect.Struct && k != reflect.Map && k != reflect.Slice
+}
+
+// flowSequence renders a list as a YAML flow sequence, e.g. [a.log, b.log].
+func flowSequence(list any) string {
+	var node yaml.Node
+	if err := node.Encode(list); err != nil {
+		return fmt.Sprint(list)
+	}
+	node.Style = yaml.FlowStyle
+	data, _ := yaml.Marshal(&node)
+	return strings.TrimSpace(string(data))
+}
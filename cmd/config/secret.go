@@ -0,0 +1,179 @@
+package config
+
+import (
+	"crypto/rand"
+	"fmt"
+	"maps"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// PasswordEnv holds the password of the PostgreSQL database created by
+// `jotl init`. It is kept in jotl/.env, where docker compose reads it too.
+const PasswordEnv = "POSTGRES_PASSWORD"
+
+// MySQLPasswordEnv holds the password of the MySQL or MariaDB user that
+// `jotl init` puts in database.path. jotl doesn't run that server, so it is
+// left empty for the user to fill in.
+const MySQLPasswordEnv = "MYSQL_PASSWORD"
+
+// secretRef matches the secret references allowed in database.path:
+// ${NAME} for an environment variable and ${file:PATH} for the contents of
+// a file. A bare file: prefix would be ambiguous with SQLite paths.
+var (
+	secretRef = regexp.MustCompile(`\$\{([^}]*)\}`)
+	envName   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+const passwordChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// GeneratePassword returns a random 24 character password. It only uses
+// letters and digits so it can be put in a URL without escaping.
+func GeneratePassword() (string, error) {
+	b := make([]byte, 24)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordChars))))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %w", err)
+		}
+		b[i] = passwordChars[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// checkSecretRefs reports the first malformed secret reference in s.
+func checkSecretRefs(s string) error {
+	for _, m := range secretRef.FindAllStringSubmatch(s, -1) {
+		ref := m[1]
+		if path, ok := strings.CutPrefix(ref, "file:"); ok {
+			if path == "" {
+				return fmt.Errorf("%s needs a file path", m[0])
+			}
+		} else if !envName.MatchString(ref) {
+			return fmt.Errorf("%s is not a valid secret reference; use ${NAME} or ${file:PATH}", m[0])
+		}
+	}
+	return nil
+}
+
+// ExpandSecrets replaces the secret references in s: ${NAME} with the
+// variable returned by lookup and ${file:PATH} with the contents of the
+// file, without trailing newlines. Relative paths are resolved against dir.
+//
+// Values put into the user name or password of a URL-style connection
+// string are escaped, so a password such as "p@ss/word" doesn't break the
+// URL; the drivers unescape them again. Values anywhere else are inserted
+// as they are.
+func ExpandSecrets(s string, lookup func(string) (string, bool), dir string) (string, error) {
+	if err := checkSecretRefs(s); err != nil {
+		return "", err
+	}
+
+	userinfo := userinfoEnd(s)
+	var b strings.Builder
+	last := 0
+	for _, loc := range secretRef.FindAllStringIndex(s, -1) {
+		value, err := expandSecret(s[loc[0]:loc[1]], lookup, dir)
+		if err != nil {
+			return "", err
+		}
+		if loc[1] <= userinfo {
+			value = url.User(value).String()
+		}
+		b.WriteString(s[last:loc[0]])
+		b.WriteString(value)
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String(), nil
+}
+
+// expandSecret returns the value of the reference m.
+func expandSecret(m string, lookup func(string) (string, bool), dir string) (string, error) {
+	ref := m[2 : len(m)-1]
+	if path, ok := strings.CutPrefix(ref, "file:"); ok {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	value, ok := lookup(ref)
+	if !ok {
+		return "", fmt.Errorf("%s is not set; define %s in the environment or jotl/.env", m, ref)
+	}
+	return value, nil
+}
+
+// userinfoEnd returns the index of the @ that ends the user name and
+// password of a URL-style connection string, or 0 if s has none.
+func userinfoEnd(s string) int {
+	// References may contain slashes and @, so mask them without moving
+	// the rest of s.
+	masked := secretRef.ReplaceAllStringFunc(s, func(m string) string {
+		return strings.Repeat("\x00", len(m))
+	})
+	scheme, rest, ok := strings.Cut(masked, "://")
+	if !ok {
+		return 0
+	}
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	i := strings.LastIndex(rest, "@")
+	if i < 0 {
+		return 0
+	}
+	return len(scheme) + len("://") + i
+}
+
+// PlaintextPassword reports whether the connection string dsn contains a
+// password literally instead of through a secret reference.
+func PlaintextPassword(dsn string) bool {
+	// References may contain slashes, so take them out before splitting.
+	dsn = secretRef.ReplaceAllString(dsn, "\x00")
+
+	if _, rest, ok := strings.Cut(dsn, "://"); ok {
+		authority, _, _ := strings.Cut(rest, "/")
+		if i := strings.LastIndex(authority, "@"); i >= 0 {
+			if _, password, ok := strings.Cut(authority[:i], ":"); ok && isLiteral(password) {
+				return true
+			}
+		}
+	}
+	if _, query, ok := strings.Cut(dsn, "?"); ok {
+		for _, kv := range strings.Split(query, "&") {
+			if key, value, ok := strings.Cut(kv, "="); ok && key == "password" && isLiteral(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isLiteral(secret string) bool {
+	return secret != "" && !strings.Contains(secret, "\x00")
+}
+
+// PlaintextSecrets returns the paths of config values holding a database
+// password in plaintext, including those of profiles.
+func (c *JotlConfig) PlaintextSecrets() []string {
+	var paths []string
+	if PlaintextPassword(c.Database.Path) {
+		paths = append(paths, "database.path")
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Profiles)) {
+		if p := c.Profiles[name]; p != nil && p.Database != nil && PlaintextPassword(p.Database.Path) {
+			paths = append(paths, "profiles."+name+".database.path")
+		}
+	}
+	return paths
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// An upgrade rewrites a config document from one schema version to the next.
+type upgrade struct {
+	from, to string
+	apply    func(root *yaml.Node) error
+}
+
+// upgrades are applied in order until the document reaches CurrentVersion.
+// Append new steps when the schema changes; never edit released ones.
+var upgrades = []upgrade{
+	// 1.1.0 validates logging and dashboard settings strictly, so keys that
+	// 1.0.0 left to implicit defaults are now spelled out.
+	{"1.0.0", "1.1.0", func(root *yaml.Node) error {
+		logging := mappingValue(root, "logging")
+		setDefault(logging, "level", string(Info))
+		setDefault(logging, "format", string(Text))
+		setDefault(logging, "timeFormat", DefaultTimeFormat)
+
+		dashboard := mappingValue(root, "dashboard")
+		setDefault(dashboard, "port", "8080")
+		setDefault(dashboard, "theme", "system")
+		setDefault(dashboard, "refreshRate", fmt.Sprint(DefaultRefreshRate))
+		return nil
+	}},
+}
+
+// Upgrade brings the config document doc up to CurrentVersion in place and
+// returns the version it started from. Documents without a version are
+// treated as DefaultVersion.
+func Upgrade(doc *yaml.Node) (string, error) {
+	root := documentRoot(doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("config file must be a mapping")
+	}
+
+	from := DefaultVersion
+	if v := lookup(root, "version"); v != nil && v.Value != "" {
+		from = v.Value
+	}
+
+	version := from
+	for version != CurrentVersion {
+		i := 0
+		for i < len(upgrades) && upgrades[i].from != version {
+			i++
+		}
+		if i == len(upgrades) {
+			return from, fmt.Errorf("unsupported config version %q (this jotl supports up to %s)", version, CurrentVersion)
+		}
+		if err := upgrades[i].apply(root); err != nil {
+			return from, fmt.Errorf("failed to upgrade config from %s to %s: %w", upgrades[i].from, upgrades[i].to, err)
+		}
+		version = upgrades[i].to
+		setScalar(root, "version", version)
+	}
+	return from, nil
+}
+
+// Migrate upgrades the config file at path to CurrentVersion, keeping its
+// comments and layout. It returns the version the file was at; the file is
+// only rewritten when that differs from CurrentVersion.
+func Migrate(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	out, from, err := MigrateBytes(data)
+	if err != nil || from == CurrentVersion {
+		return from, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return from, err
+	}
+	if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+		return from, fmt.Errorf("failed to write config file: %w", err)
+	}
+	return from, nil
+}
+
+// MigrateBytes is Migrate for a config document held in memory. The result
+// is validated before it is returned.
+func MigrateBytes(data []byte) ([]byte, string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, "", fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	from, err := Upgrade(&doc)
+	if err != nil || from == CurrentVersion {
+		return data, from, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(detectIndent(data))
+	if err := enc.Encode(&doc); err != nil {
+		return nil, from, fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, from, err
+	}
+
+	if _, err := Parse(buf.Bytes()); err != nil {
+		return nil, from, err
+	}
+	return buf.Bytes(), from, nil
+}
+
+// detectIndent returns the indentation of the first indented line in data,
+// or yaml.v3's default of 4 used by SaveConfig.
+func detectIndent(data []byte) int {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || trimmed == line || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "- ") {
+			continue
+		}
+		return len(line) - len(trimmed)
+	}
+	return 4
+}
+
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		return doc.Content[0]
+	}
+	return doc
+}
+
+// lookup returns the value of key in the mapping m, or nil.
+func lookup(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mappingValue returns the mapping under key in m, adding an empty one if
+// it is missing or null.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if v := lookup(m, key); v != nil {
+		if v.Kind != yaml.MappingNode && v.Tag == "!!null" {
+			*v = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		return v
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+	return v
+}
+
+// setScalar sets key in the mapping m to value.
+func setScalar(m *yaml.Node, key, value string) {
+	if v := lookup(m, key); v != nil {
+		v.Kind, v.Tag, v.Value, v.Style = yaml.ScalarNode, "", value, 0
+		return
+	}
+	m.Content = append(m.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: value},
+	)
+}
+
+// setDefault sets key in the mapping m to value unless it already has a
+// non-empty value.
+func setDefault(m *yaml.Node, key, value string) {
+	if m.Kind != yaml.MappingNode {
+		return
+	}
+	if v := lookup(m, key); v != nil && v.Value != "" {
+		return
+	}
+	setScalar(m, key, value)
+}
@@ -0,0 +1,384 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// LogLevels, LogFormats and Themes are the values accepted for
+	// logging.level, logging.format and dashboard.theme.
+	LogLevels  = []string{string(Debug), string(Info), string(Warn), string(Error)}
+	LogFormats = []string{string(Text), string(JSON)}
+	Themes     = []string{"system", "light", "dark"}
+
+	alertTypes     = []string{"rate", "pattern", "new_error"}
+	webhookFormats = []string{"json", "slack", "discord"}
+)
+
+// FieldError is a problem with a single value in a config file.
+type FieldError struct {
+	Path    string // Dotted path of the value, e.g. logging.level
+	Line    int    // 1-based position in the file; 0 when unknown
+	Column  int
+	Source  string // Environment variable or flag the value came from, if not the file
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("%s (from %s): %s", e.Path, e.Source, e.Message)
+	}
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.Path, e.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s: %s", e.Line, e.Column, e.Path, e.Message)
+}
+
+// ValidationError lists every problem found in a config file.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		lines[i] = "  " + fe.Error()
+	}
+	return "invalid config:\n" + strings.Join(lines, "\n")
+}
+
+// Parse decodes and validates a config document, upgrading it to
+// CurrentVersion in memory first. Keys it doesn't set keep their Defaults.
+// Problems are reported together as a *ValidationError.
+func Parse(data []byte) (*JotlConfig, error) {
+	cfg := Defaults()
+	v := newValidator()
+	if err := v.decode(data, cfg); err != nil {
+		return nil, err
+	}
+	if err := v.check(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validator collects errors and remembers where each value was found so
+// semantic errors can point at it.
+type validator struct {
+	root    *yaml.Node
+	nodes   map[string]*yaml.Node
+	origins map[string]string // Non-file sources of values, by path
+	profile string            // Profile applied to the config being checked
+	errs    []*FieldError
+}
+
+func newValidator() *validator {
+	return &validator{nodes: map[string]*yaml.Node{}, origins: map[string]string{}}
+}
+
+// decode upgrades the document in data and decodes it into cfg, checking
+// its structure on the way.
+func (v *validator) decode(data []byte, cfg *JotlConfig) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	root := documentRoot(&doc)
+	if root == nil {
+		return fmt.Errorf("config file is empty")
+	}
+	if _, err := Upgrade(&doc); err != nil {
+		return err
+	}
+
+	v.root = root
+	v.walk(root, reflect.TypeOf(JotlConfig{}), "")
+	if len(v.errs) > 0 {
+		return &ValidationError{Errors: v.errs}
+	}
+	if err := root.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return nil
+}
+
+// overridden records that the value at path came from origin rather than
+// the file, so errors name the origin instead of a file position.
+func (v *validator) overridden(path, origin string) {
+	for p := range v.nodes {
+		if p == path || strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") {
+			delete(v.nodes, p)
+		}
+	}
+	v.origins[path] = origin
+}
+
+// check validates the values of cfg and of every profile other than the
+// one applied to it.
+func (v *validator) check(cfg *JotlConfig) error {
+	v.validate(cfg)
+	v.checkProfiles()
+	if len(v.errs) == 0 {
+		return nil
+	}
+	// Report in file order; errors without a position go last.
+	slices.SortStableFunc(v.errs, func(a, b *FieldError) int {
+		if (a.Line == 0) != (b.Line == 0) {
+			return b.Line - a.Line
+		}
+		return cmp.Or(a.Line-b.Line, a.Column-b.Column)
+	})
+	return &ValidationError{Errors: v.errs}
+}
+
+func (v *validator) errorf(path string, format string, args ...any) {
+	fe := &FieldError{Path: path, Source: v.origins[path], Message: fmt.Sprintf(format, args...)}
+	if n := v.nodes[path]; n != nil {
+		fe.Line, fe.Column = n.Line, n.Column
+	}
+	v.errs = append(v.errs, fe)
+}
+
+func (v *validator) nodeErrorf(n *yaml.Node, path string, format string, args ...any) {
+	v.errs = append(v.errs, &FieldError{Path: path, Line: n.Line, Column: n.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// walk checks that node fits type t: no unknown keys and scalars of the
+// right kind.
+func (v *validator) walk(n *yaml.Node, t reflect.Type, path string) {
+	v.nodes[path] = n
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Tag == "!!null" {
+		return
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) && n.Kind == yaml.ScalarNode {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			v.nodeErrorf(n, path, "expected a mapping")
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			field, ok := fieldByKey(t, key.Value)
+			if !ok {
+				v.nodeErrorf(key, join(path, key.Value), "unknown key")
+				continue
+			}
+			v.walk(value, field.Type, join(path, key.Value))
+		}
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			v.nodeErrorf(n, path, "expected a list")
+			return
+		}
+		for i, item := range n.Content {
+			v.walk(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			v.nodeErrorf(n, path, "expected a mapping")
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v.walk(n.Content[i+1], t.Elem(), join(path, n.Content[i].Value))
+		}
+	default:
+		if n.Kind != yaml.ScalarNode {
+			v.nodeErrorf(n, path, "expected %s", kindName(t))
+			return
+		}
+		if err := n.Decode(reflect.New(t).Interface()); err != nil {
+			v.nodeErrorf(n, path, "expected %s, got %q", kindName(t), n.Value)
+		}
+	}
+}
+
+// validate checks the values of a decoded config.
+func (v *validator) validate(c *JotlConfig) {
+	if c.Project.Name == "" {
+		v.errorf("project.name", "is required")
+	}
+	if c.Database.Path == "" {
+		v.errorf("database.path", "is required")
+	} else if err := checkSecretRefs(c.Database.Path); err != nil {
+		v.errorf("database.path", "%s", err)
+	}
+
+	v.oneOf("logging.level", string(c.Logging.Level), LogLevels)
+	v.oneOf("logging.format", string(c.Logging.Format), LogFormats)
+	v.oneOf("dashboard.theme", c.Dashboard.Theme, Themes)
+	if c.Dashboard.Port < 1 || c.Dashboard.Port > 65535 {
+		v.errorf("dashboard.port", "%d is not a valid port (1-65535)", c.Dashboard.Port)
+	}
+	if c.Dashboard.RefreshRate < 1 {
+		v.errorf("dashboard.refreshRate", "must be at least 1 second")
+	}
+
+	v.addr("sources.syslog", c.Sources.Syslog)
+	v.addr("sources.http.addr", c.Sources.HTTP.Addr)
+	v.addr("sources.otlp", c.Sources.OTLP)
+
+	names := map[string]bool{}
+	for i, rule := range c.Alerts.Rules {
+		path := fmt.Sprintf("alerts.rules[%d]", i)
+		if rule.Name == "" {
+			v.errorf(path+".name", "is required")
+		} else if names[rule.Name] {
+			v.errorf(path+".name", "duplicate rule name %q", rule.Name)
+		}
+		names[rule.Name] = true
+
+		v.oneOf(path+".type", rule.Type, alertTypes)
+		if rule.Level != "" {
+			v.oneOf(path+".level", string(rule.Level), LogLevels)
+		}
+		if rule.Pattern != "" {
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				v.errorf(path+".pattern", "%v", err)
+			}
+		}
+		v.duration(path+".window", rule.Window)
+		v.duration(path+".cooldown", rule.Cooldown)
+		v.webhooks(path+".webhooks", rule.Webhooks)
+	}
+	v.webhooks("alerts.webhooks", c.Alerts.Webhooks)
+}
+
+// checkProfiles validates each profile applied to the base config, so a
+// broken profile is reported before anyone selects it. Only errors in
+// values the profile sets are reported.
+func (v *validator) checkProfiles() {
+	profiles := lookup(v.root, "profiles")
+	if profiles == nil || profiles.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(profiles.Content); i += 2 {
+		name, node := profiles.Content[i].Value, profiles.Content[i+1]
+		if name == v.profile {
+			continue
+		}
+
+		cfg := Defaults()
+		if err := v.root.Decode(cfg); err != nil {
+			return
+		}
+		if err := node.Decode(cfg); err != nil {
+			continue
+		}
+
+		pv := newValidator()
+		pv.walk(node, reflect.TypeOf(JotlConfig{}), "")
+		pv.errs = nil
+		pv.validate(cfg)
+		for _, fe := range pv.errs {
+			if _, ok := pv.nodes[fe.Path]; ok {
+				fe.Path = "profiles." + name + "." + fe.Path
+				v.errs = append(v.errs, fe)
+			}
+		}
+	}
+}
+
+func (v *validator) oneOf(path, value string, allowed []string) {
+	if !slices.Contains(allowed, value) {
+		v.errorf(path, "%q is not one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+// addr checks a listen address of the form [host]:port.
+func (v *validator) addr(path, addr string) {
+	if addr == "" {
+		return
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		v.errorf(path, "%q is not a host:port address", addr)
+		return
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		v.errorf(path, "%q is not a valid port (1-65535)", port)
+	}
+}
+
+func (v *validator) duration(path, value string) {
+	if value == "" {
+		return
+	}
+	if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+		v.errorf(path, "%q is not a positive duration such as 30s or 5m", value)
+	}
+}
+
+func (v *validator) webhooks(path string, hooks []Webhook) {
+	for i, w := range hooks {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		if w.URL == "" {
+			v.errorf(p, "url is required")
+		}
+		if w.Format != "" {
+			v.oneOf(p+".format", w.Format, webhookFormats)
+		}
+	}
+}
+
+// fieldByKey finds the field of struct t whose yaml name is key.
+func fieldByKey(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if yamlName(f) == key {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
+
+func kindName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	default:
+		return "a string"
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/ebarthur/jotl/cmd/config"
+)
+
+// editConfig opens a copy of the config file in the user's editor until it
+// validates or the user gives up, then replaces the file with it.
+func editConfig(path string) error {
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp("", "jotl-config-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(original); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	for {
+		if err := runEditor(tmp.Name()); err != nil {
+			return err
+		}
+
+		edited, err := os.ReadFile(tmp.Name())
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(edited, original) {
+			fmt.Println(tipMsgStyle.Render("No changes."))
+			return nil
+		}
+
+		if _, err := config.Parse(edited); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Print("Edit again? [Y/n] ")
+			answer, err := in.ReadString('\n')
+			if a := strings.ToLower(strings.TrimSpace(answer)); err != nil || a == "n" || a == "no" {
+				return fmt.Errorf("%s was not changed", filepath.Base(path))
+			}
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, edited, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write config file: %w", err)
+		}
+		fmt.Println(endingMsgStyle.Render("Saved " + path))
+		return nil
+	}
+}
+
+// runEditor opens file in $VISUAL or $EDITOR, falling back to vi (notepad
+// on Windows). The variables may include arguments, e.g. "code --wait".
+func runEditor(file string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], file)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run editor %q: %w", editor, err)
+	}
+	return nil
+}
@@ -0,0 +1,397 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/compose"
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/ebarthur/jotl/cmd/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dbNoWait  bool
+	dbTimeout time.Duration
+	dbFollow  bool
+	dbTail    string
+	dbJSON    bool
+	dbForce   bool
+	dbYes     bool
+)
+
+var dbCommand = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the project database",
+	Long: `The db command manages the database in jotl/config.yaml.
+
+up, down, status and logs drive the PostgreSQL container that jotl init
+added to jotl/docker-compose.yml or to the project's own compose file, using
+` + "`docker compose`" + ` or ` + "`docker-compose`" + `, whichever is installed.
+
+stats, vacuum, backup and restore work with both SQLite and PostgreSQL.
+SQLite backups are database files made with the online backup API; PostgreSQL
+backups are plain SQL with a COPY block per table, which psql can load too.
+migrate-driver moves the data to a database of another driver.`,
+}
+
+var dbUpCommand = &cobra.Command{
+	Use:   "up",
+	Short: "Start the PostgreSQL container, wait until it is healthy and migrate",
+	Args:  cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		currentDir, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		c, err := projectCompose(currentDir, cfg)
+		cobra.CheckErr(err)
+		cobra.CheckErr(c.Up(ctx, os.Stdout))
+		if dbNoWait {
+			fmt.Println(endingMsgStyle.Render("Started PostgreSQL."))
+			return
+		}
+
+		applied, err := waitForDatabase(ctx, c, paths, cfg, dbTimeout)
+		cobra.CheckErr(err)
+		fmt.Println(endingMsgStyle.Render(fmt.Sprintf("PostgreSQL is ready; the schema is at version %d.", applied)))
+	},
+}
+
+var dbDownCommand = &cobra.Command{
+	Use:   "down",
+	Short: "Stop and remove the PostgreSQL container, keeping its data",
+	Args:  cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		currentDir, _, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		c, err := projectCompose(currentDir, cfg)
+		cobra.CheckErr(err)
+		cobra.CheckErr(c.Down(context.Background(), os.Stdout))
+		fmt.Println(endingMsgStyle.Render("Stopped PostgreSQL. Its data is kept in the volume; `jotl db up` starts it again."))
+	},
+}
+
+var dbStatusCommand = &cobra.Command{
+	Use:   "status",
+	Short: "Show the database container and schema version",
+	Args:  cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		currentDir, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+		ctx := context.Background()
+
+		driver, err := store.DriverFromPath(cfg.Database.Path)
+		cobra.CheckErr(err)
+		fmt.Printf("Driver:    %s\n", driver)
+
+		if file := databaseFile(cfg.Database.Path, paths.ConfigDir); file != "" {
+			rel, _ := filepath.Rel(currentDir, file)
+			fmt.Printf("Database:  %s\n", rel)
+		} else if driver == flags.MySQL {
+			fmt.Println("Server:    not managed by jotl")
+		} else if c, err := projectCompose(currentDir, cfg); err != nil {
+			fmt.Printf("Container: %v\n", err)
+		} else if containers, err := c.Status(ctx); err != nil {
+			fmt.Printf("Container: %v\n", err)
+		} else if len(containers) == 0 {
+			fmt.Println("Container: not created; start it with `jotl db up`")
+		} else {
+			fmt.Println()
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "SERVICE\tCONTAINER\tSTATE\tHEALTH\tPORTS")
+			for _, ct := range containers {
+				health := ct.Health
+				if health == "" {
+					health = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", ct.Service, ct.Name, ct.State, health, ct.Ports())
+			}
+			w.Flush()
+			fmt.Println()
+		}
+
+		db, err := store.Open(cfg.Database.Path, paths.ConfigDir)
+		if err != nil {
+			fmt.Printf("Schema:    unavailable (%v)\n", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+		applied, latest, err := db.SchemaVersion(ctx)
+		cobra.CheckErr(err)
+		if applied < latest {
+			fmt.Printf("Schema:    version %d of %d; pending migrations run on the next `jotl dev` or `jotl db up`\n", applied, latest)
+		} else {
+			fmt.Printf("Schema:    version %d, up to date\n", applied)
+		}
+	},
+}
+
+var dbLogsCommand = &cobra.Command{
+	Use:   "logs",
+	Short: "Print the logs of the PostgreSQL container",
+	Args:  cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		currentDir, _, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		c, err := projectCompose(currentDir, cfg)
+		cobra.CheckErr(err)
+		cobra.CheckErr(c.Logs(ctx, os.Stdout, dbFollow, dbTail))
+	},
+}
+
+var dbStatsCommand = &cobra.Command{
+	Use:   "stats",
+	Short: "Show row counts, sizes and the time range of the logs",
+	Args:  cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		_, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+		ctx := context.Background()
+
+		db, err := openStore(ctx, paths, cfg)
+		cobra.CheckErr(err)
+		defer db.Close()
+		st, err := db.Stats(ctx)
+		cobra.CheckErr(err)
+
+		if dbJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			cobra.CheckErr(enc.Encode(st))
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TABLE\tROWS\tSIZE\tINDEXES")
+		for _, t := range st.Tables {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", t.Name, t.Rows, sizeOrDash(t.Size), sizeOrDash(t.IndexSize))
+		}
+		w.Flush()
+
+		if len(st.Levels) > 0 {
+			fmt.Println()
+			w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "LEVEL\tENTRIES")
+			for _, c := range st.Levels {
+				fmt.Fprintf(w, "%s\t%d\n", c.Key, c.Count)
+			}
+			w.Flush()
+		}
+
+		fmt.Println()
+		if st.Oldest != nil {
+			fmt.Printf("Oldest:  %s\n", st.Oldest.Local().Format(time.DateTime))
+			fmt.Printf("Newest:  %s\n", st.Newest.Local().Format(time.DateTime))
+		} else {
+			fmt.Println("Oldest:  no logs yet")
+		}
+		fmt.Printf("Size:    %s\n", sizeOrDash(st.Size))
+	},
+}
+
+var dbVacuumCommand = &cobra.Command{
+	Use:   "vacuum",
+	Short: "Compact the database and refresh its query statistics",
+	Long: `Vacuum returns the space of deleted rows, e.g. after retention pruned old
+logs, to the operating system. It locks the database while it runs, so
+stop jotl dev first on large databases.`,
+	Args: cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		_, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+		ctx := context.Background()
+
+		db, err := openStore(ctx, paths, cfg)
+		cobra.CheckErr(err)
+		defer db.Close()
+
+		before, err := db.Stats(ctx)
+		cobra.CheckErr(err)
+		cobra.CheckErr(db.Vacuum(ctx))
+		after, err := db.Stats(ctx)
+		cobra.CheckErr(err)
+		fmt.Println(endingMsgStyle.Render(fmt.Sprintf("Vacuumed the database: %s before, %s after.", sizeOrDash(before.Size), sizeOrDash(after.Size))))
+	},
+}
+
+var dbBackupCommand = &cobra.Command{
+	Use:   "backup <file>",
+	Short: "Write a consistent copy of the database to a file",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		_, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		file := args[0]
+		if _, err := os.Stat(file); err == nil {
+			if !dbForce {
+				cobra.CheckErr(fmt.Errorf("%s already exists; pass --force to overwrite it", file))
+			}
+			cobra.CheckErr(os.Remove(file))
+		}
+
+		db, err := openStore(ctx, paths, cfg)
+		cobra.CheckErr(err)
+		defer db.Close()
+		if err := db.Backup(ctx, file); err != nil {
+			os.Remove(file)
+			cobra.CheckErr(err)
+		}
+
+		size := "-"
+		if info, err := os.Stat(file); err == nil {
+			size = utils.FormatBytes(uint64(info.Size()))
+		}
+		fmt.Println(endingMsgStyle.Render(fmt.Sprintf("Backed up the database to %s (%s).", file, size)))
+		fmt.Println(tipMsgStyle.Render("The backup holds your logs; keep it private. Restore it with `jotl db restore " + file + "`."))
+	},
+}
+
+var dbRestoreCommand = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Replace the data in the database with a backup",
+	Long: `Restore replaces every log, run, checkpoint and notification in the
+database with those of a backup made by jotl db backup with the same driver,
+then applies migrations newer than the backup.`,
+	Args: cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		_, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		file := args[0]
+		_, err = os.Stat(file)
+		cobra.CheckErr(err)
+		if !dbYes {
+			fmt.Printf("Replace all data in the database with %s? [y/N] ", file)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+				fmt.Println(tipMsgStyle.Render("Nothing was restored."))
+				return
+			}
+		}
+
+		db, err := openStore(ctx, paths, cfg)
+		cobra.CheckErr(err)
+		defer db.Close()
+		cobra.CheckErr(db.Restore(ctx, file))
+
+		applied, _, err := db.SchemaVersion(ctx)
+		cobra.CheckErr(err)
+		fmt.Println(endingMsgStyle.Render(fmt.Sprintf("Restored %s; the schema is at version %d.", file, applied)))
+	},
+}
+
+// sizeOrDash formats a size in bytes, or - where the database doesn't
+// report it.
+func sizeOrDash(n int64) string {
+	if n <= 0 {
+		return "-"
+	}
+	return utils.FormatBytes(uint64(n))
+}
+
+// projectCompose returns the compose file that runs the project database.
+func projectCompose(currentDir string, cfg *config.JotlConfig) (*compose.Compose, error) {
+	if driver, _ := store.DriverFromPath(cfg.Database.Path); driver != flags.Postgres {
+		return nil, fmt.Errorf("the project uses %s, which doesn't run in a container", driver)
+	}
+	c, err := compose.New(currentDir)
+	if errors.Is(err, config.ErrNoCompose) {
+		return nil, fmt.Errorf("%w; jotl db only manages the PostgreSQL container created by `jotl init`", err)
+	}
+	return c, err
+}
+
+// startDatabase starts the PostgreSQL container of the project unless it
+// is already running, then waits for it and applies pending migrations.
+func startDatabase(ctx context.Context, currentDir string, paths utils.ConfigPaths, cfg *config.JotlConfig, out io.Writer) error {
+	c, err := projectCompose(currentDir, cfg)
+	if err != nil {
+		return err
+	}
+	if ct, ok, err := c.Postgres(ctx); err != nil || !ok || ct.State != "running" {
+		fmt.Fprintln(out, tipMsgStyle.Render("Starting PostgreSQL..."))
+		if err := c.Up(ctx, out); err != nil {
+			return err
+		}
+	}
+	_, err = waitForDatabase(ctx, c, paths, cfg, dbTimeout)
+	return err
+}
+
+// waitForDatabase waits for the container's healthcheck, then until the
+// database accepts connections, and applies pending migrations. It returns
+// the schema version.
+func waitForDatabase(ctx context.Context, c *compose.Compose, paths utils.ConfigPaths, cfg *config.JotlConfig, timeout time.Duration) (int, error) {
+	deadline := time.Now().Add(timeout)
+	if err := c.WaitHealthy(ctx, timeout); err != nil {
+		return 0, err
+	}
+
+	// The image restarts the server after its first initialization, so a
+	// healthy container may still refuse connections for a moment.
+	for {
+		db, err := openStore(ctx, paths, cfg)
+		if err == nil {
+			defer db.Close()
+			applied, _, err := db.SchemaVersion(ctx)
+			return applied, err
+		}
+		if time.Now().After(deadline) {
+			return 0, err
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(dbCommand)
+	dbCommand.AddCommand(dbUpCommand, dbDownCommand, dbStatusCommand, dbLogsCommand,
+		dbStatsCommand, dbVacuumCommand, dbBackupCommand, dbRestoreCommand)
+	dbUpCommand.Flags().BoolVar(&dbNoWait, "no-wait", false, "Return once the container is started, without waiting or migrating")
+	dbUpCommand.Flags().DurationVar(&dbTimeout, "timeout", 60*time.Second, "How long to wait for PostgreSQL to become healthy")
+	dbLogsCommand.Flags().BoolVarP(&dbFollow, "follow", "f", false, "Follow the logs until interrupted")
+	dbLogsCommand.Flags().StringVar(&dbTail, "tail", "100", "Number of lines to show from the end, or all")
+	dbStatsCommand.Flags().BoolVar(&dbJSON, "json", false, "Print the statistics as JSON")
+	dbBackupCommand.Flags().BoolVar(&dbForce, "force", false, "Overwrite the file if it exists")
+	dbRestoreCommand.Flags().BoolVarP(&dbYes, "yes", "y", false, "Don't ask for confirmation")
+}
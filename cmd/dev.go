@@ -1,7 +1,20 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
 	"github.com/charmbracelet/glamour"
+	"github.com/ebarthur/jotl/cmd/alert"
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/ingest"
+	"github.com/ebarthur/jotl/cmd/metrics"
+	"github.com/ebarthur/jotl/cmd/notify"
+	"github.com/ebarthur/jotl/cmd/store"
 	"github.com/spf13/cobra"
 )
 
@@ -16,8 +29,77 @@ When running, it will:
 For npm/node projects, add to your package.json scripts:
 "dev": "jotl dev --watch & npm run dev"
 "start": "jotl dev & npm start"
+
+Log files written by other programs can be followed like ` + "`tail -F`" + `:
+jotl dev --file 'logs/*.log'
+
+Patterns can also be listed under ` + "`sources.files`" + ` in jotl/config.yaml.
+Rotated and truncated files are handled, and read offsets are checkpointed
+in the database so a restart neither re-ingests nor skips lines.
+
+Programs that only log to syslog can send RFC 5424 or RFC 3164 messages over
+UDP or TCP:
+jotl dev --syslog :5514
+
+Browsers and other tools can push JSON events to an HTTP endpoint, configured
+under ` + "`sources.http`" + ` (see ` + "`jotl help ingest`" + `):
+jotl dev --http 127.0.0.1:8090
+
+Services instrumented with OpenTelemetry can export logs over OTLP/HTTP
+(protobuf or JSON). Point the exporter at ` + "`http://localhost:4318`" + `:
+jotl dev --otlp :4318
+
+Containers can be followed through the Docker Engine socket, by name or ID or
+by label, e.g. everything in a compose project:
+jotl dev --docker api --docker label=com.docker.compose.project=myapp
+
+Alert rules under ` + "`alerts`" + ` in jotl/config.yaml are evaluated as logs arrive. A firing
+rule rings the terminal bell, prints a banner, and POSTs to the configured webhooks:
+
+` + "```yaml" + `
+alerts:
+  webhooks: ["https://hooks.example.com/jotl"]
+  rules:
+    - name: error-spike
+      type: rate
+      threshold: 10
+      window: 1m
+    - name: panic
+      type: pattern
+      pattern: "panic:"
+    - name: new-error
+      type: new_error
+      cooldown: 10m
+` + "```" + `
+
+Each rule notifies at most once per cooldown (5m by default) for the same error;
+alerts held back in the meantime are counted in the next notification.
+
+Webhooks receive the alert as JSON, or Slack and Discord messages when the URL
+points at either service (or ` + "`format: slack|discord`" + ` is set). A Go ` + "`template`" + `
+can shape the body instead. Deliveries are kept in the database and retried
+with exponential backoff, also across restarts. See ` + "`jotl help alerts`" + `.
+
+Projects on the PostgreSQL container created by ` + "`jotl init`" + ` can start it on
+the way, waiting until it is healthy:
+jotl dev --start-db --file 'logs/*.log'
+
+Prometheus can scrape metrics derived from the logs (lines per level, source and
+status class, and request latency from access logs) from a separate listener:
+jotl dev --metrics-addr :9464
 `)
 
+var (
+	devFiles   []string
+	devSyslog  string
+	devHTTP    string
+	devOTLP    string
+	devDocker  []string
+	devEnv     string
+	devMetrics string
+	devStartDB bool
+)
+
 var devCommand = &cobra.Command{
 	Use:   "dev",
 	Short: "Start logging console output to database with optional real-time display",
@@ -27,11 +109,141 @@ var devCommand = &cobra.Command{
 	}(),
 
 	Run: func(cmd *cobra.Command, args []string) {
-		//
+		currentDir, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if devStartDB {
+			cobra.CheckErr(startDatabase(ctx, currentDir, paths, cfg, os.Stdout))
+		}
+
+		db, err := openStore(ctx, paths, cfg)
+		cobra.CheckErr(err)
+		defer db.Close()
+
+		var sources []ingest.Source
+
+		patterns := append(append([]string{}, cfg.Sources.Files...), devFiles...)
+		if len(patterns) > 0 {
+			sources = append(sources, &ingest.FileSource{
+				Patterns:    patterns,
+				BaseDir:     currentDir,
+				Checkpoints: db,
+			})
+		}
+
+		syslogAddr := cfg.Sources.Syslog
+		if devSyslog != "" {
+			syslogAddr = devSyslog
+		}
+		if syslogAddr != "" {
+			sources = append(sources, &ingest.SyslogSource{Addr: syslogAddr})
+		}
+
+		httpAddr := cfg.Sources.HTTP.Addr
+		if devHTTP != "" {
+			httpAddr = devHTTP
+		}
+		if httpAddr != "" {
+			sources = append(sources, &ingest.HTTPSource{
+				Addr:           httpAddr,
+				AllowedOrigins: cfg.Sources.HTTP.AllowedOrigins,
+				Token:          cfg.Sources.HTTP.Token,
+			})
+		}
+
+		otlpAddr := cfg.Sources.OTLP
+		if devOTLP != "" {
+			otlpAddr = devOTLP
+		}
+		if otlpAddr != "" {
+			sources = append(sources, &ingest.OTLPSource{Addr: otlpAddr})
+		}
+
+		selectors := append(append([]string{}, cfg.Sources.Docker...), devDocker...)
+		if len(selectors) > 0 {
+			sources = append(sources, &ingest.DockerSource{
+				Selectors:   selectors,
+				Checkpoints: db,
+			})
+		}
+
+		if len(sources) == 0 {
+			cobra.CheckErr("no log sources configured. Use --file, --syslog, --http, --otlp, --docker or add `sources` to jotl/config.yaml")
+		}
+
+		names := make([]string, len(sources))
+		for i, src := range sources {
+			names[i] = src.Name()
+		}
+		fmt.Println(endingMsgStyle.Render(fmt.Sprintf("Jotl is capturing logs from %s", strings.Join(names, "; "))))
+		fmt.Println(tipMsgStyle.Render("Press Ctrl+C to stop."))
+
+		runID, endRun, err := startRun(ctx, db, "dev", devEnv, sources)
+		cobra.CheckErr(err)
+		defer endRun()
+		fmt.Println(tipMsgStyle.Render(fmt.Sprintf("Run %s; share it with `jotl bundle create --run %s`.", runID, runID)))
+
+		pipeline := &ingest.Pipeline{
+			Store:    db,
+			Env:      devEnv,
+			MinLevel: flags.LogLevel(cfg.Logging.Level),
+			RunID:    runID,
+		}
+
+		// Webhooks are delivered from the outbox until alerts raised during
+		// the final flush have been queued, and retried on the next run.
+		sender := notify.NewSender(db)
+		senderCtx, stopSender := context.WithCancel(context.Background())
+		go sender.Run(senderCtx)
+		defer stopSender()
+
+		var observers []func([]store.Entry)
+		pipeline.OnInsert = func(entries []store.Entry) {
+			for _, observe := range observers {
+				observe(entries)
+			}
+		}
+
+		if devMetrics != "" {
+			collector := metrics.New()
+			observers = append(observers, collector.Observe)
+			go func() {
+				if err := collector.Serve(ctx, devMetrics); err != nil {
+					fmt.Fprintln(os.Stderr, "metrics:", err)
+				}
+			}()
+			fmt.Println(tipMsgStyle.Render(fmt.Sprintf("Serving Prometheus metrics on http://%s%s", devMetrics, metrics.Path)))
+		}
+
+		if len(cfg.Alerts.Rules) > 0 {
+			engine, err := alert.NewEngine(cfg.Alerts,
+				&alert.Terminal{Out: os.Stderr, Silent: cfg.Alerts.Silent},
+				sender,
+			)
+			cobra.CheckErr(err)
+			cobra.CheckErr(engine.LoadFingerprints(ctx, db))
+
+			go engine.Run()
+			defer engine.Close()
+			observers = append(observers, engine.Observe)
+			fmt.Println(tipMsgStyle.Render(fmt.Sprintf("Watching %d alert rule(s).", engine.Rules())))
+		}
+
+		cobra.CheckErr(pipeline.Run(ctx, sources...))
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(devCommand)
-
+	devCommand.Flags().StringArrayVar(&devFiles, "file", nil, "Follow log files matching a glob pattern (repeatable)")
+	devCommand.Flags().StringVar(&devSyslog, "syslog", "", "Listen for syslog messages on this address (UDP and TCP)")
+	devCommand.Flags().StringVar(&devHTTP, "http", "", "Accept log events over HTTP on this address")
+	devCommand.Flags().StringVar(&devOTLP, "otlp", "", "Receive OpenTelemetry logs over OTLP/HTTP on this address")
+	devCommand.Flags().StringArrayVar(&devDocker, "docker", nil, "Stream logs of a container by name, ID or label=key=value (repeatable)")
+	devCommand.Flags().StringVarP(&devEnv, "env", "e", "development", "Environment recorded on captured logs")
+	devCommand.Flags().StringVar(&devMetrics, "metrics-addr", "", "Serve Prometheus metrics derived from the logs on this address")
+	devCommand.Flags().BoolVar(&devStartDB, "start-db", false, "Start the PostgreSQL container with \"jotl db up\" first if it isn't running")
 }
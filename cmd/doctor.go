@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ebarthur/jotl/cmd/doctor"
+	"github.com/ebarthur/jotl/cmd/utils"
+	"github.com/spf13/cobra"
+)
+
+var doctorJSON bool
+
+var doctorStatusStyles = map[doctor.Status]lipgloss.Style{
+	doctor.Pass: lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Bold(true),
+	doctor.Warn: lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Bold(true),
+	doctor.Fail: lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true),
+}
+
+var doctorCommand = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the Jotl project setup and suggest fixes",
+	Long: `The doctor command checks the Jotl project in the current directory:
+the config.yaml schema, database connectivity and migrations, jotl/.env,
+plaintext credentials, the PostgreSQL container, free disk space for SQLite
+and git's user.email.
+
+Every check passes, warns or fails with a suggested fix. The command exits
+with status 1 when a check fails.`,
+	Args: cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		currentDir, err := os.Getwd()
+		cobra.CheckErr(err)
+
+		layers, err := configLayers(utils.GetConfigPaths(currentDir))
+		cobra.CheckErr(err)
+		results := doctor.Run(context.Background(), currentDir, layers)
+
+		if doctorJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			cobra.CheckErr(enc.Encode(results))
+		} else {
+			for _, r := range results {
+				status := doctorStatusStyles[r.Status].Render(fmt.Sprintf("%-4s", r.Status))
+				fmt.Printf(" %s  %-8s %s\n", status, r.Name, r.Message)
+				if r.Fix != "" && r.Status != doctor.Pass {
+					fmt.Println(tipMsgStyle.Render(fmt.Sprintf("               %s", r.Fix)))
+				}
+			}
+		}
+
+		if doctor.Failed(results) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCommand)
+	doctorCommand.Flags().BoolVar(&doctorJSON, "json", false, "Print the results as JSON")
+}
@@ -0,0 +1,244 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/compose"
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/ebarthur/jotl/cmd/utils"
+)
+
+const (
+	// Free space below which SQLite writes are likely to fail soon.
+	minFreeSpace = 100 << 20
+	// Free space below which a warning is shown.
+	lowFreeSpace = 1 << 30
+
+	commandTimeout = 10 * time.Second
+)
+
+func checkConfig(ctx context.Context, p *Project) Result {
+	if !exists(p.Paths.ConfigFile) {
+		return fail("Run `jotl init` in the project root.", "%s not found", p.Paths.ConfigFile)
+	}
+
+	cfg, err := config.LoadConfig(p.Paths.ConfigFile)
+	var invalid *config.ValidationError
+	if errors.As(err, &invalid) {
+		problems := make([]string, len(invalid.Errors))
+		for i, fe := range invalid.Errors {
+			problems[i] = fe.Error()
+		}
+		return fail("Correct these values in jotl/config.yaml.", "%s", strings.Join(problems, "; "))
+	}
+	if err != nil {
+		return fail("Fix jotl/config.yaml, or run `jotl config migrate` if it was written by another jotl version.", "%v", err)
+	}
+
+	resolved, err := config.Resolve(p.Layers)
+	if err != nil {
+		return fail("Fix the JOTL_* environment variables, jotl/.env or flags that override config.yaml.", "%v", err)
+	}
+
+	p.File, p.Config = cfg, resolved.Config
+	return pass("%s is valid", filepath.Base(p.Paths.ConfigFile))
+}
+
+func checkDatabase(ctx context.Context, p *Project) Result {
+	if p.Config == nil {
+		return skipped()
+	}
+
+	path := p.Config.Database.Path
+	driver, err := store.DriverFromPath(path)
+	if err != nil {
+		return fail("Set database.path in jotl/config.yaml to a postgres:// or mysql:// URL, a file: path or a duckdb: path.", "%v", err)
+	}
+
+	fix := "Check that jotl/db exists and is writable."
+	switch driver {
+	case flags.Sqlite, flags.DuckDB:
+		if driver == flags.DuckDB && !store.DuckDBAvailable {
+			return fail("Use a jotl built with `go build -tags duckdb`.", "%v", store.ErrNoDuckDB)
+		}
+		if file := databaseFile(driver, path, p.Paths.ConfigDir); !exists(file) {
+			return warn("It is created on the next `jotl dev`; check database.path if you expected existing logs.", "%s doesn't exist yet", file)
+		}
+	case flags.Postgres:
+		fix = "Start PostgreSQL with `jotl db up` and check database.path."
+	case flags.MySQL:
+		fix = fmt.Sprintf("Check that the MySQL server is running, the database exists and %s in jotl/.env is the user's password.", config.MySQLPasswordEnv)
+	}
+
+	db, err := store.Open(path, p.Paths.ConfigDir)
+	if err != nil {
+		return fail(fix, "%v", err)
+	}
+	defer db.Close()
+
+	applied, latest, err := db.SchemaVersion(ctx)
+	switch {
+	case err != nil:
+		return fail(fix, "%v", err)
+	case applied > latest:
+		return fail("Upgrade jotl; this database was migrated by a newer version.", "schema version %d is newer than this jotl supports (%d)", applied, latest)
+	case applied < latest:
+		return warn("Run `jotl runs` (or any command that opens the database) to apply them.", "connected to %s, %d migration(s) pending", driver, latest-applied)
+	}
+	return pass("connected to %s, schema is up to date (version %d)", driver, applied)
+}
+
+func checkEnv(ctx context.Context, p *Project) Result {
+	if p.Config == nil {
+		return skipped()
+	}
+
+	envPath := filepath.Join(p.Paths.ConfigDir, ".env")
+	env, err := config.ReadDotEnv(envPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return warn("Create jotl/.env with DB_CONNECTION_STRING and APP_NAME, or run `jotl init` again.", "%s not found", envPath)
+	}
+	if err != nil {
+		return fail("Check the permissions of jotl/.env.", "%v", err)
+	}
+
+	var problems []string
+	if v, ok := env["DB_CONNECTION_STRING"]; ok && v != p.File.Database.Path {
+		problems = append(problems, "DB_CONNECTION_STRING overrides a different database.path")
+	}
+	if v, ok := env["APP_NAME"]; ok && v != p.File.Project.Name {
+		problems = append(problems, "APP_NAME overrides a different project.name")
+	}
+	if len(problems) > 0 {
+		return warn("Make jotl/.env and jotl/config.yaml agree, e.g. with `jotl config set`; .env wins until then.", "%s", strings.Join(problems, "; "))
+	}
+	return pass(".env matches config.yaml")
+}
+
+func checkSecrets(ctx context.Context, p *Project) Result {
+	if p.File == nil {
+		return skipped()
+	}
+
+	var problems []string
+	for _, path := range p.File.PlaintextSecrets() {
+		problems = append(problems, fmt.Sprintf("%s holds a plaintext password", path))
+	}
+	envPath := filepath.Join(p.Paths.ConfigDir, ".env")
+	if env, err := config.ReadDotEnv(envPath); err == nil && runtime.GOOS != "windows" && hasSecrets(env) {
+		if info, err := os.Stat(envPath); err == nil && info.Mode().Perm()&0077 != 0 {
+			problems = append(problems, fmt.Sprintf("jotl/.env holds secrets but is readable by others (mode %04o)", info.Mode().Perm()))
+		}
+	}
+	if len(problems) > 0 {
+		return warn(fmt.Sprintf("Keep passwords in jotl/.env, reference them as ${%s} or ${file:PATH} in database.path, and run `chmod 600 jotl/.env`.", config.PasswordEnv),
+			"%s", strings.Join(problems, "; "))
+	}
+	return pass("no plaintext credentials in config.yaml")
+}
+
+// hasSecrets reports whether a jotl/.env holds a password.
+func hasSecrets(env map[string]string) bool {
+	for _, name := range []string{config.PasswordEnv, config.MySQLPasswordEnv} {
+		if env[name] != "" {
+			return true
+		}
+	}
+	return config.PlaintextPassword(env["DB_CONNECTION_STRING"])
+}
+
+func checkDocker(ctx context.Context, p *Project) Result {
+	if p.Config == nil {
+		return skipped()
+	}
+	if driver, _ := store.DriverFromPath(p.Config.Database.Path); driver != flags.Postgres {
+		return pass("not needed for %s", driver)
+	}
+
+	c, err := compose.New(p.Dir)
+	if errors.Is(err, config.ErrNoCompose) {
+		return warn("Ignore this if you run PostgreSQL yourself.", "%v", err)
+	}
+	if errors.Is(err, compose.ErrNoDocker) {
+		return warn("Install Docker to run the bundled PostgreSQL container.", "docker compose is not installed")
+	}
+	if err != nil {
+		return warn("Check the compose file.", "%v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+
+	ct, found, err := c.Postgres(ctx)
+	if err != nil {
+		return warn("Make sure the Docker daemon is running.", "could not query docker compose: %v", err)
+	}
+	if !found || ct.State != "running" {
+		return fail("Run `jotl db up`.", "%s container is not running", c.Service)
+	}
+	return pass("%s container is running", c.Service)
+}
+
+func checkDisk(ctx context.Context, p *Project) Result {
+	if p.Config == nil {
+		return skipped()
+	}
+	driver, _ := store.DriverFromPath(p.Config.Database.Path)
+	if driver != flags.Sqlite && driver != flags.DuckDB {
+		return pass("not needed for %s", driver)
+	}
+
+	file := databaseFile(driver, p.Config.Database.Path, p.Paths.ConfigDir)
+	dir := filepath.Dir(file)
+	if !exists(dir) {
+		dir = p.Dir
+	}
+
+	free, err := freeSpace(dir)
+	if err != nil {
+		return warn("", "could not determine free space: %v", err)
+	}
+
+	var used int64
+	for _, suffix := range []string{"", "-wal", "-shm", ".wal"} {
+		if info, err := os.Stat(file + suffix); err == nil {
+			used += info.Size()
+		}
+	}
+
+	switch {
+	case free < minFreeSpace:
+		return fail("Free up disk space or move the database with database.path.", "only %s free, the database uses %s", utils.FormatBytes(free), utils.FormatBytes(uint64(used)))
+	case free < lowFreeSpace:
+		return warn("Free up disk space or prune old logs.", "%s free, the database uses %s", utils.FormatBytes(free), utils.FormatBytes(uint64(used)))
+	}
+	return pass("%s free, the database uses %s", utils.FormatBytes(free), utils.FormatBytes(uint64(used)))
+}
+
+func checkGit(ctx context.Context, p *Project) Result {
+	ok, err := utils.CheckGitConfig("user.email")
+	if err != nil {
+		return warn("Install git to use `jotl init --git`.", "git is not available: %v", err)
+	}
+	if !ok {
+		return warn(`Run git config --global user.email "you@example.com".`, "user.email is not set; `jotl init --git` can't commit")
+	}
+	return pass("user.email is set")
+}
+
+// databaseFile returns the file of a SQLite or DuckDB database.
+func databaseFile(driver flags.Database, path, configDir string) string {
+	if driver == flags.DuckDB {
+		return store.DuckDBFile(path, configDir)
+	}
+	return store.SQLiteFile(path, configDir)
+}
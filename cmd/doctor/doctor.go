@@ -0,0 +1,103 @@
+// Package doctor diagnoses common problems with the setup of a Jotl project.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/utils"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// Result is the outcome of a single check together with a suggested fix.
+type Result struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix,omitempty"`
+}
+
+// Project is the project under inspection. File is config.yaml as written
+// and Config the effective configuration after jotl/.env, environment
+// variables and flags. Both are nil when the config could not be loaded, in
+// which case checks that depend on it are skipped.
+type Project struct {
+	Dir    string
+	Paths  utils.ConfigPaths
+	Layers config.Layers
+	File   *config.JotlConfig
+	Config *config.JotlConfig
+}
+
+// A check inspects one aspect of the project.
+type check struct {
+	name string
+	run  func(ctx context.Context, p *Project) Result
+}
+
+// checks run in order; checkConfig fills in Project.Config for the rest.
+var checks = []check{
+	{"config", checkConfig},
+	{"database", checkDatabase},
+	{"env", checkEnv},
+	{"secrets", checkSecrets},
+	{"docker", checkDocker},
+	{"disk", checkDisk},
+	{"git", checkGit},
+}
+
+// Run performs every check against the project in dir, whose config is
+// resolved from layers.
+func Run(ctx context.Context, dir string, layers config.Layers) []Result {
+	p := &Project{Dir: dir, Paths: utils.GetConfigPaths(dir), Layers: layers}
+
+	results := make([]Result, 0, len(checks))
+	for _, c := range checks {
+		r := c.run(ctx, p)
+		r.Name = c.name
+		results = append(results, r)
+	}
+	return results
+}
+
+// Failed reports whether any of results failed.
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+func pass(format string, args ...any) Result {
+	return Result{Status: Pass, Message: fmt.Sprintf(format, args...)}
+}
+
+func warn(fix, format string, args ...any) Result {
+	return Result{Status: Warn, Message: fmt.Sprintf(format, args...), Fix: fix}
+}
+
+func fail(fix, format string, args ...any) Result {
+	return Result{Status: Fail, Message: fmt.Sprintf(format, args...), Fix: fix}
+}
+
+// skipped is reported by checks that need a loaded config.
+func skipped() Result {
+	return warn("Fix config.yaml first.", "skipped, config.yaml could not be loaded")
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
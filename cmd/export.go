@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/ebarthur/jotl/cmd/export"
+	"github.com/spf13/cobra"
+)
+
+const exportMsg = (`The export command writes stored logs to a file for sharing or analysis.
+
+Supported formats:
+- ` + "`csv`" + `: one row per entry, structured fields as a JSON column
+- ` + "`ndjson`" + `: one JSON object per line, the same shape the studio API returns
+- ` + "`parquet`" + `: a columnar file that DuckDB, pandas and Spark load directly
+
+The format and compression are inferred from the output name (` + "`logs.csv.gz`" + `,
+` + "`logs.ndjson.zst`" + `, ` + "`logs.parquet`" + `) or set with ` + "`--format`" + ` and ` + "`--compress`" + `.
+Entries are streamed oldest first, so exports of any size use little memory.
+With ` + "`--limit N`" + `, the newest N entries are exported, still oldest first.
+
+Examples:
+jotl export -o errors.csv --min-level error --since 24h
+jotl export --format ndjson --compress zstd -o logs.ndjson.zst
+jotl export --format ndjson --source docker:api | jq .message
+
+The studio offers the same as a download from ` + "`/api/export?format=csv`" + `.`)
+
+var (
+	exportFilter   filterFlags
+	exportFormat   string
+	exportCompress string
+	exportOutput   string
+)
+
+var exportCommand = &cobra.Command{
+	Use:   "export",
+	Short: "Export stored logs to CSV, NDJSON or Parquet",
+	Long: func() string {
+		out, _ := glamour.Render(exportMsg, "dark")
+		return out
+	}(),
+	Args: cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		_, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		f, err := exportFilter.filter()
+		cobra.CheckErr(err)
+
+		format, compression := export.Infer(exportOutput)
+		if cmd.Flags().Changed("format") || format == "" {
+			format = exportFormat
+		}
+		if cmd.Flags().Changed("compress") {
+			compression = exportCompress
+		}
+		if format == export.Parquet && (exportOutput == "" || exportOutput == "-") {
+			cobra.CheckErr("parquet exports need an output file, e.g. -o logs.parquet")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		db, err := openStore(ctx, paths, cfg)
+		cobra.CheckErr(err)
+		defer db.Close()
+
+		var out io.Writer = os.Stdout
+		var file *os.File
+		if exportOutput != "" && exportOutput != "-" {
+			file, err = os.Create(exportOutput)
+			cobra.CheckErr(err)
+			defer file.Close()
+			out = file
+		}
+		buf := bufio.NewWriterSize(out, 256*1024)
+
+		w, err := export.NewWriter(buf, format, compression)
+		cobra.CheckErr(err)
+
+		n, err := export.Export(ctx, db, f, w)
+		if err == nil {
+			err = buf.Flush()
+		}
+		if err == nil && file != nil {
+			err = file.Close()
+		}
+		if err != nil && file != nil {
+			os.Remove(exportOutput)
+		}
+		cobra.CheckErr(err)
+
+		if file != nil {
+			fmt.Println(endingMsgStyle.Render(fmt.Sprintf("Exported %d entries to %s", n, exportOutput)))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCommand)
+	exportFilter.register(exportCommand)
+	exportCommand.Flags().StringVarP(&exportOutput, "output", "o", "", "File to write; standard output when empty or -")
+	exportCommand.Flags().StringVarP(&exportFormat, "format", "f", export.NDJSON, "Output format: "+strings.Join(export.Formats, ", "))
+	exportCommand.Flags().StringVar(&exportCompress, "compress", "", "Compress the output with gzip or zstd")
+}
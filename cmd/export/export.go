@@ -0,0 +1,184 @@
+// Package export writes log entries to files in formats other tools can
+// load: CSV, newline-delimited JSON and Apache Parquet.
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Formats.
+const (
+	CSV     = "csv"
+	NDJSON  = "ndjson"
+	Parquet = "parquet"
+)
+
+// Compression codecs.
+const (
+	None = ""
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// Formats lists the supported export formats.
+var Formats = []string{CSV, NDJSON, Parquet}
+
+// Writer encodes entries one at a time.
+type Writer interface {
+	Write(e store.Entry) error
+	// Close flushes buffered data. It doesn't close the underlying writer.
+	Close() error
+}
+
+// NewWriter returns a writer encoding entries in format onto w. CSV and
+// NDJSON output is compressed as a whole; Parquet compresses each column
+// chunk with the codec instead, so the file stays readable by other tools.
+func NewWriter(w io.Writer, format, compression string) (Writer, error) {
+	switch compression {
+	case None, Gzip, Zstd:
+	default:
+		return nil, fmt.Errorf("unknown compression %q (want %s or %s)", compression, Gzip, Zstd)
+	}
+
+	switch format {
+	case Parquet:
+		return newParquetWriter(w, compression), nil
+	case CSV, NDJSON:
+	default:
+		return nil, fmt.Errorf("unknown format %q (want %s)", format, strings.Join(Formats, ", "))
+	}
+
+	cw, err := compressor(w, compression)
+	if err != nil {
+		return nil, err
+	}
+	if format == CSV {
+		return &closeBoth{Writer: newCSVWriter(cw), out: cw}, nil
+	}
+	return &closeBoth{Writer: newNDJSONWriter(cw), out: cw}, nil
+}
+
+// Export writes every entry matching f to w, oldest first, and returns how
+// many were written. With a limit, the newest f.Limit entries are written,
+// still oldest first. Entries are streamed rather than loaded into memory.
+func Export(ctx context.Context, db store.Store, f store.Filter, w Writer) (int, error) {
+	if f.Limit > 0 {
+		// Walk the newest entries to find the oldest of them, then export
+		// from there.
+		var first int64
+		if err := db.Each(ctx, f, func(e store.Entry) error {
+			first = e.ID
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+		if first == 0 {
+			return 0, w.Close()
+		}
+		f.AfterID = first - 1
+	}
+	f.Oldest = true
+	n := 0
+	err := db.Each(ctx, f, func(e store.Entry) error {
+		n++
+		return w.Write(e)
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, w.Close()
+}
+
+// Infer guesses the format and compression from a file name such as
+// logs.csv.gz or logs.parquet.
+func Infer(path string) (format, compression string) {
+	name := strings.ToLower(filepath.Base(path))
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		compression, name = Gzip, strings.TrimSuffix(name, ".gz")
+	case strings.HasSuffix(name, ".zst"):
+		compression, name = Zstd, strings.TrimSuffix(name, ".zst")
+	}
+	switch filepath.Ext(name) {
+	case ".csv":
+		format = CSV
+	case ".ndjson", ".jsonl", ".json":
+		format = NDJSON
+	case ".parquet":
+		format = Parquet
+	}
+	return format, compression
+}
+
+// Extension returns the file extension for format and compression, e.g.
+// ".csv.gz".
+func Extension(format, compression string) string {
+	ext := "." + format
+	if format == Parquet {
+		return ext
+	}
+	switch compression {
+	case Gzip:
+		ext += ".gz"
+	case Zstd:
+		ext += ".zst"
+	}
+	return ext
+}
+
+// ContentType returns the MIME type of an export.
+func ContentType(format, compression string) string {
+	if format != Parquet {
+		switch compression {
+		case Gzip:
+			return "application/gzip"
+		case Zstd:
+			return "application/zstd"
+		}
+	}
+	switch format {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case NDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// nopCloser adapts an uncompressed writer.
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func compressor(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	default:
+		return nopCloser{w}, nil
+	}
+}
+
+// closeBoth closes the encoder, then the compressor underneath it.
+type closeBoth struct {
+	Writer
+	out io.Closer
+}
+
+func (c *closeBoth) Close() error {
+	if err := c.Writer.Close(); err != nil {
+		return err
+	}
+	return c.out.Close()
+}
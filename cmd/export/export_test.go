@@ -0,0 +1,269 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/parquet-go/parquet-go"
+)
+
+var base = time.Date(2026, 3, 1, 10, 0, 0, 123456000, time.UTC)
+
+func openStore(t *testing.T) store.Store {
+	t.Helper()
+	db, err := store.Open("jotl.db", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := []store.Entry{
+		{Timestamp: base, Level: flags.Info, Message: "server started", Source: "file:app.log", Env: "dev", Service: "api", RunID: "run-1"},
+		{Timestamp: base.Add(time.Second), Level: flags.Error, Message: "query failed, \"users\"\nretrying", Source: "file:app.log", Env: "dev", StatusCode: 500, TraceID: "4bf9", SpanID: "00f0", RunID: "run-1", Fields: map[string]any{"attempt": float64(2)}},
+		{Timestamp: base.Add(time.Minute), Level: flags.Warn, Message: "slow request", Source: "docker:worker", RunID: "run-2"},
+		{Timestamp: base.Add(2 * time.Minute), Level: flags.Debug, Message: "cache warm", Source: "docker:worker"},
+	}
+	if err := db.Insert(context.Background(), entries, nil); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// export runs Export with f and returns the file it wrote.
+func export(t *testing.T, db store.Store, f store.Filter, format, compression string) ([]byte, int) {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, compression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := Export(context.Background(), db, f, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), n
+}
+
+func decompress(t *testing.T, data []byte, compression string) []byte {
+	t.Helper()
+	var r io.Reader
+	switch compression {
+	case Gzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	case Zstd:
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return data
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestCSV(t *testing.T) {
+	db := openStore(t)
+
+	for _, compression := range []string{None, Gzip, Zstd} {
+		data, n := export(t, db, store.Filter{}, CSV, compression)
+		records, err := csv.NewReader(bytes.NewReader(decompress(t, data, compression))).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != 4 || len(records) != 5 {
+			t.Fatalf("%q: exported %d entries in %d records, want 4 and a header", compression, n, len(records))
+		}
+		if want := []string{"id", "timestamp", "level", "message", "source", "env", "status", "service", "trace_id", "span_id", "fingerprint", "run_id", "fields"}; !slices.Equal(records[0], want) {
+			t.Errorf("header = %q, want %q", records[0], want)
+		}
+		row := map[string]string{}
+		for i, column := range records[0] {
+			row[column] = records[2][i]
+		}
+		want := map[string]string{
+			"id":          "2",
+			"timestamp":   "2026-03-01T10:00:01.123456Z",
+			"level":       "error",
+			"message":     "query failed, \"users\"\nretrying",
+			"source":      "file:app.log",
+			"env":         "dev",
+			"status":      "500",
+			"service":     "",
+			"trace_id":    "4bf9",
+			"span_id":     "00f0",
+			"fingerprint": row["fingerprint"],
+			"run_id":      "run-1",
+			"fields":      `{"attempt":2}`,
+		}
+		if row["fingerprint"] == "" || !equalMaps(row, want) {
+			t.Errorf("row = %q, want %q", row, want)
+		}
+	}
+
+	// An empty export is still a valid CSV file.
+	data, _ := export(t, db, store.Filter{Search: "nothing matches"}, CSV, None)
+	if got := strings.TrimSpace(string(data)); got != strings.Join(csvHeader, ",") {
+		t.Errorf("empty export = %q, want the header", got)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	db := openStore(t)
+
+	data, n := export(t, db, store.Filter{}, NDJSON, Gzip)
+	lines := strings.Split(strings.TrimSpace(string(decompress(t, data, Gzip))), "\n")
+	if n != 4 || len(lines) != 4 {
+		t.Fatalf("exported %d entries in %d lines, want 4", n, len(lines))
+	}
+
+	var e store.Entry
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != 2 || e.RunID != "run-1" || e.StatusCode != 500 || e.Fields["attempt"] != float64(2) || !e.Timestamp.Equal(base.Add(time.Second)) {
+		t.Errorf("entry = %+v", e)
+	}
+	if !strings.Contains(lines[0], `"run_id":"run-1"`) {
+		t.Errorf("line = %s, want a run_id", lines[0])
+	}
+
+	data, _ = export(t, db, store.Filter{Search: "nothing matches"}, NDJSON, None)
+	if len(data) != 0 {
+		t.Errorf("empty export = %q", data)
+	}
+}
+
+func TestParquet(t *testing.T) {
+	db := openStore(t)
+
+	for _, compression := range []string{None, Gzip, Zstd} {
+		data, n := export(t, db, store.Filter{}, Parquet, compression)
+		rows, err := parquet.Read[parquetRow](bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 4 || len(rows) != 4 {
+			t.Fatalf("%q: exported %d entries in %d rows, want 4", compression, n, len(rows))
+		}
+
+		r := rows[1]
+		if r.ID != 2 || r.Level != "error" || !r.Timestamp.Equal(base.Add(time.Second)) || r.Status == nil || *r.Status != 500 {
+			t.Errorf("row = %+v", r)
+		}
+		if r.RunID == nil || *r.RunID != "run-1" || r.Fields == nil || *r.Fields != `{"attempt":2}` || r.TraceID == nil || *r.TraceID != "4bf9" {
+			t.Errorf("optional columns of row %+v", r)
+		}
+		if last := rows[3]; last.RunID != nil || last.Status != nil || last.Fields != nil || last.Fingerprint != nil {
+			t.Errorf("empty optional columns of row %+v are set", last)
+		}
+	}
+
+	data, _ := export(t, db, store.Filter{Search: "nothing matches"}, Parquet, None)
+	rows, err := parquet.Read[parquetRow](bytes.NewReader(data), int64(len(data)))
+	if err != nil || len(rows) != 0 {
+		t.Errorf("empty export: %d rows, %v", len(rows), err)
+	}
+}
+
+func TestExportLimit(t *testing.T) {
+	db := openStore(t)
+
+	tests := []struct {
+		name   string
+		filter store.Filter
+		want   []int64
+	}{
+		{"all", store.Filter{}, []int64{1, 2, 3, 4}},
+		{"newest two, oldest first", store.Filter{Limit: 2}, []int64{3, 4}},
+		{"limit above the count", store.Filter{Limit: 10}, []int64{1, 2, 3, 4}},
+		{"filtered", store.Filter{Source: "file:app.log", Limit: 1}, []int64{2}},
+		{"filtered and skipping", store.Filter{MinLevel: flags.Warn, Limit: 2}, []int64{2, 3}},
+		{"nothing matches", store.Filter{Search: "nothing", Limit: 5}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, n := export(t, db, tt.filter, NDJSON, None)
+			var ids []int64
+			dec := json.NewDecoder(bytes.NewReader(data))
+			for dec.More() {
+				var e store.Entry
+				if err := dec.Decode(&e); err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, e.ID)
+			}
+			if !slices.Equal(ids, tt.want) || n != len(tt.want) {
+				t.Errorf("exported ids %v (n = %d), want %v", ids, n, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWriterErrors(t *testing.T) {
+	if _, err := NewWriter(io.Discard, "xlsx", None); err == nil {
+		t.Error("NewWriter accepted an unknown format")
+	}
+	if _, err := NewWriter(io.Discard, CSV, "brotli"); err == nil {
+		t.Error("NewWriter accepted an unknown compression")
+	}
+}
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		path, format, compression, ext string
+	}{
+		{"logs.csv", CSV, None, ".csv"},
+		{"out/Logs.CSV.GZ", CSV, Gzip, ".csv.gz"},
+		{"logs.jsonl.zst", NDJSON, Zstd, ".ndjson.zst"},
+		{"logs.parquet", Parquet, None, ".parquet"},
+		{"logs.txt", "", None, ""},
+	}
+	for _, tt := range tests {
+		format, compression := Infer(tt.path)
+		if format != tt.format || compression != tt.compression {
+			t.Errorf("Infer(%q) = %q, %q, want %q, %q", tt.path, format, compression, tt.format, tt.compression)
+		}
+		if format != "" {
+			if ext := Extension(format, compression); ext != tt.ext {
+				t.Errorf("Extension(%q, %q) = %q, want %q", format, compression, ext, tt.ext)
+			}
+		}
+	}
+}
+
+func equalMaps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
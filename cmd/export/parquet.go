@@ -0,0 +1,122 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+	pgzip "github.com/parquet-go/parquet-go/compress/gzip"
+	"github.com/parquet-go/parquet-go/compress/snappy"
+	pzstd "github.com/parquet-go/parquet-go/compress/zstd"
+)
+
+const (
+	parquetBatchSize = 1024
+	// parquetRowGroupSize bounds how many rows are buffered in memory
+	// before a row group is written out.
+	parquetRowGroupSize = 64 * 1024
+)
+
+// parquetRow is the Parquet schema of an exported entry.
+type parquetRow struct {
+	ID          int64     `parquet:"id"`
+	Timestamp   time.Time `parquet:"timestamp,timestamp(microsecond)"`
+	Level       string    `parquet:"level,dict"`
+	Message     string    `parquet:"message"`
+	Source      string    `parquet:"source,dict"`
+	Env         string    `parquet:"env,dict"`
+	Status      *int32    `parquet:"status,optional"`
+	Service     string    `parquet:"service,dict"`
+	TraceID     *string   `parquet:"trace_id,optional"`
+	SpanID      *string   `parquet:"span_id,optional"`
+	Fingerprint *string   `parquet:"fingerprint,optional"`
+	RunID       *string   `parquet:"run_id,optional,dict"`
+	Fields      *string   `parquet:"fields,optional"` // JSON text
+}
+
+type parquetWriter struct {
+	w       *parquet.GenericWriter[parquetRow]
+	batch   []parquetRow
+	pending int // Rows in the current row group
+}
+
+func newParquetWriter(w io.Writer, compression string) *parquetWriter {
+	var codec compress.Codec = &snappy.Codec{}
+	switch compression {
+	case Gzip:
+		codec = &pgzip.Codec{}
+	case Zstd:
+		codec = &pzstd.Codec{}
+	}
+	return &parquetWriter{
+		w:     parquet.NewGenericWriter[parquetRow](w, parquet.Compression(codec)),
+		batch: make([]parquetRow, 0, parquetBatchSize),
+	}
+}
+
+func (p *parquetWriter) Write(e store.Entry) error {
+	row := parquetRow{
+		ID:          e.ID,
+		Timestamp:   e.Timestamp.UTC(),
+		Level:       string(e.Level),
+		Message:     e.Message,
+		Source:      e.Source,
+		Env:         e.Env,
+		Service:     e.Service,
+		TraceID:     optional(e.TraceID),
+		SpanID:      optional(e.SpanID),
+		Fingerprint: optional(e.Fingerprint),
+		RunID:       optional(e.RunID),
+	}
+	if e.StatusCode != 0 {
+		status := int32(e.StatusCode)
+		row.Status = &status
+	}
+	if len(e.Fields) > 0 {
+		data, err := json.Marshal(e.Fields)
+		if err != nil {
+			return err
+		}
+		row.Fields = optional(string(data))
+	}
+
+	p.batch = append(p.batch, row)
+	if len(p.batch) < parquetBatchSize {
+		return nil
+	}
+	return p.flushBatch()
+}
+
+func (p *parquetWriter) flushBatch() error {
+	if len(p.batch) == 0 {
+		return nil
+	}
+	if _, err := p.w.Write(p.batch); err != nil {
+		return err
+	}
+	p.pending += len(p.batch)
+	p.batch = p.batch[:0]
+
+	if p.pending >= parquetRowGroupSize {
+		p.pending = 0
+		return p.w.Flush()
+	}
+	return nil
+}
+
+func (p *parquetWriter) Close() error {
+	if err := p.flushBatch(); err != nil {
+		return err
+	}
+	return p.w.Close()
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
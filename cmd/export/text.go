@@ -0,0 +1,91 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+// csvHeader names the CSV columns, matching the JSON field names.
+var csvHeader = []string{"id", "timestamp", "level", "message", "source", "env", "status", "service", "trace_id", "span_id", "fingerprint", "run_id", "fields"}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+	record []string
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), record: make([]string, len(csvHeader))}
+}
+
+func (c *csvWriter) Write(e store.Entry) error {
+	if !c.header {
+		c.header = true
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	status := ""
+	if e.StatusCode != 0 {
+		status = strconv.Itoa(e.StatusCode)
+	}
+	fields := ""
+	if len(e.Fields) > 0 {
+		data, err := json.Marshal(e.Fields)
+		if err != nil {
+			return err
+		}
+		fields = string(data)
+	}
+
+	r := c.record
+	r[0] = strconv.FormatInt(e.ID, 10)
+	r[1] = e.Timestamp.UTC().Format(time.RFC3339Nano)
+	r[2] = string(e.Level)
+	r[3] = e.Message
+	r[4] = e.Source
+	r[5] = e.Env
+	r[6] = status
+	r[7] = e.Service
+	r[8] = e.TraceID
+	r[9] = e.SpanID
+	r[10] = e.Fingerprint
+	r[11] = e.RunID
+	r[12] = fields
+	return c.w.Write(r)
+}
+
+func (c *csvWriter) Close() error {
+	if !c.header {
+		// Still write the header so an empty export is a valid CSV file.
+		c.header = true
+		c.w.Write(csvHeader)
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	buf := bufio.NewWriter(w)
+	return &ndjsonWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (n *ndjsonWriter) Write(e store.Entry) error {
+	return n.enc.Encode(e)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.buf.Flush()
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/spf13/cobra"
+)
+
+// filterFlags are the flags shared by commands that select stored logs.
+type filterFlags struct {
+	levels      []string
+	minLevel    string
+	since       string
+	until       string
+	search      string
+	source      string
+	env         string
+	service     string
+	traceID     string
+	fingerprint string
+	runID       string
+	limit       int
+}
+
+func (f *filterFlags) register(cmd *cobra.Command) {
+	fs := cmd.Flags()
+	fs.StringArrayVarP(&f.levels, "level", "l", nil, "Only entries at this level (repeatable)")
+	fs.StringVar(&f.minLevel, "min-level", "", "Only entries at this level or more severe")
+	fs.StringVar(&f.since, "since", "", "Only entries at or after this time (RFC 3339, or a duration such as 1h)")
+	fs.StringVar(&f.until, "until", "", "Only entries before this time (RFC 3339, or a duration such as 10m)")
+	fs.StringVarP(&f.search, "search", "q", "", "Only entries whose message contains this text")
+	fs.StringVar(&f.source, "source", "", "Only entries from this source")
+	fs.StringVar(&f.env, "env", "", "Only entries from this environment")
+	fs.StringVar(&f.service, "service", "", "Only entries from this service")
+	fs.StringVar(&f.traceID, "trace-id", "", "Only entries of this trace")
+	fs.StringVar(&f.fingerprint, "fingerprint", "", "Only entries of this error group")
+	fs.StringVar(&f.runID, "run", "", "Only entries captured by this run")
+	fs.IntVarP(&f.limit, "limit", "n", 0, "Maximum number of entries (0 for no limit)")
+}
+
+// filter converts the flags into a store filter.
+func (f *filterFlags) filter() (store.Filter, error) {
+	sf := store.Filter{
+		Search:      f.search,
+		Source:      f.source,
+		Env:         f.env,
+		Service:     f.service,
+		TraceID:     f.traceID,
+		Fingerprint: f.fingerprint,
+		RunID:       f.runID,
+		Limit:       f.limit,
+	}
+	for _, l := range f.levels {
+		var level flags.LogLevel
+		if err := level.Set(l); err != nil {
+			return store.Filter{}, err
+		}
+		sf.Levels = append(sf.Levels, level)
+	}
+	if f.minLevel != "" {
+		if err := sf.MinLevel.Set(f.minLevel); err != nil {
+			return store.Filter{}, err
+		}
+	}
+
+	var err error
+	if sf.Since, err = store.ParseTime(f.since); err != nil {
+		return store.Filter{}, fmt.Errorf("invalid --since: %w", err)
+	}
+	if sf.Until, err = store.ParseTime(f.until); err != nil {
+		return store.Filter{}, fmt.Errorf("invalid --until: %w", err)
+	}
+	return sf, nil
+}
@@ -13,9 +13,11 @@ type Database string
 const (
 	Postgres Database = "postgres"
 	Sqlite   Database = "sqlite"
+	MySQL    Database = "mysql"
+	DuckDB   Database = "duckdb"
 )
 
-var AllowedDBDrivers = []string{string(Postgres), string(Sqlite)}
+var AllowedDBDrivers = []string{string(Postgres), string(Sqlite), string(MySQL), string(DuckDB)}
 
 func (f Database) String() string {
 	return string(f)
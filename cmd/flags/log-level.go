@@ -33,3 +33,18 @@ func (f *LogLevel) Set(value string) error {
 	}
 	return fmt.Errorf("invalid log level. Allowed values: %s", strings.Join(AllowedLogLevels, ", "))
 }
+
+// severity orders the log levels from least to most severe.
+var severity = map[LogLevel]int{Debug: 0, Info: 1, Warn: 2, Error: 3}
+
+// AtLeast reports whether the level is as severe as min.
+// Unknown levels are treated as info.
+func (f LogLevel) AtLeast(min LogLevel) bool {
+	rank := func(l LogLevel) int {
+		if r, ok := severity[l]; ok {
+			return r
+		}
+		return severity[Info]
+	}
+	return rank(f) >= rank(min)
+}
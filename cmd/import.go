@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/ingest"
+	"github.com/spf13/cobra"
+)
+
+const importMsg = (`The import command loads existing log files into the project database, e.g. dumps
+from QA or staging that you want to explore in the studio.
+
+Lines go through the same parsing as ` + "`jotl dev`" + `. Pick the format with ` + "`--format`" + `:
+- ` + "`json`" + `: one JSON object per line
+- ` + "`logfmt`" + `: ` + "`key=value`" + ` pairs, as written by logrus, zerolog's console writer or Heroku
+- ` + "`text`" + `: plain lines; a leading timestamp, level and HTTP status are picked up
+- ` + "`clf`" + `: Apache/nginx common or combined access logs
+
+Without ` + "`--format`" + `, JSON lines are detected and everything else is read as text.
+Gzipped files (` + "`.gz`" + `) are decompressed automatically.
+
+Imports are recorded by a hash of their content, so importing the same dump twice,
+even under another name, adds nothing. Importing a file that grew since only adds
+the new lines, and an interrupted import continues where it stopped. Use
+` + "`--force`" + ` to import the content again.
+
+Example:
+jotl import staging-api.log.gz --format json --env staging`)
+
+var (
+	importFormat string
+	importEnv    string
+	importForce  bool
+)
+
+var importCommand = &cobra.Command{
+	Use:   "import <file>...",
+	Short: "Load existing log files into the project database",
+	Long: func() string {
+		out, _ := glamour.Render(importMsg, "dark")
+		return out
+	}(),
+	Args: cobra.MinimumNArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		_, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		if importFormat != ingest.FormatAuto && !slices.Contains(ingest.LineFormats, importFormat) {
+			cobra.CheckErr(fmt.Errorf("unknown format %q (want %s)", importFormat, strings.Join(ingest.LineFormats, ", ")))
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		db, err := openStore(ctx, paths, cfg)
+		cobra.CheckErr(err)
+		defer db.Close()
+
+		imports := make([]*ingest.ImportSource, len(args))
+		sources := make([]ingest.Source, len(args))
+		for i, path := range args {
+			imports[i] = &ingest.ImportSource{Path: path, Format: importFormat, MinLevel: flags.LogLevel(cfg.Logging.Level)}
+			if !importForce {
+				imports[i].Checkpoints = db
+			}
+			sources[i] = imports[i]
+		}
+
+		runID, endRun, err := startRun(ctx, db, "import", importEnv, sources)
+		cobra.CheckErr(err)
+		defer endRun()
+
+		pipeline := &ingest.Pipeline{
+			Store:    db,
+			Env:      importEnv,
+			MinLevel: flags.LogLevel(cfg.Logging.Level),
+			RunID:    runID,
+		}
+		cobra.CheckErr(pipeline.Run(ctx, sources...))
+
+		for _, imp := range imports {
+			if imp.Empty {
+				fmt.Println(tipMsgStyle.Render(fmt.Sprintf("%s: empty, nothing to import", imp.Path)))
+				continue
+			}
+			if imp.Skipped {
+				fmt.Println(tipMsgStyle.Render(fmt.Sprintf("%s: already imported, skipped (use --force to import again)", imp.Path)))
+				continue
+			}
+
+			msg := fmt.Sprintf("%s: imported %d lines", imp.Path, imp.Imported)
+			if imp.Resumed > 0 {
+				msg = fmt.Sprintf("%s: imported %d new lines", imp.Path, imp.Imported)
+			}
+			if imp.Filtered > 0 {
+				msg += fmt.Sprintf(", skipped %d below the %s level", imp.Filtered, cfg.Logging.Level)
+			}
+			switch {
+			case imp.Invalid > 0 && importFormat == ingest.FormatAuto:
+				msg += fmt.Sprintf(", skipped %d that could not be parsed", imp.Invalid)
+			case imp.Invalid > 0:
+				msg += fmt.Sprintf(", skipped %d that are not %s", imp.Invalid, importFormat)
+			}
+			fmt.Println(endingMsgStyle.Render(msg))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCommand)
+	importCommand.Flags().StringVarP(&importFormat, "format", "f", ingest.FormatAuto, "Line format: "+strings.Join(ingest.LineFormats, ", ")+" (detected when empty)")
+	importCommand.Flags().StringVarP(&importEnv, "env", "e", "development", "Environment recorded on imported logs")
+	importCommand.Flags().BoolVar(&importForce, "force", false, "Import files even if their content was imported before")
+}
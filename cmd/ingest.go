@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/ingest"
+	"github.com/spf13/cobra"
+)
+
+const ingestMsg = (`The ingest command runs a standalone HTTP endpoint that stores pushed log events in the project database.
+
+It accepts POST requests on ` + "`/ingest`" + ` whose body is a JSON array of events, a single
+JSON object, or newline-delimited JSON (NDJSON). Events use the same keys as JSON log lines:
+- ` + "`level`" + `, ` + "`message`" + ` (or ` + "`msg`" + `), ` + "`timestamp`" + ` and ` + "`status`" + `
+- ` + "`source`" + ` and ` + "`env`" + ` to label where the event came from
+- anything else is kept as structured fields
+
+Settings are read from ` + "`sources.http`" + ` in jotl/config.yaml and can be overridden with flags.
+When a token is configured, send it as ` + "`Authorization: Bearer <token>`" + `, an ` + "`X-Jotl-Token`" + `
+header, or a ` + "`?token=`" + ` query parameter (for ` + "`navigator.sendBeacon`" + `).
+Without a token the endpoint only listens on loopback addresses such as ` + "`127.0.0.1`" + `.
+
+To capture browser errors:
+
+` + "```js" + `
+const send = (e) => navigator.sendBeacon("http://localhost:8090/ingest", JSON.stringify(e));
+window.addEventListener("error", (ev) => send({ level: "error", message: ev.message, source: "browser" }));
+window.addEventListener("unhandledrejection", (ev) => send({ level: "error", message: String(ev.reason), source: "browser" }));
+` + "```" + `
+`)
+
+var (
+	ingestAddr    string
+	ingestOrigins []string
+	ingestToken   string
+	ingestEnv     string
+)
+
+var ingestCommand = &cobra.Command{
+	Use:   "ingest",
+	Short: "Accept log events over HTTP and store them in the project database",
+	Long: func() string {
+		out, _ := glamour.Render(ingestMsg, "dark")
+		return out
+	}(),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		_, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		db, err := openStore(ctx, paths, cfg)
+		cobra.CheckErr(err)
+		defer db.Close()
+
+		source := &ingest.HTTPSource{
+			Addr:           cfg.Sources.HTTP.Addr,
+			AllowedOrigins: cfg.Sources.HTTP.AllowedOrigins,
+			Token:          cfg.Sources.HTTP.Token,
+		}
+		if cmd.Flags().Changed("addr") || source.Addr == "" {
+			source.Addr = ingestAddr
+		}
+		if cmd.Flags().Changed("origin") {
+			source.AllowedOrigins = ingestOrigins
+		}
+		if cmd.Flags().Changed("token") {
+			source.Token = ingestToken
+		}
+
+		fmt.Println(endingMsgStyle.Render(fmt.Sprintf("Jotl is accepting logs on http://%s%s", source.Addr, ingest.HTTPIngestPath)))
+		fmt.Println(tipMsgStyle.Render("Press Ctrl+C to stop."))
+
+		runID, endRun, err := startRun(ctx, db, "ingest", ingestEnv, []ingest.Source{source})
+		cobra.CheckErr(err)
+		defer endRun()
+
+		pipeline := &ingest.Pipeline{
+			Store:    db,
+			Env:      ingestEnv,
+			MinLevel: flags.LogLevel(cfg.Logging.Level),
+			RunID:    runID,
+		}
+		cobra.CheckErr(pipeline.Run(ctx, source))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ingestCommand)
+	ingestCommand.Flags().StringVarP(&ingestAddr, "addr", "a", config.DefaultIngestAddr, "Address to listen on")
+	ingestCommand.Flags().StringArrayVar(&ingestOrigins, "origin", nil, "Allow cross-origin requests from this origin (repeatable, * for any)")
+	ingestCommand.Flags().StringVar(&ingestToken, "token", "", "Shared secret clients must send")
+	ingestCommand.Flags().StringVarP(&ingestEnv, "env", "e", "development", "Environment recorded on events that don't set one")
+}
@@ -0,0 +1,383 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+const (
+	// DefaultDockerSocket is where the Docker Engine API listens by default.
+	DefaultDockerSocket = "/var/run/docker.sock"
+
+	dockerPollInterval = 2 * time.Second
+)
+
+// DockerSource streams logs of running containers from the Docker Engine API.
+//
+// Each selector is either a container name or ID, or a label filter written
+// as "label=key=value" (or "label=key"). Containers matching a selector are
+// attached as they start, and the timestamp of the last stored line is
+// checkpointed per container so a restart continues where it stopped.
+type DockerSource struct {
+	Selectors   []string
+	Socket      string // Path to the Engine API unix socket; defaults to DOCKER_HOST or DefaultDockerSocket
+	Checkpoints CheckpointReader
+
+	client *http.Client
+	mu     sync.Mutex
+	active map[string]bool
+	// latest is the newest line timestamp seen per container during this
+	// run, which may be ahead of the last flushed checkpoint.
+	latest map[string]int64
+}
+
+// dockerContainer holds the parts of a container inspect response Jotl uses.
+type dockerContainer struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Tty    bool              `json:"Tty"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	State struct {
+		Running bool `json:"Running"`
+	} `json:"State"`
+}
+
+func (s *DockerSource) Name() string {
+	return "docker " + strings.Join(s.Selectors, ", ")
+}
+
+// Run attaches to matching containers until ctx is cancelled.
+func (s *DockerSource) Run(ctx context.Context, out chan<- Event) error {
+	socket := s.socketPath()
+	s.client = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}}
+	s.active = map[string]bool{}
+	s.latest = map[string]int64{}
+
+	if err := s.ping(ctx); err != nil {
+		return fmt.Errorf("cannot reach the Docker Engine at %s: %w", socket, err)
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(dockerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		ids, err := s.resolve(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("docker: %v", err)
+		}
+
+		for _, id := range ids {
+			s.mu.Lock()
+			attached := s.active[id]
+			s.active[id] = true
+			s.mu.Unlock()
+			if attached {
+				continue
+			}
+
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				defer func() {
+					s.mu.Lock()
+					delete(s.active, id)
+					s.mu.Unlock()
+				}()
+				if err := s.follow(ctx, id, out); err != nil && ctx.Err() == nil {
+					log.Printf("docker: stopped following %s: %v", shortID(id), err)
+				}
+			}(id)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *DockerSource) socketPath() string {
+	if s.Socket != "" {
+		return s.Socket
+	}
+	if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, "unix://") {
+		return strings.TrimPrefix(host, "unix://")
+	}
+	return DefaultDockerSocket
+}
+
+func (s *DockerSource) ping(ctx context.Context) error {
+	resp, err := s.get(ctx, "/_ping", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// resolve returns the IDs of running containers matching any selector.
+func (s *DockerSource) resolve(ctx context.Context) ([]string, error) {
+	seen := map[string]bool{}
+	var ids []string
+	add := func(id string) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var errs []error
+	for _, sel := range s.Selectors {
+		if label, ok := strings.CutPrefix(sel, "label="); ok {
+			filters, _ := json.Marshal(map[string][]string{"label": {label}})
+			var list []struct {
+				ID string `json:"Id"`
+			}
+			if err := s.getJSON(ctx, "/containers/json", url.Values{"filters": {string(filters)}}, &list); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, c := range list {
+				add(c.ID)
+			}
+			continue
+		}
+
+		c, err := s.inspect(ctx, sel)
+		if err != nil {
+			// Not created yet; it will be picked up once it exists.
+			continue
+		}
+		if c.State.Running {
+			add(c.ID)
+		}
+	}
+	return ids, errors.Join(errs...)
+}
+
+func (s *DockerSource) inspect(ctx context.Context, nameOrID string) (*dockerContainer, error) {
+	var c dockerContainer
+	if err := s.getJSON(ctx, "/containers/"+url.PathEscape(nameOrID)+"/json", nil, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// follow streams the logs of one container until it stops or ctx is done.
+func (s *DockerSource) follow(ctx context.Context, id string, out chan<- Event) error {
+	c, err := s.inspect(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	key := "docker:" + c.ID
+	s.mu.Lock()
+	since, seen := s.latest[c.ID]
+	s.mu.Unlock()
+	if !seen && s.Checkpoints != nil {
+		if cp, found, err := s.Checkpoints.Checkpoint(ctx, key); err != nil {
+			return err
+		} else if found {
+			since = cp.Offset
+		}
+	}
+
+	query := url.Values{
+		"follow":     {"1"},
+		"stdout":     {"1"},
+		"stderr":     {"1"},
+		"timestamps": {"1"},
+	}
+	if since > 0 {
+		query.Set("since", fmt.Sprintf("%d.%09d", since/1e9, since%1e9))
+	}
+
+	resp, err := s.get(ctx, "/containers/"+c.ID+"/logs", query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	name := strings.TrimPrefix(c.Name, "/")
+	service := c.Config.Labels["com.docker.compose.service"]
+	if service == "" {
+		service = name
+	}
+
+	// Lines from stdout and stderr may arrive slightly out of order, so only
+	// lines up to the resume point are skipped and the checkpoint records
+	// the newest timestamp seen.
+	latest := since
+	emit := func(stream, line string) error {
+		ts, text := splitDockerTimestamp(line)
+		if ts.UnixNano() <= since || strings.TrimSpace(text) == "" {
+			return nil
+		}
+		if ts.UnixNano() > latest {
+			latest = ts.UnixNano()
+			s.mu.Lock()
+			s.latest[c.ID] = latest
+			s.mu.Unlock()
+		}
+
+		entry := ParseLine(text)
+		if entry.Timestamp.IsZero() {
+			entry.Timestamp = ts
+		}
+		entry.Source = "docker:" + name
+		entry.Service = service
+		if entry.Fields == nil {
+			entry.Fields = map[string]any{}
+		}
+		entry.Fields["container"] = name
+		entry.Fields["container_id"] = shortID(c.ID)
+		entry.Fields["image"] = c.Config.Image
+		entry.Fields["stream"] = stream
+
+		select {
+		case out <- Event{Entry: &entry, Checkpoint: &store.Checkpoint{Key: key, Offset: latest, Identity: c.ID}}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if c.Config.Tty {
+		return readDockerLines(resp.Body, "stdout", emit)
+	}
+	return readDockerFrames(resp.Body, emit)
+}
+
+// readDockerFrames decodes the multiplexed stream used for containers
+// without a TTY: an 8 byte header (stream type, three zero bytes, big-endian
+// payload size) followed by the payload.
+func readDockerFrames(r io.Reader, emit func(stream, line string) error) error {
+	var header [8]byte
+	pending := map[string]*bytes.Buffer{"stdout": {}, "stderr": {}}
+
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return err
+		}
+
+		stream := "stdout"
+		if header[0] == 2 {
+			stream = "stderr"
+		}
+		size := binary.BigEndian.Uint32(header[4:])
+
+		buf := pending[stream]
+		if _, err := io.CopyN(buf, r, int64(size)); err != nil {
+			return err
+		}
+
+		for {
+			line, err := buf.ReadString('\n')
+			if err != nil {
+				// Incomplete line: keep it for the next frame.
+				buf.Reset()
+				buf.WriteString(line)
+				break
+			}
+			if err := emit(stream, strings.TrimRight(line, "\r\n")); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readDockerLines reads the raw stream used for containers with a TTY.
+func readDockerLines(r io.Reader, stream string, emit func(stream, line string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+	for scanner.Scan() {
+		if err := emit(stream, strings.TrimRight(scanner.Text(), "\r")); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// splitDockerTimestamp separates the RFC 3339 timestamp Docker prefixes each
+// line with when `timestamps=1` is requested.
+func splitDockerTimestamp(line string) (time.Time, string) {
+	prefix, rest, ok := strings.Cut(line, " ")
+	if ok {
+		if ts, err := time.Parse(time.RFC3339Nano, prefix); err == nil {
+			return ts, rest
+		}
+	}
+	return time.Now(), line
+}
+
+func (s *DockerSource) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := "http://docker" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var body struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return nil, fmt.Errorf("docker %s: %s (%s)", path, resp.Status, body.Message)
+	}
+	return resp, nil
+}
+
+func (s *DockerSource) getJSON(ctx context.Context, path string, query url.Values, v any) error {
+	resp, err := s.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,260 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+const containerID = "3f2a9c1d7e4b5a6c8d9e0f1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c"
+
+// fakeEngine serves the parts of the Docker Engine API the source uses on a
+// temporary unix socket. Logs are served as multiplexed frames.
+type fakeEngine struct {
+	socket string
+	frames [][]byte
+
+	mu    sync.Mutex
+	since []string // since parameter of every logs request
+}
+
+func newFakeEngine(t *testing.T, frames ...[]byte) *fakeEngine {
+	t.Helper()
+
+	// Unix socket paths are limited to about 100 bytes, which t.TempDir
+	// can exceed.
+	dir, err := os.MkdirTemp("", "jotl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	e := &fakeEngine{socket: filepath.Join(dir, "docker.sock"), frames: frames}
+	ln, err := net.Listen("unix", e.socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /_ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("GET /containers/json", func(w http.ResponseWriter, r *http.Request) {
+		var filters map[string][]string
+		json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+		list := []map[string]string{}
+		if len(filters["label"]) == 1 && filters["label"][0] == "com.docker.compose.project=shop" {
+			list = append(list, map[string]string{"Id": containerID})
+		}
+		json.NewEncoder(w).Encode(list)
+	})
+	mux.HandleFunc("GET /containers/{name}/json", func(w http.ResponseWriter, r *http.Request) {
+		if name := r.PathValue("name"); name != "web" && name != containerID {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "No such container: " + name})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"Id":   containerID,
+			"Name": "/web",
+			"Config": map[string]any{
+				"Image":  "shop/web:1.2",
+				"Tty":    false,
+				"Labels": map[string]string{"com.docker.compose.service": "api"},
+			},
+			"State": map[string]any{"Running": true},
+		})
+	})
+	mux.HandleFunc("GET /containers/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
+		e.mu.Lock()
+		e.since = append(e.since, r.URL.Query().Get("since"))
+		e.mu.Unlock()
+		for _, f := range e.frames {
+			w.Write(f)
+		}
+		// Keep following until the client goes away.
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return e
+}
+
+func (e *fakeEngine) sinceParams() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.since...)
+}
+
+// frame builds one multiplexed log frame; stream is 1 for stdout and 2 for
+// stderr.
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+type checkpoints map[string]store.Checkpoint
+
+func (c checkpoints) Checkpoint(_ context.Context, key string) (store.Checkpoint, bool, error) {
+	cp, ok := c[key]
+	return cp, ok, nil
+}
+
+func (c checkpoints) CheckpointByIdentity(_ context.Context, identity string) (store.Checkpoint, bool, error) {
+	for _, cp := range c {
+		if cp.Identity == identity {
+			return cp, true, nil
+		}
+	}
+	return store.Checkpoint{}, false, nil
+}
+
+// collect runs src until it has sent n events or a timeout expires.
+func collect(t *testing.T, src *DockerSource, n int) []Event {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out := make(chan Event)
+	done := make(chan error, 1)
+	go func() { done <- src.Run(ctx, out) }()
+
+	var events []Event
+	for len(events) < n {
+		select {
+		case ev := <-out:
+			events = append(events, ev)
+		case err := <-done:
+			t.Fatalf("Run returned after %d of %d events: %v", len(events), n, err)
+		case <-ctx.Done():
+			t.Fatalf("timed out after %d of %d events", len(events), n)
+		}
+	}
+	cancel()
+	<-done
+	return events
+}
+
+func TestDockerSource(t *testing.T) {
+	engine := newFakeEngine(t,
+		frame(1, "2025-03-01T10:00:00.000000001Z server started\n"),
+		frame(2, "2025-03-01T10:00:00.5Z connection re"),
+		frame(1, "2025-03-01T10:00:01Z   \n"), // blank lines are skipped
+		frame(2, "fused\n2025-03-01T10:00:02Z retrying\n"),
+	)
+
+	tests := []struct {
+		name     string
+		selector string
+	}{
+		{"name", "web"},
+		{"label", "label=com.docker.compose.project=shop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := collect(t, &DockerSource{Selectors: []string{tt.selector}, Socket: engine.socket}, 3)
+
+			want := []struct {
+				message, stream, ts string
+			}{
+				{"server started", "stdout", "2025-03-01T10:00:00.000000001Z"},
+				{"connection refused", "stderr", "2025-03-01T10:00:00.5Z"},
+				{"retrying", "stderr", "2025-03-01T10:00:02Z"},
+			}
+			for i, w := range want {
+				e := events[i].Entry
+				if e.Message != w.message {
+					t.Errorf("event %d: message = %q, want %q", i, e.Message, w.message)
+				}
+				if e.Fields["stream"] != w.stream {
+					t.Errorf("event %d: stream = %v, want %s", i, e.Fields["stream"], w.stream)
+				}
+				ts, _ := time.Parse(time.RFC3339Nano, w.ts)
+				if !e.Timestamp.Equal(ts) {
+					t.Errorf("event %d: timestamp = %s, want %s", i, e.Timestamp, ts)
+				}
+				if e.Source != "docker:web" || e.Service != "api" {
+					t.Errorf("event %d: source, service = %q, %q, want docker:web, api", i, e.Source, e.Service)
+				}
+				if e.Fields["container"] != "web" || e.Fields["container_id"] != containerID[:12] || e.Fields["image"] != "shop/web:1.2" {
+					t.Errorf("event %d: fields = %v", i, e.Fields)
+				}
+
+				cp := events[i].Checkpoint
+				if cp == nil || cp.Key != "docker:"+containerID || cp.Identity != containerID || cp.Offset != ts.UnixNano() {
+					t.Errorf("event %d: checkpoint = %+v, want offset %d", i, cp, ts.UnixNano())
+				}
+			}
+		})
+	}
+}
+
+func TestDockerSourceResumes(t *testing.T) {
+	engine := newFakeEngine(t,
+		// The engine returns lines from the start of the second that since
+		// points into; those up to the checkpoint are skipped.
+		frame(1, "2025-03-01T10:00:01Z already stored\n2025-03-01T10:00:01.25Z new line\n"),
+	)
+
+	last, _ := time.Parse(time.RFC3339Nano, "2025-03-01T10:00:01Z")
+	src := &DockerSource{
+		Selectors: []string{"web"},
+		Socket:    engine.socket,
+		Checkpoints: checkpoints{
+			"docker:" + containerID: {Key: "docker:" + containerID, Offset: last.UnixNano(), Identity: containerID},
+		},
+	}
+	events := collect(t, src, 1)
+
+	if got := events[0].Entry.Message; got != "new line" {
+		t.Errorf("message = %q, want %q", got, "new line")
+	}
+	if got, want := engine.sinceParams(), "1740823201.000000000"; len(got) == 0 || got[0] != want {
+		t.Errorf("since = %v, want %s", got, want)
+	}
+}
+
+func TestDockerSourceUnreachable(t *testing.T) {
+	src := &DockerSource{Selectors: []string{"web"}, Socket: filepath.Join(t.TempDir(), "missing.sock")}
+	err := src.Run(context.Background(), make(chan Event))
+	if err == nil || !strings.Contains(err.Error(), "cannot reach the Docker Engine") {
+		t.Errorf("Run = %v, want an unreachable engine error", err)
+	}
+}
+
+func TestReadDockerFrames(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(frame(1, "a\nb"))
+	stream.Write(frame(2, "x\r\n"))
+	stream.Write(frame(1, "c\n"))
+	stream.Write(frame(1, "unterminated"))
+
+	var got []string
+	err := readDockerFrames(&stream, func(stream, line string) error {
+		got = append(got, stream+":"+line)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"stdout:a", "stderr:x", "stdout:bc"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
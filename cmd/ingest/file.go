@@ -0,0 +1,372 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+const (
+	defaultPollInterval = 250 * time.Millisecond
+	maxLineLength       = 1 << 20
+	// headLength is how much of the start of a file is hashed to tell a
+	// rewrite from a file that was only touched.
+	headLength = 4 << 10
+)
+
+// FileSource follows files matching a set of glob patterns, like `tail -F`.
+//
+// Files are polled rather than watched so the source behaves the same on
+// every platform. Rotated files (renamed or recreated) are drained before
+// switching to the new file, and truncated files are re-read from the start.
+// The offset of the last complete line is checkpointed with every entry, so a
+// restart resumes exactly where the previous run stopped.
+type FileSource struct {
+	Patterns     []string         // Glob patterns, relative to BaseDir
+	BaseDir      string           // Directory relative patterns and source names are resolved against
+	Checkpoints  CheckpointReader // Where to look up offsets from earlier runs
+	PollInterval time.Duration
+
+	tracked map[string]*tailedFile
+	// drained remembers where files closed during this run stopped, by
+	// identity, so a rotated file that still matches a pattern is not re-read.
+	drained map[string]int64
+}
+
+// tailedFile is an open file being followed.
+type tailedFile struct {
+	path     string
+	name     string
+	file     *os.File
+	info     os.FileInfo
+	identity string
+	offset   int64             // Offset just past the last emitted line
+	partial  []byte            // Bytes read after offset that don't end in a newline yet
+	head     [sha256.Size]byte // Hash of the first headSize bytes read
+	headSize int64
+}
+
+func (s *FileSource) Name() string {
+	return "file " + strings.Join(s.Patterns, ", ")
+}
+
+// Run polls the matching files until ctx is cancelled.
+func (s *FileSource) Run(ctx context.Context, out chan<- Event) error {
+	for _, pattern := range s.Patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+		}
+	}
+
+	s.tracked = map[string]*tailedFile{}
+	s.drained = map[string]int64{}
+	defer func() {
+		for _, tf := range s.tracked {
+			tf.file.Close()
+		}
+	}()
+
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.poll(ctx, out); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll discovers new files and reads whatever was appended to tracked ones.
+func (s *FileSource) poll(ctx context.Context, out chan<- Event) error {
+	matches, err := s.glob()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range matches {
+		if _, ok := s.tracked[path]; ok {
+			continue
+		}
+		tf, err := s.open(ctx, path)
+		if err != nil {
+			// The file may have vanished between glob and open; retry next poll.
+			continue
+		}
+		s.tracked[path] = tf
+	}
+
+	for path, tf := range s.tracked {
+		if err := s.follow(ctx, tf, out); err != nil {
+			return err
+		}
+		if tf.file == nil {
+			delete(s.tracked, path)
+		}
+	}
+	return nil
+}
+
+func (s *FileSource) glob() ([]string, error) {
+	seen := map[string]bool{}
+	var paths []string
+	for _, pattern := range s.Patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(s.BaseDir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() && !seen[m] {
+				seen[m] = true
+				paths = append(paths, m)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// open starts following path from its checkpoint, if one applies.
+func (s *FileSource) open(ctx context.Context, path string) (*tailedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	tf := &tailedFile{path: path, name: s.sourceName(path), file: f, info: info, identity: fileIdentity(info)}
+
+	offset, err := s.resume(ctx, tf)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	tf.offset = offset
+
+	if tf.offset > info.Size() {
+		// Truncated while we weren't looking.
+		tf.offset = 0
+	}
+	if _, err := f.Seek(tf.offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := tf.hashHead(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return tf, nil
+}
+
+// resume finds the offset to continue tf from: where this run left the same
+// file, the checkpoint for its path, or a checkpoint saved under a previous
+// name. Files seen for the first time are read from the start.
+func (s *FileSource) resume(ctx context.Context, tf *tailedFile) (int64, error) {
+	if offset, ok := s.drained[tf.identity]; ok && tf.identity != "" {
+		return offset, nil
+	}
+	if s.Checkpoints == nil {
+		return 0, nil
+	}
+
+	cp, found, err := s.Checkpoints.Checkpoint(ctx, checkpointKey(tf.path))
+	if err != nil {
+		return 0, err
+	}
+	if found && (cp.Identity == "" || cp.Identity == tf.identity) {
+		return cp.Offset, nil
+	}
+
+	if tf.identity != "" {
+		cp, found, err = s.Checkpoints.CheckpointByIdentity(ctx, tf.identity)
+		if err != nil {
+			return 0, err
+		}
+		if found {
+			return cp.Offset, nil
+		}
+	}
+	return 0, nil
+}
+
+// follow reads new data from tf, handling rotation and truncation.
+func (s *FileSource) follow(ctx context.Context, tf *tailedFile, out chan<- Event) error {
+	current, statErr := os.Stat(tf.path)
+
+	switch {
+	case statErr != nil || !os.SameFile(tf.info, current):
+		// Rotated or removed: finish the old file, then pick up the new one
+		// (if any) on the next poll.
+		if err := s.read(ctx, tf, out, true); err != nil {
+			return err
+		}
+		s.drained[tf.identity] = tf.offset
+		tf.file.Close()
+		tf.file = nil
+		return nil
+	case truncated(tf, current):
+		// Truncated in place (copytruncate).
+		if _, err := tf.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		tf.offset = 0
+		tf.partial = nil
+		tf.headSize = 0
+	}
+
+	if err := s.read(ctx, tf, out, false); err != nil {
+		return err
+	}
+	if err := tf.hashHead(); err != nil {
+		return err
+	}
+
+	// Remember the state we have read up to, so the next poll can tell an
+	// append from a truncate-and-rewrite that happens to restore the size.
+	info, err := tf.file.Stat()
+	if err != nil {
+		return err
+	}
+	tf.info = info
+	return nil
+}
+
+// truncated reports whether the file shrank below what was already read, or
+// was rewritten since the last poll, whatever its new size. A file that was
+// only touched or appended to, or rewritten with the same start, keeps its
+// offset.
+func truncated(tf *tailedFile, current os.FileInfo) bool {
+	read := tf.offset + int64(len(tf.partial))
+	if current.Size() < read {
+		return true
+	}
+	if tf.headSize == 0 || !current.ModTime().After(tf.info.ModTime()) {
+		return false
+	}
+	head, err := tf.hashPrefix(tf.headSize)
+	return err != nil || head != tf.head
+}
+
+// hashHead records the hash of the start of the file once more of it has
+// been read, up to headLength bytes.
+func (tf *tailedFile) hashHead() error {
+	n := min(tf.offset+int64(len(tf.partial)), headLength)
+	if n <= tf.headSize {
+		return nil
+	}
+	head, err := tf.hashPrefix(n)
+	if err != nil {
+		return err
+	}
+	tf.head, tf.headSize = head, n
+	return nil
+}
+
+// hashPrefix hashes the first n bytes of the file.
+func (tf *tailedFile) hashPrefix(n int64) ([sha256.Size]byte, error) {
+	buf := make([]byte, n)
+	if _, err := tf.file.ReadAt(buf, 0); err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to read %s: %w", tf.path, err)
+	}
+	return sha256.Sum256(buf), nil
+}
+
+// read emits every complete line appended to tf. When final is set the
+// trailing partial line is emitted too, since nothing more will follow.
+func (s *FileSource) read(ctx context.Context, tf *tailedFile, out chan<- Event, final bool) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := tf.file.Read(buf)
+		if n > 0 {
+			tf.partial = append(tf.partial, buf[:n]...)
+			if err := s.emitLines(ctx, tf, out); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", tf.path, err)
+		}
+	}
+
+	if final && len(tf.partial) > 0 {
+		return s.emit(ctx, tf, out, len(tf.partial))
+	}
+	return nil
+}
+
+func (s *FileSource) emitLines(ctx context.Context, tf *tailedFile, out chan<- Event) error {
+	for {
+		i := bytes.IndexByte(tf.partial, '\n')
+		if i < 0 {
+			if len(tf.partial) >= maxLineLength {
+				return s.emit(ctx, tf, out, len(tf.partial))
+			}
+			return nil
+		}
+		if err := s.emit(ctx, tf, out, i+1); err != nil {
+			return err
+		}
+	}
+}
+
+// emit sends the first n bytes of tf.partial as one entry.
+func (s *FileSource) emit(ctx context.Context, tf *tailedFile, out chan<- Event, n int) error {
+	line := string(tf.partial[:n])
+	tf.partial = tf.partial[n:]
+	tf.offset += int64(n)
+
+	ev := Event{
+		Checkpoint: &store.Checkpoint{Key: checkpointKey(tf.path), Offset: tf.offset, Identity: tf.identity},
+	}
+	// Blank lines still advance the checkpoint but aren't stored.
+	if strings.TrimSpace(line) != "" {
+		entry := ParseLine(line)
+		entry.Source = tf.name
+		ev.Entry = &entry
+	}
+
+	select {
+	case out <- ev:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *FileSource) sourceName(path string) string {
+	if rel, err := filepath.Rel(s.BaseDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		path = rel
+	}
+	return "file:" + filepath.ToSlash(path)
+}
+
+func checkpointKey(path string) string {
+	return "file:" + path
+}
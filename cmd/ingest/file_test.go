@@ -0,0 +1,65 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTruncated(t *testing.T) {
+	tests := []struct {
+		name    string
+		rewrite string // New content; empty to only touch the file
+		want    bool
+	}{
+		{"touched", "", false},
+		{"same content", "first line\nsecond line\n", false},
+		{"appended", "first line\nsecond line\nthird line\n", false},
+		{"rewritten to the same size", "other line\nsecond line\n", true},
+		{"rewritten larger", "other line\nsecond line\nand a third one\n", true},
+		{"shrunk", "first\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			if err := os.WriteFile(path, []byte("first line\nsecond line\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			s := &FileSource{BaseDir: filepath.Dir(path), drained: map[string]int64{}}
+			tf, err := s.open(context.Background(), path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tf.file.Close()
+			out := make(chan Event, 10)
+			if err := s.follow(context.Background(), tf, out); err != nil {
+				t.Fatal(err)
+			}
+			if len(out) != 2 {
+				t.Fatalf("read %d lines, want 2", len(out))
+			}
+
+			if tt.rewrite != "" {
+				// Truncate in place, like copytruncate, keeping the inode.
+				if err := os.WriteFile(path, []byte(tt.rewrite), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			later := time.Now().Add(time.Minute)
+			if err := os.Chtimes(path, later, later); err != nil {
+				t.Fatal(err)
+			}
+
+			current, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := truncated(tf, current); got != tt.want {
+				t.Errorf("truncated = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+// Line formats understood by ParseFormat.
+const (
+	FormatAuto   = ""
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+	FormatText   = "text"
+	FormatCLF    = "clf" // Common and combined log format, as written by Apache and nginx
+)
+
+// LineFormats lists the formats accepted by ParseFormat.
+var LineFormats = []string{FormatJSON, FormatLogfmt, FormatText, FormatCLF}
+
+var (
+	clfPattern = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] "([^"]*)" (\d{3}) (\S+)(?: "([^"]*)" "([^"]*)")?`)
+
+	// leadingTimePattern matches a timestamp at the start of a text line,
+	// optionally in brackets.
+	leadingTimePattern = regexp.MustCompile(`^\[?(\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?)\]?\s*`)
+)
+
+// ParseFormat parses a line written in a known format. Lines that don't
+// match the format are an error, except in auto mode, which behaves like
+// ParseLine.
+func ParseFormat(format, line string) (store.Entry, error) {
+	line = strings.TrimRight(line, "\r\n")
+
+	switch format {
+	case FormatAuto:
+		return ParseLine(line), nil
+	case FormatJSON:
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			return store.Entry{}, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return entryFromMap(obj, line), nil
+	case FormatLogfmt:
+		obj := parseLogfmt(line)
+		if len(obj) == 0 {
+			return store.Entry{}, fmt.Errorf("no logfmt key=value pairs")
+		}
+		return entryFromMap(obj, line), nil
+	case FormatText:
+		entry := parseText(line)
+		if m := leadingTimePattern.FindStringSubmatch(line); m != nil {
+			layout := "2006-01-02T15:04:05.999999999Z07:00"
+			value := strings.Replace(strings.Replace(m[1], " ", "T", 1), ",", ".", 1)
+			if ts, err := time.Parse(layout, value); err == nil {
+				entry.Timestamp = ts
+			} else if ts, err := time.ParseInLocation("2006-01-02T15:04:05.999999999", value, time.Local); err == nil {
+				entry.Timestamp = ts
+			}
+		}
+		return entry, nil
+	case FormatCLF:
+		return parseCLF(line)
+	default:
+		return store.Entry{}, fmt.Errorf("unknown format %q (want %s)", format, strings.Join(LineFormats, ", "))
+	}
+}
+
+// parseLogfmt splits key=value pairs; values may be double-quoted. A bare
+// key is recorded as true. Lines without any pair yield nil.
+func parseLogfmt(line string) map[string]any {
+	obj := map[string]any{}
+	pairs := 0
+	i := 0
+	for i < len(line) {
+		for i < len(line) && line[i] == ' ' {
+			i++
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			i++
+			continue
+		}
+		if i >= len(line) || line[i] != '=' {
+			obj[key] = true
+			continue
+		}
+		i++ // Skip '='
+		pairs++
+
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && (line[end] != '"' || line[end-1] == '\\') {
+				end++
+			}
+			if value, err := strconv.Unquote(line[i:min(end+1, len(line))]); err == nil {
+				obj[key] = value
+			} else {
+				obj[key] = strings.Trim(line[i:min(end+1, len(line))], `"`)
+			}
+			i = end + 1
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] != ' ' {
+			i++
+		}
+		obj[key] = line[start:i]
+	}
+	if pairs == 0 {
+		return nil
+	}
+	return obj
+}
+
+// parseCLF parses a common or combined log format line.
+func parseCLF(line string) (store.Entry, error) {
+	m := clfPattern.FindStringSubmatch(line)
+	if m == nil {
+		return store.Entry{}, fmt.Errorf("not a common log format line")
+	}
+
+	status, _ := strconv.Atoi(m[6])
+	entry := store.Entry{
+		Message:    m[5] + " " + m[6],
+		StatusCode: status,
+		Level:      flags.Info,
+		Fields:     map[string]any{"remote_addr": m[1]},
+	}
+	switch {
+	case status >= 500:
+		entry.Level = flags.Error
+	case status >= 400:
+		entry.Level = flags.Warn
+	}
+	if ts, err := time.Parse("02/Jan/2006:15:04:05 -0700", m[4]); err == nil {
+		entry.Timestamp = ts
+	}
+
+	if m[3] != "-" {
+		entry.Fields["user"] = m[3]
+	}
+	if parts := strings.Fields(m[5]); len(parts) == 3 {
+		entry.Fields["method"] = parts[0]
+		entry.Fields["path"] = parts[1]
+		entry.Fields["protocol"] = parts[2]
+	}
+	if bytes, err := strconv.Atoi(m[7]); err == nil {
+		entry.Fields["bytes"] = bytes
+	}
+	if m[8] != "" && m[8] != "-" {
+		entry.Fields["referer"] = m[8]
+	}
+	if m[9] != "" && m[9] != "-" {
+		entry.Fields["user_agent"] = m[9]
+	}
+	return entry, nil
+}
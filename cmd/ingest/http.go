@@ -0,0 +1,234 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+const (
+	// HTTPIngestPath is where the HTTP source accepts log events.
+	HTTPIngestPath = "/ingest"
+
+	maxHTTPBody = 5 << 20
+)
+
+// HTTPSource accepts log events pushed over HTTP, e.g. from browsers.
+//
+// The body of a POST to /ingest is either a JSON array of events, a single
+// JSON object, or newline-delimited JSON. Each event uses the same keys as a
+// JSON log line (level, message, timestamp, status, ...); "source" and "env"
+// are also honoured and everything else is kept as fields.
+type HTTPSource struct {
+	Addr           string   // Listen address, e.g. "127.0.0.1:8090"
+	AllowedOrigins []string // Origins allowed by CORS; "*" allows any
+	Token          string   // Optional shared secret required on every request
+}
+
+func (s *HTTPSource) Name() string {
+	return "http " + s.Addr
+}
+
+// Run serves the ingest endpoint until ctx is cancelled. Anyone who can
+// reach the endpoint can write to the database, so without a token it only
+// listens on loopback addresses.
+func (s *HTTPSource) Run(ctx context.Context, out chan<- Event) error {
+	if s.Token == "" && !isLoopback(s.Addr) {
+		return fmt.Errorf("refusing to accept logs on %s without a token; set sources.http.token or listen on 127.0.0.1", s.Addr)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(HTTPIngestPath, s.Handler(ctx, out))
+	return serveHTTP(ctx, s.Addr, mux)
+}
+
+// isLoopback reports whether the listen address addr is only reachable from
+// this machine.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serveHTTP serves handler on addr until ctx is cancelled, then shuts the
+// server down gracefully.
+func serveHTTP(ctx context.Context, addr string, handler http.Handler) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
+
+// Handler returns the ingest endpoint, sending accepted events to out.
+func (s *HTTPSource) Handler(ctx context.Context, out chan<- Event) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.setCORSHeaders(w, r)
+
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case http.MethodPost:
+		default:
+			writeJSONError(w, http.StatusMethodNotAllowed, "only POST is supported")
+			return
+		}
+
+		if !s.authorized(r) {
+			writeJSONError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHTTPBody))
+		if err != nil {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return
+		}
+
+		entries, err := DecodeEvents(body)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		for i := range entries {
+			if entries[i].Source == "" {
+				entries[i].Source = "http"
+			}
+			select {
+			case out <- Event{Entry: &entries[i]}:
+			case <-ctx.Done():
+				writeJSONError(w, http.StatusServiceUnavailable, "shutting down")
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]int{"accepted": len(entries)})
+	})
+}
+
+// DecodeEvents parses a JSON array, a single JSON object or NDJSON into
+// entries.
+func DecodeEvents(body []byte) ([]store.Entry, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	var objects []map[string]any
+	if body[0] == '[' {
+		if err := json.Unmarshal(body, &objects); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		scanner.Buffer(make([]byte, 64*1024), maxHTTPBody)
+		for line := 1; scanner.Scan(); line++ {
+			text := bytes.TrimSpace(scanner.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+			var obj map[string]any
+			if err := json.Unmarshal(text, &obj); err != nil {
+				return nil, fmt.Errorf("invalid JSON on line %d: %w", line, err)
+			}
+			objects = append(objects, obj)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make([]store.Entry, 0, len(objects))
+	for _, obj := range objects {
+		if obj == nil {
+			continue
+		}
+		source, _ := takeString(obj, []string{"source"})
+		env, _ := takeString(obj, []string{"env", "environment"})
+
+		raw, _ := json.Marshal(obj)
+		entry := entryFromMap(obj, string(raw))
+		if source != "" {
+			entry.Source = "http:" + source
+		}
+		entry.Env = env
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (s *HTTPSource) setCORSHeaders(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return
+	}
+	for _, allowed := range s.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			h := w.Header()
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Jotl-Token")
+			h.Set("Access-Control-Max-Age", "600")
+			h.Add("Vary", "Origin")
+			return
+		}
+	}
+}
+
+// authorized checks the shared secret, which may be sent as a bearer token,
+// an X-Jotl-Token header, or a `token` query parameter for navigator.sendBeacon.
+func (s *HTTPSource) authorized(r *http.Request) bool {
+	if s.Token == "" {
+		return true
+	}
+
+	candidates := []string{
+		strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		r.Header.Get("X-Jotl-Token"),
+		r.URL.Query().Get("token"),
+	}
+	for _, c := range candidates {
+		if c != "" && subtle.ConstantTimeCompare([]byte(c), []byte(s.Token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,220 @@
+package ingest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+func TestIsLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1:8090", true},
+		{"localhost:8090", true},
+		{"[::1]:8090", true},
+		{":8090", false},
+		{"0.0.0.0:8090", false},
+		{"192.168.1.20:8090", false},
+		{"example.com:8090", false},
+		{"8090", false},
+	}
+	for _, tt := range tests {
+		if got := isLoopback(tt.addr); got != tt.want {
+			t.Errorf("isLoopback(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPSourceNeedsTokenOffLoopback(t *testing.T) {
+	src := &HTTPSource{Addr: ":0"}
+	err := src.Run(context.Background(), make(chan Event))
+	if err == nil || !strings.Contains(err.Error(), "without a token") {
+		t.Errorf("Run = %v, want a missing token error", err)
+	}
+}
+
+func TestDecodeEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string // source/level/message of each entry
+	}{
+		{"array", `[{"level":"error","message":"boom","source":"web"},{"msg":"hi"}]`, []string{"http:web/error/boom", "/info/hi"}},
+		{"single object", ` {"level":"warn","message":"slow","status":504} `, []string{"/warn/slow"}},
+		{"ndjson", "{\"message\":\"one\"}\n\n{\"message\":\"two\",\"level\":\"debug\"}\r\n", []string{"/info/one", "/debug/two"}},
+		{"null events are skipped", `[null,{"message":"kept"}]`, []string{"/info/kept"}},
+		{"empty", "  \n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := DecodeEvents([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Source+"/"+string(e.Level)+"/"+e.Message)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("entries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	entries, err := DecodeEvents([]byte(`{"message":"checkout failed","env":"staging","status":"502","timestamp":"2026-03-01T10:00:00Z","cart":3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := entries[0]
+	if e.Env != "staging" || e.StatusCode != 502 || !e.Timestamp.Equal(time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("entry = %+v", e)
+	}
+	if len(e.Fields) != 1 || e.Fields["cart"] != float64(3) {
+		t.Errorf("fields = %v, want only cart", e.Fields)
+	}
+
+	for _, body := range []string{`[{"message":`, `{"message":"ok"}` + "\nnot json", `"just a string"`, `[1, 2]`} {
+		if _, err := DecodeEvents([]byte(body)); err == nil {
+			t.Errorf("DecodeEvents(%q) succeeded, want an error", body)
+		}
+	}
+}
+
+// post sends body to the ingest handler and returns the response with the
+// entries it accepted.
+func post(t *testing.T, src *HTTPSource, method, body string, header http.Header) (*http.Response, []store.Entry) {
+	t.Helper()
+
+	out := make(chan Event, 10)
+	srv := httptest.NewServer(src.Handler(context.Background(), out))
+	defer srv.Close()
+
+	req, err := http.NewRequest(method, srv.URL+HTTPIngestPath, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	var entries []store.Entry
+	for len(out) > 0 {
+		entries = append(entries, *(<-out).Entry)
+	}
+	return resp, entries
+}
+
+func TestHTTPHandler(t *testing.T) {
+	src := &HTTPSource{}
+
+	resp, entries := post(t, src, http.MethodPost, `[{"message":"a"},{"message":"b","source":"browser"}]`, nil)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+	}
+	if len(entries) != 2 || entries[0].Source != "http" || entries[1].Source != "http:browser" {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	resp, entries = post(t, src, http.MethodPost, `{"message":`, nil)
+	if resp.StatusCode != http.StatusBadRequest || len(entries) != 0 {
+		t.Errorf("bad body: status = %d, %d entries, want 400 and none", resp.StatusCode, len(entries))
+	}
+
+	resp, _ = post(t, src, http.MethodGet, "", nil)
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status = %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+
+	resp, _ = post(t, src, http.MethodPost, strings.Repeat(" ", maxHTTPBody+1), nil)
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("large body: status = %d, want %d", resp.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestHTTPHandlerCORS(t *testing.T) {
+	src := &HTTPSource{AllowedOrigins: []string{"http://localhost:5173"}}
+
+	preflight := http.Header{"Origin": {"http://LOCALHOST:5173"}, "Access-Control-Request-Method": {"POST"}}
+	resp, _ := post(t, src, http.MethodOptions, "", preflight)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("preflight status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "http://LOCALHOST:5173" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Authorization") {
+		t.Errorf("Access-Control-Allow-Headers = %q", got)
+	}
+
+	resp, _ = post(t, src, http.MethodOptions, "", http.Header{"Origin": {"https://evil.example"}})
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("other origin allowed: Access-Control-Allow-Origin = %q", got)
+	}
+
+	wildcard := &HTTPSource{AllowedOrigins: []string{"*"}}
+	resp, _ = post(t, wildcard, http.MethodPost, `{"message":"hi"}`, http.Header{"Origin": {"https://app.example"}})
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://app.example" {
+		t.Errorf("wildcard: Access-Control-Allow-Origin = %q", got)
+	}
+}
+
+func TestHTTPHandlerToken(t *testing.T) {
+	src := &HTTPSource{Token: "s3cret"}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		query  string
+		want   int
+	}{
+		{"bearer", http.Header{"Authorization": {"Bearer s3cret"}}, "", http.StatusAccepted},
+		{"header", http.Header{"X-Jotl-Token": {"s3cret"}}, "", http.StatusAccepted},
+		{"query", nil, "?token=s3cret", http.StatusAccepted},
+		{"missing", nil, "", http.StatusUnauthorized},
+		{"wrong bearer", http.Header{"Authorization": {"Bearer guess"}}, "", http.StatusUnauthorized},
+		{"bare token", http.Header{"Authorization": {"s3cret"}}, "", http.StatusAccepted},
+		{"other scheme", http.Header{"Authorization": {"Basic s3cret"}}, "", http.StatusUnauthorized},
+		{"empty bearer", http.Header{"Authorization": {"Bearer "}}, "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := make(chan Event, 1)
+			srv := httptest.NewServer(src.Handler(context.Background(), out))
+			defer srv.Close()
+
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+HTTPIngestPath+tt.query, strings.NewReader(`{"message":"hi"}`))
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if accepted := len(out) == 1; accepted != (tt.want == http.StatusAccepted) {
+				t.Errorf("entry accepted = %v with status %d", accepted, resp.StatusCode)
+			}
+		})
+	}
+
+	// Preflight requests carry no credentials and must still pass.
+	resp, _ := post(t, &HTTPSource{Token: "s3cret", AllowedOrigins: []string{"*"}}, http.MethodOptions, "", http.Header{"Origin": {"https://app.example"}})
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("preflight with a token configured: status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+}
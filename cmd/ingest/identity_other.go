@@ -0,0 +1,11 @@
+//go:build !unix
+
+package ingest
+
+import "os"
+
+// fileIdentity is not available on this platform; checkpoints fall back to
+// path and size checks only.
+func fileIdentity(info os.FileInfo) string {
+	return ""
+}
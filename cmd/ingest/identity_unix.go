@@ -0,0 +1,17 @@
+//go:build unix
+
+package ingest
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the device and inode of a file, which survive renames.
+func fileIdentity(info os.FileInfo) string {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fmt.Sprintf("%d:%d", st.Dev, st.Ino)
+	}
+	return ""
+}
@@ -0,0 +1,201 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+// ImportSource reads a finished log file once, e.g. a dump from another
+// environment. Gzipped files are detected by their content.
+//
+// The checkpoint of an import records how much of the (uncompressed)
+// content was imported and the SHA-256 of that prefix. Importing a file
+// again only reads what was appended since, as long as the prefix is
+// unchanged, and an interrupted import continues where it stopped. Content
+// imported completely before under any name is skipped.
+type ImportSource struct {
+	Path        string
+	Format      string         // One of LineFormats, or FormatAuto
+	MinLevel    flags.LogLevel // Entries below this level are dropped, as by Pipeline.MinLevel
+	Checkpoints CheckpointReader
+
+	// Set by Run.
+	Hash     string // SHA-256 of the whole content
+	Size     int64
+	Empty    bool  // The file holds no content
+	Skipped  bool  // The content had been imported completely before
+	Resumed  int64 // Bytes imported before and not read again
+	Invalid  int   // Lines that didn't match Format and were skipped
+	Filtered int   // Entries below MinLevel
+	Imported int   // Entries stored
+}
+
+// ImportKey is the checkpoint key of imports of the file at path.
+func ImportKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return "import:" + path
+}
+
+func (s *ImportSource) Name() string {
+	return "import " + s.Path
+}
+
+// Run emits every line after the last checkpoint, then returns.
+func (s *ImportSource) Run(ctx context.Context, out chan<- Event) error {
+	key := ImportKey(s.Path)
+	var last store.Checkpoint
+	if s.Checkpoints != nil {
+		cp, found, err := s.Checkpoints.Checkpoint(ctx, key)
+		if err != nil {
+			return err
+		}
+		if found {
+			last = cp
+		}
+	}
+
+	prefix, err := s.hash(last.Offset)
+	if err != nil {
+		return err
+	}
+	if s.Size == 0 {
+		s.Empty = true
+		return nil
+	}
+
+	var offset int64
+	if last.Offset > 0 && prefix == last.Identity {
+		offset = last.Offset
+	}
+	if offset == 0 && s.Checkpoints != nil {
+		// The same content may have been imported under another name.
+		if cp, found, err := s.Checkpoints.CheckpointByIdentity(ctx, s.Hash); err != nil {
+			return err
+		} else if found && cp.Offset == s.Size {
+			offset = s.Size
+		}
+	}
+	if offset >= s.Size {
+		s.Skipped = true
+		return nil
+	}
+	s.Resumed = offset
+
+	r, closer, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	// The hash of the content read so far is the identity of each checkpoint.
+	h := sha256.New()
+	if _, err := io.CopyN(h, r, offset); err != nil {
+		return err
+	}
+
+	source := "import:" + filepath.Base(s.Path)
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadString('\n')
+		if len(line) == 0 && err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		offset += int64(len(line))
+		h.Write([]byte(line))
+		cp := &store.Checkpoint{Key: key, Offset: offset, Identity: hex.EncodeToString(h.Sum(nil))}
+
+		ev := Event{Checkpoint: cp}
+		if text := bytes.TrimSpace([]byte(line)); len(text) > 0 {
+			entry, perr := ParseFormat(s.Format, line)
+			if perr == nil && len(line) > maxLineLength {
+				perr = fmt.Errorf("line longer than %d bytes", maxLineLength)
+			}
+			switch {
+			case perr != nil:
+				s.Invalid++
+				if s.Invalid <= 5 {
+					log.Printf("%s: skipping line %d: %v", s.Path, lineNo, perr)
+				}
+			case s.MinLevel != "" && !entry.Level.AtLeast(s.MinLevel):
+				s.Filtered++
+			default:
+				if entry.Source == "" {
+					entry.Source = source
+				}
+				ev.Entry = &entry
+				s.Imported++
+			}
+		}
+
+		select {
+		case out <- ev:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// hash reads the content once to compute its hash and size. It returns the
+// hash of the first n bytes, or "" if the content is shorter.
+func (s *ImportSource) hash(n int64) (string, error) {
+	r, closer, err := s.open()
+	if err != nil {
+		return "", err
+	}
+	defer closer.Close()
+
+	h := sha256.New()
+	read, err := io.CopyN(h, r, n)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read %s: %w", s.Path, err)
+	}
+	var prefix string
+	if read == n {
+		prefix = hex.EncodeToString(h.Sum(nil))
+	}
+
+	rest, err := io.Copy(h, r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", s.Path, err)
+	}
+	s.Hash = hex.EncodeToString(h.Sum(nil))
+	s.Size = read + rest
+	return prefix, nil
+}
+
+// open returns the uncompressed content of the file.
+func (s *ImportSource) open() (io.Reader, io.Closer, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("failed to read %s: %w", s.Path, err)
+		}
+		return gz, f, nil
+	}
+	return br, f, nil
+}
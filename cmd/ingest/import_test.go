@@ -0,0 +1,104 @@
+package ingest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+)
+
+// runImport imports path like `jotl import`, recording the checkpoints in
+// cps, and returns the source and the messages of the entries it sent.
+func runImport(t *testing.T, path string, cps checkpoints) (*ImportSource, []string) {
+	t.Helper()
+
+	src := &ImportSource{Path: path, Format: FormatAuto, MinLevel: flags.Info, Checkpoints: cps}
+	out := make(chan Event)
+	done := make(chan error, 1)
+	go func() {
+		done <- src.Run(context.Background(), out)
+		close(out)
+	}()
+
+	var messages []string
+	for ev := range out {
+		if ev.Entry != nil {
+			messages = append(messages, ev.Entry.Message)
+		}
+		if ev.Checkpoint != nil {
+			cps[ev.Checkpoint.Key] = *ev.Checkpoint
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	return src, messages
+}
+
+func TestImportSource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.log")
+	write := func(name, content string, flag int) {
+		f, err := os.OpenFile(filepath.Join(dir, name), flag|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cps := checkpoints{}
+
+	steps := []struct {
+		name     string
+		file     string
+		content  string
+		flag     int
+		want     []string
+		skipped  bool
+		filtered int
+	}{
+		{"first import", "api.log", "{\"level\":\"info\",\"msg\":\"one\"}\n{\"level\":\"debug\",\"msg\":\"noise\"}\n", os.O_TRUNC, []string{"one"}, false, 1},
+		{"unchanged", "api.log", "", os.O_APPEND, nil, true, 0},
+		{"grown", "api.log", "{\"level\":\"warn\",\"msg\":\"two\"}\n", os.O_APPEND, []string{"two"}, false, 0},
+		{"copied", "copy.log", "", 0, nil, true, 0},
+		{"rewritten", "api.log", "{\"level\":\"error\",\"msg\":\"three\"}\n", os.O_TRUNC, []string{"three"}, false, 0},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			file := filepath.Join(dir, step.file)
+			if step.file == "copy.log" {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				write(step.file, string(data), os.O_TRUNC)
+			} else {
+				write(step.file, step.content, step.flag)
+			}
+
+			src, got := runImport(t, file, cps)
+			if !slices.Equal(got, step.want) || src.Imported != len(step.want) {
+				t.Errorf("imported %q (Imported = %d), want %q", got, src.Imported, step.want)
+			}
+			if src.Skipped != step.skipped || src.Filtered != step.filtered {
+				t.Errorf("Skipped, Filtered = %v, %d, want %v, %d", src.Skipped, src.Filtered, step.skipped, step.filtered)
+			}
+		})
+	}
+}
+
+func TestImportSourceEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.log")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	src, got := runImport(t, path, checkpoints{})
+	if !src.Empty || src.Skipped || len(got) != 0 {
+		t.Errorf("Empty, Skipped = %v, %v with %q, want an empty file", src.Empty, src.Skipped, got)
+	}
+}
@@ -0,0 +1,31 @@
+// Package ingest turns external log streams into store entries and feeds
+// them through a single batching pipeline into the project database.
+package ingest
+
+import (
+	"context"
+
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+// Event is a parsed log entry emitted by a Source. Sources that can resume
+// after a restart attach the checkpoint reached once this entry is stored;
+// an event with a nil Entry only advances the checkpoint.
+type Event struct {
+	Entry      *store.Entry
+	Checkpoint *store.Checkpoint
+}
+
+// Source is anything `jotl dev` can read log lines from.
+type Source interface {
+	// Name identifies the source in messages shown to the user.
+	Name() string
+	// Run emits events until ctx is cancelled or the source fails.
+	Run(ctx context.Context, out chan<- Event) error
+}
+
+// CheckpointReader looks up previously saved checkpoints.
+type CheckpointReader interface {
+	Checkpoint(ctx context.Context, key string) (store.Checkpoint, bool, error)
+	CheckpointByIdentity(ctx context.Context, identity string) (store.Checkpoint, bool, error)
+}
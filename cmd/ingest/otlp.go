@@ -0,0 +1,202 @@
+package ingest
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+const (
+	// OTLPLogsPath is the standard OTLP/HTTP path for log exports.
+	OTLPLogsPath = "/v1/logs"
+
+	maxOTLPBody = 16 << 20
+)
+
+// OTLPSource receives OpenTelemetry log exports over OTLP/HTTP, in either the
+// protobuf or the JSON encoding, so an instrumented service can point its
+// exporter at Jotl without code changes.
+type OTLPSource struct {
+	Addr string // Listen address, normally ":4318"
+}
+
+func (s *OTLPSource) Name() string {
+	return "otlp " + s.Addr
+}
+
+// Run serves the OTLP logs endpoint until ctx is cancelled.
+func (s *OTLPSource) Run(ctx context.Context, out chan<- Event) error {
+	mux := http.NewServeMux()
+	mux.Handle(OTLPLogsPath, s.Handler(ctx, out))
+	return serveHTTP(ctx, s.Addr, mux)
+}
+
+// Handler returns the OTLP/HTTP logs endpoint, sending records to out.
+func (s *OTLPSource) Handler(ctx context.Context, out chan<- Event) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSONError(w, http.StatusMethodNotAllowed, "only POST is supported")
+			return
+		}
+
+		isJSON := false
+		switch mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType {
+		case "application/json":
+			isJSON = true
+		case "application/x-protobuf", "application/protobuf":
+		default:
+			writeJSONError(w, http.StatusUnsupportedMediaType, "content type must be application/x-protobuf or application/json")
+			return
+		}
+
+		var body io.Reader = http.MaxBytesReader(w, r.Body, maxOTLPBody)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(body)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid gzip body")
+				return
+			}
+			defer gz.Close()
+			body = io.LimitReader(gz, maxOTLPBody)
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "failed to read request body")
+			return
+		}
+
+		var logs []otlpResourceLogs
+		if isJSON {
+			logs, err = decodeOTLPJSON(data)
+		} else {
+			logs, err = decodeOTLPProto(data)
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		for _, entry := range otlpEntries(logs) {
+			select {
+			case out <- Event{Entry: &entry}:
+			case <-ctx.Done():
+				writeJSONError(w, http.StatusServiceUnavailable, "shutting down")
+				return
+			}
+		}
+
+		// An empty ExportLogsServiceResponse means full success.
+		if isJSON {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("{}"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// otlpEntries flattens decoded resource logs into entries. The service name
+// and deployment environment come from resource attributes; other resource
+// attributes and the instrumentation scope are kept as fields.
+func otlpEntries(logs []otlpResourceLogs) []store.Entry {
+	var entries []store.Entry
+	for _, rl := range logs {
+		var service, env string
+		resource := map[string]any{}
+		for _, kv := range rl.Resource {
+			switch kv.Key {
+			case "service.name":
+				service, _ = kv.Value.(string)
+			case "deployment.environment.name", "deployment.environment":
+				env, _ = kv.Value.(string)
+			default:
+				resource[kv.Key] = kv.Value
+			}
+		}
+
+		for _, sl := range rl.ScopeLogs {
+			for _, rec := range sl.Records {
+				entry := store.Entry{
+					Level:   otlpLevel(rec.SeverityNumber, rec.SeverityText),
+					Source:  "otlp",
+					Env:     env,
+					Service: service,
+					TraceID: hexID(rec.TraceID),
+					SpanID:  hexID(rec.SpanID),
+					Fields:  map[string]any{},
+				}
+
+				switch {
+				case rec.TimeUnixNano > 0:
+					entry.Timestamp = time.Unix(0, int64(rec.TimeUnixNano))
+				case rec.ObservedTimeUnixNano > 0:
+					entry.Timestamp = time.Unix(0, int64(rec.ObservedTimeUnixNano))
+				}
+
+				switch body := rec.Body.(type) {
+				case string:
+					entry.Message = body
+				case nil:
+				default:
+					data, _ := json.Marshal(body)
+					entry.Message = string(data)
+					entry.Fields["body"] = body
+				}
+
+				for _, kv := range rec.Attributes {
+					entry.Fields[kv.Key] = kv.Value
+				}
+				if code, ok := entry.Fields["http.response.status_code"]; ok {
+					entry.StatusCode = toInt(code)
+				}
+				if len(resource) > 0 {
+					entry.Fields["resource"] = resource
+				}
+				if sl.ScopeName != "" {
+					entry.Fields["scope"] = sl.ScopeName
+				}
+
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+// otlpLevel maps an OTLP severity number (1-24) onto a Jotl level, falling
+// back to the severity text when no number was set.
+func otlpLevel(number int32, text string) flags.LogLevel {
+	switch {
+	case number >= 17: // ERROR, FATAL
+		return flags.Error
+	case number >= 13: // WARN
+		return flags.Warn
+	case number >= 9: // INFO
+		return flags.Info
+	case number >= 1: // TRACE, DEBUG
+		return flags.Debug
+	}
+	if level := NormalizeLevel(text); level != "" {
+		return level
+	}
+	return flags.Info
+}
+
+// hexID encodes a trace or span ID, treating all-zero IDs as absent.
+func hexID(id []byte) string {
+	for _, b := range id {
+		if b != 0 {
+			return hex.EncodeToString(id)
+		}
+	}
+	return ""
+}
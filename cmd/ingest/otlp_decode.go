@@ -0,0 +1,409 @@
+package ingest
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The types below mirror the parts of opentelemetry/proto/logs/v1 that Jotl
+// stores. They are decoded by hand so the receiver doesn't pull in the
+// generated OTLP packages and their gRPC dependencies.
+
+type otlpResourceLogs struct {
+	Resource  []otlpKeyValue
+	ScopeLogs []otlpScopeLogs
+}
+
+type otlpScopeLogs struct {
+	ScopeName    string
+	ScopeVersion string
+	Records      []otlpLogRecord
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         uint64
+	ObservedTimeUnixNano uint64
+	SeverityNumber       int32
+	SeverityText         string
+	Body                 any
+	Attributes           []otlpKeyValue
+	TraceID              []byte
+	SpanID               []byte
+}
+
+type otlpKeyValue struct {
+	Key   string
+	Value any
+}
+
+// decodeOTLPProto decodes a protobuf ExportLogsServiceRequest.
+func decodeOTLPProto(b []byte) ([]otlpResourceLogs, error) {
+	var out []otlpResourceLogs
+	err := walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		rl, err := decodeResourceLogs(v)
+		if err != nil {
+			return err
+		}
+		out = append(out, rl)
+		return nil
+	})
+	return out, err
+}
+
+func decodeResourceLogs(b []byte) (otlpResourceLogs, error) {
+	var rl otlpResourceLogs
+	err := walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1: // resource
+			return walkProto(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					kv, err := decodeKeyValue(v)
+					rl.Resource = append(rl.Resource, kv)
+					return err
+				}
+				return nil
+			})
+		case 2: // scope_logs
+			sl, err := decodeScopeLogs(v)
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+			return err
+		}
+		return nil
+	})
+	return rl, err
+}
+
+func decodeScopeLogs(b []byte) (otlpScopeLogs, error) {
+	var sl otlpScopeLogs
+	err := walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1: // scope
+			return walkProto(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				switch {
+				case num == 1 && typ == protowire.BytesType:
+					sl.ScopeName = string(v)
+				case num == 2 && typ == protowire.BytesType:
+					sl.ScopeVersion = string(v)
+				}
+				return nil
+			})
+		case 2: // log_records
+			rec, err := decodeLogRecord(v)
+			sl.Records = append(sl.Records, rec)
+			return err
+		}
+		return nil
+	})
+	return sl, err
+}
+
+func decodeLogRecord(b []byte) (otlpLogRecord, error) {
+	var rec otlpLogRecord
+	err := walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		switch num {
+		case 1:
+			rec.TimeUnixNano = n
+		case 11:
+			rec.ObservedTimeUnixNano = n
+		case 2:
+			rec.SeverityNumber = int32(n)
+		case 3:
+			rec.SeverityText = string(v)
+		case 5:
+			body, err := decodeAnyValue(v)
+			rec.Body = body
+			return err
+		case 6:
+			kv, err := decodeKeyValue(v)
+			rec.Attributes = append(rec.Attributes, kv)
+			return err
+		case 9:
+			rec.TraceID = append([]byte(nil), v...)
+		case 10:
+			rec.SpanID = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	return rec, err
+}
+
+func decodeKeyValue(b []byte) (otlpKeyValue, error) {
+	var kv otlpKeyValue
+	err := walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			kv.Key = string(v)
+		case num == 2 && typ == protowire.BytesType:
+			val, err := decodeAnyValue(v)
+			kv.Value = val
+			return err
+		}
+		return nil
+	})
+	return kv, err
+}
+
+// decodeAnyValue converts an AnyValue into the equivalent Go value.
+func decodeAnyValue(b []byte) (any, error) {
+	var out any
+	err := walkProto(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		switch num {
+		case 1:
+			out = string(v)
+		case 2:
+			out = n != 0
+		case 3:
+			out = int64(n)
+		case 4:
+			out = otlpDouble(math.Float64frombits(n))
+		case 5, 6: // array_value, kvlist_value: both a repeated field 1
+			var items []any
+			m := map[string]any{}
+			err := walkProto(v, func(inner protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if inner != 1 || typ != protowire.BytesType {
+					return nil
+				}
+				if num == 5 {
+					item, err := decodeAnyValue(v)
+					items = append(items, item)
+					return err
+				}
+				kv, err := decodeKeyValue(v)
+				m[kv.Key] = kv.Value
+				return err
+			})
+			if num == 5 {
+				out = items
+			} else {
+				out = m
+			}
+			return err
+		case 7:
+			out = base64.StdEncoding.EncodeToString(v)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// walkProto calls fn for every field in a protobuf message. Length-delimited
+// fields are passed as v; varint and fixed-width fields as n.
+func walkProto(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		num, typ, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return protowire.ParseError(l)
+		}
+		b = b[l:]
+
+		var v []byte
+		var n uint64
+		switch typ {
+		case protowire.VarintType:
+			n, l = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var n32 uint32
+			n32, l = protowire.ConsumeFixed32(b)
+			n = uint64(n32)
+		case protowire.Fixed64Type:
+			n, l = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			v, l = protowire.ConsumeBytes(b)
+		default:
+			l = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if l < 0 {
+			return protowire.ParseError(l)
+		}
+		b = b[l:]
+
+		if err := fn(num, typ, v, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OTLP/JSON uses lowerCamelCase names, hex-encoded IDs and strings for
+// 64-bit integers.
+
+type otlpJSONRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpJSONKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			Scope struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"scope"`
+			LogRecords []struct {
+				TimeUnixNano         json.Number        `json:"timeUnixNano"`
+				ObservedTimeUnixNano json.Number        `json:"observedTimeUnixNano"`
+				SeverityNumber       int32              `json:"severityNumber"`
+				SeverityText         string             `json:"severityText"`
+				Body                 *otlpJSONAnyValue  `json:"body"`
+				Attributes           []otlpJSONKeyValue `json:"attributes"`
+				TraceID              string             `json:"traceId"`
+				SpanID               string             `json:"spanId"`
+			} `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type otlpJSONKeyValue struct {
+	Key   string            `json:"key"`
+	Value *otlpJSONAnyValue `json:"value"`
+}
+
+type otlpJSONAnyValue struct {
+	StringValue *string         `json:"stringValue"`
+	BoolValue   *bool           `json:"boolValue"`
+	IntValue    json.Number     `json:"intValue"`
+	DoubleValue *otlpJSONDouble `json:"doubleValue"`
+	BytesValue  *string         `json:"bytesValue"`
+	ArrayValue  *struct {
+		Values []*otlpJSONAnyValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []otlpJSONKeyValue `json:"values"`
+	} `json:"kvlistValue"`
+}
+
+// decodeOTLPJSON decodes a JSON ExportLogsServiceRequest.
+func decodeOTLPJSON(b []byte) ([]otlpResourceLogs, error) {
+	var req otlpJSONRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		return nil, fmt.Errorf("invalid OTLP JSON: %w", err)
+	}
+
+	out := make([]otlpResourceLogs, 0, len(req.ResourceLogs))
+	for _, jrl := range req.ResourceLogs {
+		rl := otlpResourceLogs{Resource: jsonKeyValues(jrl.Resource.Attributes)}
+		for _, jsl := range jrl.ScopeLogs {
+			sl := otlpScopeLogs{ScopeName: jsl.Scope.Name, ScopeVersion: jsl.Scope.Version}
+			for _, jr := range jsl.LogRecords {
+				rec := otlpLogRecord{
+					SeverityNumber: jr.SeverityNumber,
+					SeverityText:   jr.SeverityText,
+					Body:           jr.Body.value(),
+					Attributes:     jsonKeyValues(jr.Attributes),
+				}
+				var err error
+				if rec.TimeUnixNano, err = jsonUint(jr.TimeUnixNano); err != nil {
+					return nil, err
+				}
+				if rec.ObservedTimeUnixNano, err = jsonUint(jr.ObservedTimeUnixNano); err != nil {
+					return nil, err
+				}
+				if rec.TraceID, err = hex.DecodeString(jr.TraceID); err != nil {
+					return nil, errors.New("invalid OTLP JSON: traceId is not hex")
+				}
+				if rec.SpanID, err = hex.DecodeString(jr.SpanID); err != nil {
+					return nil, errors.New("invalid OTLP JSON: spanId is not hex")
+				}
+				sl.Records = append(sl.Records, rec)
+			}
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+		out = append(out, rl)
+	}
+	return out, nil
+}
+
+func jsonKeyValues(kvs []otlpJSONKeyValue) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		out = append(out, otlpKeyValue{Key: kv.Key, Value: kv.Value.value()})
+	}
+	return out
+}
+
+func (v *otlpJSONAnyValue) value() any {
+	switch {
+	case v == nil:
+		return nil
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.IntValue != "":
+		n, _ := v.IntValue.Int64()
+		return n
+	case v.DoubleValue != nil:
+		return otlpDouble(float64(*v.DoubleValue))
+	case v.BytesValue != nil:
+		return *v.BytesValue
+	case v.ArrayValue != nil:
+		items := make([]any, 0, len(v.ArrayValue.Values))
+		for _, item := range v.ArrayValue.Values {
+			items = append(items, item.value())
+		}
+		return items
+	case v.KvlistValue != nil:
+		m := map[string]any{}
+		for _, kv := range v.KvlistValue.Values {
+			m[kv.Key] = kv.Value.value()
+		}
+		return m
+	}
+	return nil
+}
+
+// otlpJSONDouble is a doubleValue, which protojson writes as a number or, for
+// values JSON can't represent, as "NaN", "Infinity" or "-Infinity".
+type otlpJSONDouble float64
+
+func (d *otlpJSONDouble) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("%s is not a double", b)
+	}
+	*d = otlpJSONDouble(f)
+	return nil
+}
+
+// otlpDouble returns NaN and infinities as strings: stored fields are JSON,
+// which can't hold them.
+func otlpDouble(f float64) any {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return f
+}
+
+func jsonUint(n json.Number) (uint64, error) {
+	if n == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseUint(string(n), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid OTLP JSON: %q is not a timestamp", n)
+	}
+	return v, nil
+}
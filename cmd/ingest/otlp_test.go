@@ -0,0 +1,342 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Builders for protobuf ExportLogsServiceRequest bodies.
+
+func pbMessage(fields ...[]byte) []byte {
+	return bytes.Join(fields, nil)
+}
+
+func pbBytes(num protowire.Number, v []byte) []byte {
+	b := protowire.AppendTag(nil, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func pbString(num protowire.Number, s string) []byte {
+	return pbBytes(num, []byte(s))
+}
+
+func pbVarint(num protowire.Number, n uint64) []byte {
+	b := protowire.AppendTag(nil, num, protowire.VarintType)
+	return protowire.AppendVarint(b, n)
+}
+
+func pbFixed64(num protowire.Number, n uint64) []byte {
+	b := protowire.AppendTag(nil, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, n)
+}
+
+func pbKeyValue(key string, value []byte) []byte {
+	return pbMessage(pbString(1, key), pbBytes(2, value))
+}
+
+func pbArray(values ...[]byte) []byte {
+	var fields [][]byte
+	for _, v := range values {
+		fields = append(fields, pbBytes(1, v))
+	}
+	return pbBytes(5, pbMessage(fields...))
+}
+
+func pbKvlist(kvs ...[]byte) []byte {
+	var fields [][]byte
+	for _, kv := range kvs {
+		fields = append(fields, pbBytes(1, kv))
+	}
+	return pbBytes(6, pbMessage(fields...))
+}
+
+// pbRequest wraps log records in a request with one resource and scope.
+func pbRequest(resource [][]byte, records ...[]byte) []byte {
+	var attrs [][]byte
+	for _, kv := range resource {
+		attrs = append(attrs, pbBytes(1, kv))
+	}
+	scope := [][]byte{pbBytes(1, pbMessage(pbString(1, "checkout"), pbString(2, "1.0.0")))}
+	for _, r := range records {
+		scope = append(scope, pbBytes(2, r))
+	}
+	return pbBytes(1, pbMessage(
+		pbBytes(1, pbMessage(attrs...)),
+		pbBytes(2, pbMessage(scope...)),
+	))
+}
+
+var (
+	otlpTime     = time.Date(2026, 3, 1, 10, 0, 0, 5, time.UTC)
+	otlpObserved = otlpTime.Add(time.Second)
+	traceID      = []byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	spanID       = []byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+)
+
+func TestOTLPDecode(t *testing.T) {
+	resource := [][]byte{
+		pbKeyValue("service.name", pbString(1, "checkout")),
+		pbKeyValue("deployment.environment.name", pbString(1, "prod")),
+		pbKeyValue("host.name", pbString(1, "web-1")),
+	}
+	resourceFields := map[string]any{"host.name": "web-1"}
+
+	tests := []struct {
+		name  string
+		proto []byte
+		json  string
+		want  []store.Entry
+	}{
+		{
+			name: "resource, severity and ids",
+			proto: pbRequest(resource, pbMessage(
+				pbFixed64(1, uint64(otlpTime.UnixNano())),
+				pbVarint(2, 17),
+				pbString(3, "ERROR"),
+				pbBytes(5, pbString(1, "payment failed")),
+				pbBytes(6, pbKeyValue("http.response.status_code", pbVarint(3, 502))),
+				pbBytes(9, traceID),
+				pbBytes(10, spanID),
+			)),
+			json: `{"resourceLogs":[{"resource":{"attributes":[
+				{"key":"service.name","value":{"stringValue":"checkout"}},
+				{"key":"deployment.environment.name","value":{"stringValue":"prod"}},
+				{"key":"host.name","value":{"stringValue":"web-1"}}]},
+				"scopeLogs":[{"scope":{"name":"checkout","version":"1.0.0"},"logRecords":[{
+					"timeUnixNano":"1772359200000000005","severityNumber":17,"severityText":"ERROR",
+					"body":{"stringValue":"payment failed"},
+					"attributes":[{"key":"http.response.status_code","value":{"intValue":"502"}}],
+					"traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"00f067aa0ba902b7"}]}]}]}`,
+			want: []store.Entry{{
+				Timestamp:  time.Unix(0, otlpTime.UnixNano()),
+				Level:      flags.Error,
+				Message:    "payment failed",
+				Source:     "otlp",
+				Env:        "prod",
+				Service:    "checkout",
+				StatusCode: 502,
+				TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanID:     "00f067aa0ba902b7",
+				Fields: map[string]any{
+					"http.response.status_code": int64(502),
+					"resource":                  resourceFields,
+					"scope":                     "checkout",
+				},
+			}},
+		},
+		{
+			name: "severity text and observed time",
+			proto: pbRequest(resource, pbMessage(
+				pbFixed64(11, uint64(otlpObserved.UnixNano())),
+				pbString(3, "warning"),
+				pbBytes(5, pbString(1, "slow")),
+				pbBytes(9, make([]byte, 16)), // all-zero IDs are absent
+			)),
+			json: `{"resourceLogs":[{"resource":{"attributes":[
+				{"key":"service.name","value":{"stringValue":"checkout"}},
+				{"key":"deployment.environment.name","value":{"stringValue":"prod"}},
+				{"key":"host.name","value":{"stringValue":"web-1"}}]},
+				"scopeLogs":[{"scope":{"name":"checkout"},"logRecords":[{
+					"observedTimeUnixNano":"1772359201000000005","severityText":"warning",
+					"body":{"stringValue":"slow"},"traceId":"00000000000000000000000000000000"}]}]}]}`,
+			want: []store.Entry{{
+				Timestamp: time.Unix(0, otlpObserved.UnixNano()),
+				Level:     flags.Warn,
+				Message:   "slow",
+				Source:    "otlp",
+				Env:       "prod",
+				Service:   "checkout",
+				Fields:    map[string]any{"resource": resourceFields, "scope": "checkout"},
+			}},
+		},
+		{
+			name: "structured values",
+			proto: pbRequest(nil, pbMessage(
+				pbVarint(2, 9),
+				pbBytes(5, pbKvlist(pbKeyValue("order", pbVarint(3, 7)))),
+				pbBytes(6, pbKeyValue("tags", pbArray(pbString(1, "a"), pbVarint(2, 1), pbFixed64(4, math.Float64bits(1.5))))),
+				pbBytes(6, pbKeyValue("raw", pbBytes(7, []byte("hi")))),
+				pbBytes(6, pbKeyValue("nested", pbKvlist(pbKeyValue("ok", pbVarint(2, 0))))),
+			)),
+			json: `{"resourceLogs":[{"scopeLogs":[{"scope":{"name":"checkout"},"logRecords":[{
+				"severityNumber":9,
+				"body":{"kvlistValue":{"values":[{"key":"order","value":{"intValue":"7"}}]}},
+				"attributes":[
+					{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"},{"boolValue":true},{"doubleValue":1.5}]}}},
+					{"key":"raw","value":{"bytesValue":"aGk="}},
+					{"key":"nested","value":{"kvlistValue":{"values":[{"key":"ok","value":{"boolValue":false}}]}}}]}]}]}]}`,
+			want: []store.Entry{{
+				Level:   flags.Info,
+				Message: `{"order":7}`,
+				Source:  "otlp",
+				Fields: map[string]any{
+					"body":   map[string]any{"order": int64(7)},
+					"tags":   []any{"a", true, 1.5},
+					"raw":    "aGk=",
+					"nested": map[string]any{"ok": false},
+					"scope":  "checkout",
+				},
+			}},
+		},
+		{
+			name: "non-finite doubles",
+			proto: pbRequest(nil, pbMessage(
+				pbBytes(5, pbString(1, "ratio")),
+				pbBytes(6, pbKeyValue("nan", pbFixed64(4, math.Float64bits(math.NaN())))),
+				pbBytes(6, pbKeyValue("inf", pbFixed64(4, math.Float64bits(math.Inf(1))))),
+				pbBytes(6, pbKeyValue("ninf", pbArray(pbFixed64(4, math.Float64bits(math.Inf(-1)))))),
+			)),
+			json: `{"resourceLogs":[{"scopeLogs":[{"scope":{"name":"checkout"},"logRecords":[{
+				"body":{"stringValue":"ratio"},
+				"attributes":[
+					{"key":"nan","value":{"doubleValue":"NaN"}},
+					{"key":"inf","value":{"doubleValue":"Infinity"}},
+					{"key":"ninf","value":{"arrayValue":{"values":[{"doubleValue":"-Infinity"}]}}}]}]}]}]}`,
+			want: []store.Entry{{
+				Level:   flags.Info,
+				Message: "ratio",
+				Source:  "otlp",
+				Fields: map[string]any{
+					"nan":   "NaN",
+					"inf":   "Infinity",
+					"ninf":  []any{"-Infinity"},
+					"scope": "checkout",
+				},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		for _, enc := range []string{"proto", "json"} {
+			t.Run(tt.name+"/"+enc, func(t *testing.T) {
+				var logs []otlpResourceLogs
+				var err error
+				if enc == "proto" {
+					logs, err = decodeOTLPProto(tt.proto)
+				} else {
+					logs, err = decodeOTLPJSON([]byte(tt.json))
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := otlpEntries(logs)
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("entries =\n%#v\nwant\n%#v", got, tt.want)
+				}
+				for _, e := range got {
+					if _, err := json.Marshal(e.Fields); err != nil {
+						t.Errorf("fields can't be stored: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestOTLPDecodeErrors(t *testing.T) {
+	if _, err := decodeOTLPProto([]byte{0x0a, 0x05, 0x01}); err == nil {
+		t.Error("decodeOTLPProto accepted a truncated message")
+	}
+
+	for _, body := range []string{
+		`not json`,
+		`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"traceId":"xyz"}]}]}]}`,
+		`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"timeUnixNano":"-1"}]}]}]}`,
+		`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"body":{"doubleValue":"lots"}}]}]}]}`,
+	} {
+		if _, err := decodeOTLPJSON([]byte(body)); err == nil {
+			t.Errorf("decodeOTLPJSON(%s) succeeded, want an error", body)
+		}
+	}
+}
+
+func TestOTLPLevel(t *testing.T) {
+	tests := []struct {
+		number int32
+		text   string
+		want   flags.LogLevel
+	}{
+		{0, "", flags.Info},
+		{1, "", flags.Debug},  // TRACE
+		{5, "", flags.Debug},  // DEBUG
+		{9, "", flags.Info},   // INFO
+		{12, "", flags.Info},  // INFO4
+		{13, "", flags.Warn},  // WARN
+		{17, "", flags.Error}, // ERROR
+		{21, "", flags.Error}, // FATAL
+		{9, "ERROR", flags.Info},
+		{0, "Warning", flags.Warn},
+		{0, "fatal", flags.Error},
+		{0, "verbose", flags.Info},
+	}
+	for _, tt := range tests {
+		if got := otlpLevel(tt.number, tt.text); got != tt.want {
+			t.Errorf("otlpLevel(%d, %q) = %s, want %s", tt.number, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestOTLPHandler(t *testing.T) {
+	out := make(chan Event, 10)
+	srv := httptest.NewServer((&OTLPSource{}).Handler(context.Background(), out))
+	defer srv.Close()
+
+	body := pbRequest(nil, pbMessage(pbBytes(5, pbString(1, "hello"))))
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(body)
+	zw.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		gzip        bool
+		body        []byte
+		status      int
+		entries     int
+	}{
+		{"protobuf", "application/x-protobuf", false, body, http.StatusOK, 1},
+		{"gzip", "application/x-protobuf", true, gz.Bytes(), http.StatusOK, 1},
+		{"json", "application/json; charset=utf-8", false, []byte(`{"resourceLogs":[{"scopeLogs":[{"logRecords":[{"body":{"stringValue":"hi"}}]}]}]}`), http.StatusOK, 1},
+		{"bad body", "application/x-protobuf", false, []byte{0x0a, 0x05}, http.StatusBadRequest, 0},
+		{"bad content type", "text/plain", false, body, http.StatusUnsupportedMediaType, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, srv.URL+OTLPLogsPath, bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if len(out) != tt.entries {
+				t.Errorf("received %d entries, want %d", len(out), tt.entries)
+			}
+			for len(out) > 0 {
+				if ev := <-out; !strings.HasPrefix(ev.Entry.Message, "h") {
+					t.Errorf("message = %q", ev.Entry.Message)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package ingest
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+var (
+	levelKeys   = []string{"level", "lvl", "severity"}
+	messageKeys = []string{"msg", "message"}
+	timeKeys    = []string{"time", "ts", "timestamp"}
+	statusKeys  = []string{"status", "status_code", "statusCode"}
+
+	textLevelPattern = regexp.MustCompile(`(?i)\b(trace|debug|info|notice|warn|warning|error|err|fatal|panic|critical|crit)\b`)
+	// Common/combined log format (`"GET / HTTP/1.1" 200 `), Gin (`| 200 |`)
+	// and Rails (`Completed 200 OK`).
+	textStatusPattern = regexp.MustCompile(`" (\d{3}) |\|\s+([1-5]\d{2})\s+\||Completed ([1-5]\d{2}) `)
+)
+
+// NormalizeLevel maps the many spellings of a severity onto a Jotl level.
+// It returns an empty level when s is not recognised.
+func NormalizeLevel(s string) flags.LogLevel {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "trace", "debug":
+		return flags.Debug
+	case "info", "information", "notice":
+		return flags.Info
+	case "warn", "warning":
+		return flags.Warn
+	case "error", "err", "fatal", "panic", "critical", "crit", "alert", "emerg", "emergency":
+		return flags.Error
+	default:
+		return ""
+	}
+}
+
+// ParseLine turns a raw log line into an entry. JSON objects have their
+// well-known keys extracted and the rest kept as fields; anything else is
+// treated as plain text with a best-effort level and status code.
+func ParseLine(line string) store.Entry {
+	line = strings.TrimRight(line, "\r\n")
+
+	if strings.HasPrefix(strings.TrimSpace(line), "{") {
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(line), &obj); err == nil {
+			return entryFromMap(obj, line)
+		}
+	}
+
+	return parseText(line)
+}
+
+func parseText(line string) store.Entry {
+	entry := store.Entry{Message: line, Level: flags.Info}
+	if m := textLevelPattern.FindStringSubmatch(line); m != nil {
+		entry.Level = NormalizeLevel(m[1])
+	}
+	if m := textStatusPattern.FindStringSubmatch(line); m != nil {
+		entry.StatusCode, _ = strconv.Atoi(m[1] + m[2] + m[3])
+	}
+	return entry
+}
+
+// entryFromMap builds an entry from a decoded structured record, moving
+// recognised keys onto the entry and leaving the rest in Fields.
+func entryFromMap(obj map[string]any, raw string) store.Entry {
+	entry := store.Entry{Level: flags.Info}
+
+	if v, ok := takeString(obj, levelKeys); ok {
+		if level := NormalizeLevel(v); level != "" {
+			entry.Level = level
+		}
+	}
+	if v, ok := takeString(obj, messageKeys); ok {
+		entry.Message = v
+	} else {
+		entry.Message = raw
+	}
+	if v, ok := take(obj, timeKeys); ok {
+		entry.Timestamp = parseTime(v)
+	}
+	if v, ok := take(obj, statusKeys); ok {
+		entry.StatusCode = toInt(v)
+	}
+
+	if len(obj) > 0 {
+		entry.Fields = obj
+	}
+	return entry
+}
+
+func take(obj map[string]any, keys []string) (any, bool) {
+	for _, k := range keys {
+		if v, ok := obj[k]; ok {
+			delete(obj, k)
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func takeString(obj map[string]any, keys []string) (string, bool) {
+	v, ok := take(obj, keys)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+// parseTime accepts RFC 3339 strings and unix timestamps in seconds or
+// milliseconds. Unrecognised values yield the zero time.
+func parseTime(v any) time.Time {
+	switch t := v.(type) {
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999"} {
+			if ts, err := time.Parse(layout, t); err == nil {
+				return ts
+			}
+		}
+		if f, err := strconv.ParseFloat(t, 64); err == nil {
+			return unixTime(f)
+		}
+	case float64:
+		return unixTime(t)
+	}
+	return time.Time{}
+}
+
+func unixTime(f float64) time.Time {
+	if f > 1e12 {
+		return time.UnixMilli(int64(f))
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9))
+}
+
+func toInt(v any) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case int64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	}
+	return 0
+}
@@ -0,0 +1,169 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+const (
+	defaultBatchSize     = 500
+	defaultFlushInterval = time.Second
+)
+
+// Pipeline collects events from every source and writes them to the store
+// in batches, together with the checkpoints they advance.
+type Pipeline struct {
+	Store         store.Store
+	Env           string         // Environment recorded on entries that don't carry one
+	MinLevel      flags.LogLevel // Entries below this level are dropped
+	RunID         string         // Run recorded on every entry
+	BatchSize     int
+	FlushInterval time.Duration
+	// OnInsert, if set, is called with every batch after it is stored. The
+	// slice is reused afterwards and must not be retained.
+	OnInsert func(entries []store.Entry)
+}
+
+// Run starts all sources and blocks until ctx is cancelled or one of them
+// fails. Pending entries are flushed before it returns.
+func (p *Pipeline) Run(ctx context.Context, sources ...Source) error {
+	if len(sources) == 0 {
+		return errors.New("no log sources configured")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan Event, p.batchSize())
+	errs := make(chan error, len(sources))
+
+	var wg sync.WaitGroup
+	for _, src := range sources {
+		wg.Add(1)
+		go func(src Source) {
+			defer wg.Done()
+			if err := src.Run(ctx, events); err != nil && !errors.Is(err, context.Canceled) {
+				errs <- fmt.Errorf("%s: %w", src.Name(), err)
+				cancel()
+			}
+		}(src)
+	}
+
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+
+	writeErr := p.write(events)
+	if writeErr != nil {
+		// Stop the sources and let blocked sends through; events is closed
+		// once all of them returned, so none can send on errs after this.
+		cancel()
+		for range events {
+		}
+	}
+	close(errs)
+
+	if writeErr != nil {
+		return writeErr
+	}
+	for err := range errs {
+		return err
+	}
+	return nil
+}
+
+// write drains events until the channel is closed.
+func (p *Pipeline) write(events <-chan Event) error {
+	ticker := time.NewTicker(p.flushInterval())
+	defer ticker.Stop()
+
+	var entries []store.Entry
+	checkpoints := map[string]store.Checkpoint{}
+
+	flush := func() error {
+		if len(entries) == 0 && len(checkpoints) == 0 {
+			return nil
+		}
+		cps := make([]store.Checkpoint, 0, len(checkpoints))
+		for _, cp := range checkpoints {
+			cps = append(cps, cp)
+		}
+		// Use a fresh context so the final flush still succeeds after shutdown.
+		if err := p.Store.Insert(context.Background(), entries, cps); err != nil {
+			return err
+		}
+		if p.OnInsert != nil && len(entries) > 0 {
+			p.OnInsert(entries)
+		}
+		entries = entries[:0]
+		clear(checkpoints)
+		return nil
+	}
+
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return flush()
+			}
+			if ev.Checkpoint != nil {
+				checkpoints[ev.Checkpoint.Key] = *ev.Checkpoint
+			}
+			if ev.Entry != nil {
+				if entry, keep := p.prepare(*ev.Entry); keep {
+					entries = append(entries, entry)
+				}
+			}
+			if len(entries) >= p.batchSize() {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		case <-ticker.C:
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// prepare fills in defaults and applies the minimum level filter.
+func (p *Pipeline) prepare(e store.Entry) (store.Entry, bool) {
+	if e.Level == "" {
+		e.Level = flags.Info
+	}
+	if p.MinLevel != "" && !e.Level.AtLeast(p.MinLevel) {
+		return e, false
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+	if e.Env == "" {
+		e.Env = p.Env
+	}
+	if e.RunID == "" {
+		e.RunID = p.RunID
+	}
+	return e, true
+}
+
+func (p *Pipeline) batchSize() int {
+	if p.BatchSize > 0 {
+		return p.BatchSize
+	}
+	return defaultBatchSize
+}
+
+func (p *Pipeline) flushInterval() time.Duration {
+	if p.FlushInterval > 0 {
+		return p.FlushInterval
+	}
+	return defaultFlushInterval
+}
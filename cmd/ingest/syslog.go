@@ -0,0 +1,170 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const maxSyslogMessage = 64 * 1024
+
+// SyslogSource listens for syslog messages on both UDP and TCP.
+//
+// UDP datagrams carry one message each. TCP streams may use either
+// octet-counting framing ("LEN SP MSG", RFC 6587) or newline-terminated
+// messages; the framing is detected per message.
+type SyslogSource struct {
+	Addr string // Listen address, e.g. ":5514"
+}
+
+func (s *SyslogSource) Name() string {
+	return "syslog " + s.Addr
+}
+
+// Run accepts messages until ctx is cancelled.
+func (s *SyslogSource) Run(ctx context.Context, out chan<- Event) error {
+	udp, err := net.ListenPacket("udp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s: %w", s.Addr, err)
+	}
+	tcp, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		udp.Close()
+		return fmt.Errorf("failed to listen on tcp %s: %w", s.Addr, err)
+	}
+	return s.serve(ctx, udp, tcp, out)
+}
+
+// serve reads messages from udp and connections accepted on tcp until ctx
+// is cancelled or tcp fails, and closes both.
+func (s *SyslogSource) serve(ctx context.Context, udp net.PacketConn, tcp net.Listener, out chan<- Event) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var conns sync.Map
+	go func() {
+		<-ctx.Done()
+		udp.Close()
+		tcp.Close()
+		conns.Range(func(c, _ any) bool {
+			c.(net.Conn).Close()
+			return true
+		})
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.serveUDP(ctx, udp, out)
+	}()
+
+	var acceptErr error
+	for {
+		conn, err := tcp.Accept()
+		if err != nil {
+			// Unless ctx closed the listener, stop the UDP side too and
+			// report why the source stopped.
+			if ctx.Err() == nil {
+				acceptErr = fmt.Errorf("failed to accept syslog connection on %s: %w", s.Addr, err)
+				cancel()
+			}
+			break
+		}
+		conns.Store(conn, struct{}{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conns.Delete(conn)
+			defer conn.Close()
+			s.serveTCP(ctx, conn, out)
+		}()
+	}
+
+	wg.Wait()
+	if acceptErr != nil {
+		return acceptErr
+	}
+	return ctx.Err()
+}
+
+func (s *SyslogSource) serveUDP(ctx context.Context, conn net.PacketConn, out chan<- Event) {
+	buf := make([]byte, maxSyslogMessage)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if !s.emit(ctx, string(buf[:n]), out) {
+			return
+		}
+	}
+}
+
+func (s *SyslogSource) serveTCP(ctx context.Context, conn net.Conn, out chan<- Event) {
+	r := bufio.NewReader(conn)
+	for {
+		msg, err := readSyslogFrame(r)
+		if msg != "" && !s.emit(ctx, msg, out) {
+			return
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				log.Printf("syslog: dropping connection from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+// readSyslogFrame reads one message using octet counting when the frame
+// starts with a digit, and newline framing otherwise.
+func readSyslogFrame(r *bufio.Reader) (string, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return "", err
+	}
+
+	if first[0] >= '0' && first[0] <= '9' {
+		prefix, err := r.ReadString(' ')
+		if err != nil {
+			return "", err
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+		if err != nil || n <= 0 || n > maxSyslogMessage {
+			return "", fmt.Errorf("invalid octet count %q", prefix)
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	}
+
+	line, err := r.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// emit parses msg and sends it on. It returns false once ctx is done.
+func (s *SyslogSource) emit(ctx context.Context, msg string, out chan<- Event) bool {
+	entry, err := ParseSyslog(msg)
+	if err != nil {
+		// Not syslog after all; keep the line rather than lose it.
+		entry = ParseLine(msg)
+		entry.Source = "syslog"
+	}
+
+	select {
+	case out <- Event{Entry: &entry}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
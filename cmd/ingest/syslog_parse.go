@@ -0,0 +1,187 @@
+package ingest
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+// syslogSeverities maps syslog severities 0 (emergency) to 7 (debug) onto
+// Jotl levels.
+var syslogSeverities = [8]flags.LogLevel{
+	flags.Error, // emerg
+	flags.Error, // alert
+	flags.Error, // crit
+	flags.Error, // err
+	flags.Warn,  // warning
+	flags.Info,  // notice
+	flags.Info,  // info
+	flags.Debug, // debug
+}
+
+// ParseSyslog parses an RFC 5424 or RFC 3164 message into an entry. The
+// hostname, app-name and other header fields are kept as fields.
+func ParseSyslog(msg string) (store.Entry, error) {
+	msg = strings.TrimRight(msg, "\r\n\x00")
+
+	pri, rest, err := parsePriority(msg)
+	if err != nil {
+		return store.Entry{}, err
+	}
+
+	entry := store.Entry{
+		Level:  syslogSeverities[pri%8],
+		Source: "syslog",
+		Fields: map[string]any{
+			"facility": pri / 8,
+			"severity": pri % 8,
+		},
+	}
+
+	if strings.HasPrefix(rest, "1 ") {
+		parse5424(rest[2:], &entry)
+	} else {
+		parse3164(rest, &entry)
+	}
+	if app, ok := entry.Fields["app_name"].(string); ok {
+		entry.Source = "syslog:" + app
+	}
+	return entry, nil
+}
+
+func parsePriority(msg string) (int, string, error) {
+	if !strings.HasPrefix(msg, "<") {
+		return 0, "", errors.New("syslog message has no priority")
+	}
+	end := strings.IndexByte(msg, '>')
+	if end < 2 || end > 4 {
+		return 0, "", errors.New("syslog message has an invalid priority")
+	}
+	pri, err := strconv.Atoi(msg[1:end])
+	if err != nil || pri > 191 {
+		return 0, "", errors.New("syslog message has an invalid priority")
+	}
+	return pri, msg[end+1:], nil
+}
+
+// parse5424 handles "TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD [MSG]".
+func parse5424(s string, entry *store.Entry) {
+	header := make([]string, 5)
+	for i := range header {
+		header[i], s = nextToken(s)
+	}
+
+	if ts, err := time.Parse(time.RFC3339Nano, header[0]); err == nil {
+		entry.Timestamp = ts
+	}
+	setSyslogField(entry, "hostname", header[1])
+	setSyslogField(entry, "app_name", header[2])
+	setSyslogField(entry, "proc_id", header[3])
+	setSyslogField(entry, "msg_id", header[4])
+
+	sd, s := parseStructuredData(s)
+	if len(sd) > 0 {
+		entry.Fields["structured_data"] = sd
+	}
+
+	entry.Message = strings.TrimPrefix(strings.TrimPrefix(s, " "), "\ufeff")
+}
+
+// parseStructuredData reads "-" or a run of "[id key="value" ...]" elements.
+func parseStructuredData(s string) (map[string]any, string) {
+	if strings.HasPrefix(s, "-") {
+		return nil, s[1:]
+	}
+
+	sd := map[string]any{}
+	for strings.HasPrefix(s, "[") {
+		s = s[1:]
+		var id string
+		id, s = cutAny(s, " ]")
+		params := map[string]any{}
+
+		for strings.HasPrefix(s, " ") {
+			s = strings.TrimLeft(s, " ")
+			var name string
+			name, s = cutAny(s, "=")
+			if !strings.HasPrefix(s, `="`) {
+				break
+			}
+			s = s[2:]
+
+			var value strings.Builder
+			for len(s) > 0 && s[0] != '"' {
+				if s[0] == '\\' && len(s) > 1 {
+					s = s[1:]
+				}
+				value.WriteByte(s[0])
+				s = s[1:]
+			}
+			s = strings.TrimPrefix(s, `"`)
+			params[name] = value.String()
+		}
+
+		s = strings.TrimPrefix(s, "]")
+		sd[id] = params
+	}
+	return sd, s
+}
+
+// parse3164 handles the BSD format "Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG".
+// Senders vary a lot, so every part is optional.
+func parse3164(s string, entry *store.Entry) {
+	if len(s) >= len(time.Stamp) {
+		if ts, err := time.ParseInLocation(time.Stamp, s[:len(time.Stamp)], time.Local); err == nil {
+			now := time.Now()
+			ts = ts.AddDate(now.Year(), 0, 0)
+			// A December message received in January belongs to last year.
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			entry.Timestamp = ts
+			s = strings.TrimPrefix(s[len(time.Stamp):], " ")
+
+			// The hostname is present unless the next token is already the tag.
+			if token, rest := nextToken(s); token != "" && !strings.HasSuffix(token, ":") && !strings.Contains(token, "[") {
+				setSyslogField(entry, "hostname", token)
+				s = rest
+			}
+		}
+	}
+
+	if tag, rest, ok := strings.Cut(s, ": "); ok && !strings.ContainsAny(tag, " ") {
+		if name, pid, ok := strings.Cut(tag, "["); ok {
+			setSyslogField(entry, "app_name", name)
+			setSyslogField(entry, "proc_id", strings.TrimSuffix(pid, "]"))
+		} else {
+			setSyslogField(entry, "app_name", tag)
+		}
+		s = rest
+	}
+
+	entry.Message = s
+}
+
+func setSyslogField(entry *store.Entry, key, value string) {
+	if value != "" && value != "-" {
+		entry.Fields[key] = value
+	}
+}
+
+// nextToken returns the text up to the next space and what follows it.
+func nextToken(s string) (string, string) {
+	token, rest, _ := strings.Cut(s, " ")
+	return token, rest
+}
+
+// cutAny splits s before the first byte found in chars.
+func cutAny(s, chars string) (string, string) {
+	if i := strings.IndexAny(s, chars); i >= 0 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
@@ -0,0 +1,359 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+func TestParseSyslog(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		// time is the expected timestamp in layout, or empty for none.
+		time, layout string
+		want         store.Entry
+	}{
+		{
+			name:   "RFC 5424",
+			msg:    `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Application"][origin ip="192.0.2.1"] An application event`,
+			time:   "2003-10-11T22:14:15.003Z",
+			layout: time.RFC3339Nano,
+			want: store.Entry{
+				Level:   flags.Info,
+				Message: "An application event",
+				Source:  "syslog:evntslog",
+				Fields: map[string]any{
+					"facility": 20, "severity": 5,
+					"hostname": "mymachine.example.com", "app_name": "evntslog", "proc_id": "1234", "msg_id": "ID47",
+					"structured_data": map[string]any{
+						"exampleSDID@32473": map[string]any{"iut": "3", "eventSource": "Application"},
+						"origin":            map[string]any{"ip": "192.0.2.1"},
+					},
+				},
+			},
+		},
+		{
+			name:   "RFC 5424 with BOM and no structured data",
+			msg:    "<34>1 2003-10-11T22:14:15.003+02:00 mymachine su - ID47 - \ufeff'su root' failed\r\n",
+			time:   "2003-10-11T22:14:15.003+02:00",
+			layout: time.RFC3339Nano,
+			want: store.Entry{
+				Level:   flags.Error,
+				Message: "'su root' failed",
+				Source:  "syslog:su",
+				Fields:  map[string]any{"facility": 4, "severity": 2, "hostname": "mymachine", "app_name": "su", "msg_id": "ID47"},
+			},
+		},
+		{
+			name: "RFC 5424 escaped parameter",
+			msg:  `<14>1 - - - - - [id k="a\"b\]c\\d" empty=""]`,
+			want: store.Entry{
+				Level:  flags.Info,
+				Source: "syslog",
+				Fields: map[string]any{
+					"facility": 1, "severity": 6,
+					"structured_data": map[string]any{"id": map[string]any{"k": `a"b]c\d`, "empty": ""}},
+				},
+			},
+		},
+		{
+			name: "RFC 5424 nil values",
+			msg:  "<15>1 - - - - - - just the message",
+			want: store.Entry{
+				Level:   flags.Debug,
+				Message: "just the message",
+				Source:  "syslog",
+				Fields:  map[string]any{"facility": 1, "severity": 7},
+			},
+		},
+		{
+			name:   "RFC 3164",
+			msg:    "<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick",
+			time:   "Oct 11 22:14:15",
+			layout: time.Stamp,
+			want: store.Entry{
+				Level:   flags.Error,
+				Message: "'su root' failed for lonvick",
+				Source:  "syslog:su",
+				Fields:  map[string]any{"facility": 4, "severity": 2, "hostname": "mymachine", "app_name": "su"},
+			},
+		},
+		{
+			name:   "RFC 3164 with pid and a padded day",
+			msg:    "<38>Feb  5 17:32:18 web-1 sshd[4321]: Accepted publickey: for root",
+			time:   "Feb  5 17:32:18",
+			layout: time.Stamp,
+			want: store.Entry{
+				Level:   flags.Info,
+				Message: "Accepted publickey: for root",
+				Source:  "syslog:sshd",
+				Fields:  map[string]any{"facility": 4, "severity": 6, "hostname": "web-1", "app_name": "sshd", "proc_id": "4321"},
+			},
+		},
+		{
+			name:   "RFC 3164 without hostname",
+			msg:    "<12>Feb  5 17:32:18 cron[7]: job done",
+			time:   "Feb  5 17:32:18",
+			layout: time.Stamp,
+			want: store.Entry{
+				Level:   flags.Warn,
+				Message: "job done",
+				Source:  "syslog:cron",
+				Fields:  map[string]any{"facility": 1, "severity": 4, "app_name": "cron", "proc_id": "7"},
+			},
+		},
+		{
+			name: "tag without timestamp",
+			msg:  "<11>myapp: disk full",
+			want: store.Entry{
+				Level:   flags.Error,
+				Message: "disk full",
+				Source:  "syslog:myapp",
+				Fields:  map[string]any{"facility": 1, "severity": 3, "app_name": "myapp"},
+			},
+		},
+		{
+			name: "priority only",
+			msg:  "<0>kernel panic - not syncing",
+			want: store.Entry{
+				Level:   flags.Error,
+				Message: "kernel panic - not syncing",
+				Source:  "syslog",
+				Fields:  map[string]any{"facility": 0, "severity": 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSyslog(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			switch {
+			case tt.time == "" && !got.Timestamp.IsZero():
+				t.Errorf("timestamp = %s, want none", got.Timestamp)
+			case tt.time != "" && got.Timestamp.Format(tt.layout) != tt.time:
+				t.Errorf("timestamp = %s, want %s", got.Timestamp.Format(tt.layout), tt.time)
+			}
+			got.Timestamp = time.Time{}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSyslog =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSyslogYear(t *testing.T) {
+	// RFC 3164 timestamps have no year: messages are placed in the current
+	// one unless that puts them more than a day in the future.
+	now := time.Now()
+	for _, d := range []time.Duration{-time.Hour, 12 * time.Hour, 48 * time.Hour} {
+		ts := now.Add(d)
+		if ts.Year() != now.Year() {
+			continue // Too close to New Year to tell
+		}
+		got, err := ParseSyslog("<13>" + ts.Format(time.Stamp) + " host app: hi")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := now.Year()
+		if d > 24*time.Hour {
+			want--
+		}
+		if got.Timestamp.Year() != want {
+			t.Errorf("message from %s: year = %d, want %d", ts.Format(time.Stamp), got.Timestamp.Year(), want)
+		}
+	}
+}
+
+func TestSyslogPriority(t *testing.T) {
+	want := []flags.LogLevel{flags.Error, flags.Error, flags.Error, flags.Error, flags.Warn, flags.Info, flags.Info, flags.Debug}
+	for severity, level := range want {
+		for _, facility := range []int{0, 1, 23} {
+			e, err := ParseSyslog(fmt.Sprintf("<%d>msg", facility*8+severity))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.Level != level || e.Fields["facility"] != facility || e.Fields["severity"] != severity {
+				t.Errorf("facility %d severity %d: level %s, fields %v, want level %s", facility, severity, e.Level, e.Fields, level)
+			}
+		}
+	}
+
+	for _, msg := range []string{"no priority", "<>x", "<abc>x", "<192>x", "<1234>x", "<13"} {
+		if _, err := ParseSyslog(msg); err == nil {
+			t.Errorf("ParseSyslog(%q) succeeded, want an error", msg)
+		}
+	}
+}
+
+// octets frames msg with RFC 6587 octet counting.
+func octets(msg string) string {
+	return fmt.Sprintf("%d %s", len(msg), msg)
+}
+
+func TestReadSyslogFrame(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []string
+		err    string // expected final error, other than io.EOF
+	}{
+		{
+			name:   "newline framing",
+			stream: "<13>one\n<13>two\r\n<13>unterminated",
+			want:   []string{"<13>one", "<13>two", "<13>unterminated"},
+		},
+		{
+			name:   "octet counting",
+			stream: octets("<13>multi\nline") + octets("<13>next"),
+			want:   []string{"<13>multi\nline", "<13>next"},
+		},
+		{
+			name:   "mixed",
+			stream: octets("<13>a") + "<13>b\n" + octets("<13>c 12 d"),
+			want:   []string{"<13>a", "<13>b", "<13>c 12 d"},
+		},
+		{
+			name:   "zero count",
+			stream: "0 <13>x",
+			err:    "invalid octet count",
+		},
+		{
+			name:   "count too large",
+			stream: "99999999 <13>x",
+			err:    "invalid octet count",
+		},
+		{
+			name:   "count not a number",
+			stream: "12a <13>x",
+			err:    "invalid octet count",
+		},
+		{
+			name:   "truncated frame",
+			stream: octets("<13>hello")[:8],
+			err:    io.ErrUnexpectedEOF.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.stream))
+			var got []string
+			var err error
+			for err == nil {
+				var msg string
+				msg, err = readSyslogFrame(r)
+				if msg != "" {
+					got = append(got, msg)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("frames = %q, want %q", got, tt.want)
+			}
+			switch {
+			case tt.err == "" && !errors.Is(err, io.EOF):
+				t.Errorf("error = %v, want EOF", err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSyslogSource(t *testing.T) {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out := make(chan Event)
+	done := make(chan error, 1)
+	go func() { done <- (&SyslogSource{}).serve(ctx, udp, tcp, out) }()
+
+	conn, err := net.Dial("tcp", tcp.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, octets("<11>tcp: first")+"<14>tcp: second\n")
+
+	packet, err := net.Dial("udp", udp.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer packet.Close()
+
+	var got []string
+	for len(got) < 3 {
+		if len(got) == 2 {
+			packet.Write([]byte("not syslog"))
+		}
+		select {
+		case ev := <-out:
+			got = append(got, ev.Entry.Source+" "+ev.Entry.Message)
+		case <-ctx.Done():
+			t.Fatalf("timed out after %q", got)
+		}
+	}
+	if want := []string{"syslog:tcp first", "syslog:tcp second", "syslog not syslog"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("serve = %v, want %v", err, context.Canceled)
+	}
+}
+
+// failingListener fails every Accept.
+type failingListener struct {
+	net.Listener
+}
+
+func (failingListener) Accept() (net.Conn, error) {
+	return nil, errors.New("too many open files")
+}
+
+func TestSyslogSourceAcceptError(t *testing.T) {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- (&SyslogSource{}).serve(context.Background(), udp, failingListener{tcp}, make(chan Event))
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "too many open files") {
+			t.Errorf("serve = %v, want the Accept error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve kept running after Accept failed")
+	}
+}
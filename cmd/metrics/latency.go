@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+// latencyKeys are field names access loggers commonly record the request
+// duration under. Plain numbers are taken as seconds, as zap and nginx log
+// them, unless the key names a unit.
+var latencyKeys = []string{
+	"duration", "latency", "elapsed", "response_time", "responseTime", "took", "dur",
+	"duration_ms", "latency_ms", "elapsed_ms", "response_time_ms",
+	"duration_us", "latency_us", "duration_ns", "latency_ns",
+	"duration_s", "duration_seconds", "request_time", "upstream_response_time",
+}
+
+// textLatency matches a duration in a plain-text access log line, such as
+// Gin's "| 200 |   1.234ms |" or Rails' "Completed 200 OK in 12ms".
+var textLatency = regexp.MustCompile(`(?:^|[\s|(])(\d+(?:\.\d+)?)\s?(ns|µs|us|ms|s)(?:$|[\s|)])`)
+
+// Latency extracts the request duration from an access log entry.
+func Latency(e store.Entry) (time.Duration, bool) {
+	for _, fields := range []map[string]any{e.Fields, nested(e.Fields, "http"), nested(e.Fields, "req")} {
+		for _, key := range latencyKeys {
+			if v, ok := fields[key]; ok {
+				if d, ok := durationValue(key, v); ok {
+					return d, true
+				}
+			}
+		}
+	}
+
+	// Only trust durations in free text on lines that look like requests.
+	if e.StatusCode == 0 {
+		return 0, false
+	}
+	m := textLatency.FindStringSubmatch(e.Message)
+	if m == nil {
+		return 0, false
+	}
+	d, err := time.ParseDuration(strings.Replace(m[1]+m[2], "us", "µs", 1))
+	return d, err == nil
+}
+
+func nested(fields map[string]any, key string) map[string]any {
+	m, _ := fields[key].(map[string]any)
+	return m
+}
+
+// durationValue converts a field value to a duration. Strings may carry a
+// unit ("1.5ms"); numbers are in seconds unless key ends in a unit suffix
+// such as "_ms".
+func durationValue(key string, v any) (time.Duration, bool) {
+	var n float64
+	switch x := v.(type) {
+	case float64:
+		n = x
+	case int:
+		n = float64(x)
+	case int64:
+		n = float64(x)
+	case string:
+		if d, err := time.ParseDuration(x); err == nil {
+			return d, true
+		}
+		f, err := strconv.ParseFloat(x, 64)
+		if err != nil {
+			return 0, false
+		}
+		n = f
+	default:
+		return 0, false
+	}
+	if n < 0 {
+		return 0, false
+	}
+
+	unit := time.Second
+	switch {
+	case strings.HasSuffix(key, "_ms"):
+		unit = time.Millisecond
+	case strings.HasSuffix(key, "_us"):
+		unit = time.Microsecond
+	case strings.HasSuffix(key, "_ns"):
+		unit = time.Nanosecond
+	}
+	return time.Duration(n * float64(unit)), true
+}
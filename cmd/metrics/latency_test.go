@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+func TestLatency(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry store.Entry
+		want  time.Duration // 0 for none
+	}{
+		{
+			name:  "zap float seconds",
+			entry: store.Entry{Fields: map[string]any{"duration": 0.25}},
+			want:  250 * time.Millisecond,
+		},
+		{
+			name:  "milliseconds key",
+			entry: store.Entry{Fields: map[string]any{"duration_ms": float64(250)}},
+			want:  250 * time.Millisecond,
+		},
+		{
+			name:  "milliseconds key as a string",
+			entry: store.Entry{Fields: map[string]any{"latency_ms": "12.5"}},
+			want:  12500 * time.Microsecond,
+		},
+		{
+			name:  "microseconds",
+			entry: store.Entry{Fields: map[string]any{"duration_us": int64(1500)}},
+			want:  1500 * time.Microsecond,
+		},
+		{
+			name:  "nanoseconds",
+			entry: store.Entry{Fields: map[string]any{"latency_ns": 42}},
+			want:  42 * time.Nanosecond,
+		},
+		{
+			name:  "nginx request time",
+			entry: store.Entry{Fields: map[string]any{"request_time": "0.003"}},
+			want:  3 * time.Millisecond,
+		},
+		{
+			name:  "string with a unit",
+			entry: store.Entry{Fields: map[string]any{"took": "1.5ms"}},
+			want:  1500 * time.Microsecond,
+		},
+		{
+			name:  "nested under http",
+			entry: store.Entry{Fields: map[string]any{"http": map[string]any{"latency": 2.0}}},
+			want:  2 * time.Second,
+		},
+		{
+			name:  "top level wins",
+			entry: store.Entry{Fields: map[string]any{"elapsed": 1.0, "req": map[string]any{"elapsed": 3.0}}},
+			want:  time.Second,
+		},
+		{
+			name:  "invalid values are skipped",
+			entry: store.Entry{Fields: map[string]any{"duration": "soon", "latency": -1.0, "elapsed": true, "took": 0.5}},
+			want:  500 * time.Millisecond,
+		},
+		{
+			name:  "gin text",
+			entry: store.Entry{StatusCode: 200, Message: "[GIN] 2026/03/01 - 10:00:00 | 200 |   1.234ms |       127.0.0.1 | GET      \"/\""},
+			want:  1234 * time.Microsecond,
+		},
+		{
+			name:  "rails text",
+			entry: store.Entry{StatusCode: 200, Message: "Completed 200 OK in 12ms (Views: 5.1ms | ActiveRecord: 2.0ms)"},
+			want:  12 * time.Millisecond,
+		},
+		{
+			name:  "text in microseconds",
+			entry: store.Entry{StatusCode: 204, Message: "GET /health 204 (850us)"},
+			want:  850 * time.Microsecond,
+		},
+		{
+			name:  "text without a status",
+			entry: store.Entry{Message: "retrying in 5s"},
+		},
+		{
+			name:  "number inside a word",
+			entry: store.Entry{StatusCode: 200, Message: "GET /v2s/items 200"},
+		},
+		{
+			name:  "no duration",
+			entry: store.Entry{Fields: map[string]any{"path": "/"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Latency(tt.entry)
+			if ok != (tt.want != 0) || got != tt.want {
+				t.Errorf("Latency = %s, %t, want %s", got, ok, tt.want)
+			}
+		})
+	}
+}
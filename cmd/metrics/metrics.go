@@ -0,0 +1,195 @@
+// Package metrics derives Prometheus metrics from captured log entries and
+// serves them in the text exposition format.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+// Path is where metrics are served.
+const Path = "/metrics"
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram; the same defaults the Prometheus client libraries use.
+var latencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// histogram is a cumulative Prometheus histogram.
+type histogram struct {
+	counts []uint64 // One per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(seconds float64) {
+	for i, le := range latencyBuckets {
+		if seconds <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// Collector counts entries as they are stored. It is safe for concurrent use.
+type Collector struct {
+	mu       sync.Mutex
+	levels   map[string]uint64
+	sources  map[string]uint64
+	statuses map[string]uint64
+	latency  map[string]*histogram // By source
+}
+
+// New returns an empty collector.
+func New() *Collector {
+	return &Collector{
+		levels:   map[string]uint64{},
+		sources:  map[string]uint64{},
+		statuses: map[string]uint64{},
+		latency:  map[string]*histogram{},
+	}
+}
+
+// Observe counts a batch of entries. It is meant to be used as
+// ingest.Pipeline.OnInsert.
+func (c *Collector) Observe(entries []store.Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range entries {
+		c.levels[string(e.Level)]++
+		c.sources[e.Source]++
+		if e.StatusCode >= 100 && e.StatusCode < 600 {
+			c.statuses[strconv.Itoa(e.StatusCode/100)+"xx"]++
+		}
+		if d, ok := Latency(e); ok {
+			h := c.latency[e.Source]
+			if h == nil {
+				h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+				c.latency[e.Source] = h
+			}
+			h.observe(d.Seconds())
+		}
+	}
+}
+
+// Follow observes entries as they are written to db by another process,
+// starting with those stored after Follow is called, until ctx is cancelled.
+func (c *Collector) Follow(ctx context.Context, db store.Store, interval time.Duration) error {
+	var after int64
+	latest, err := db.Query(ctx, store.Filter{Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(latest) > 0 {
+		after = latest[0].ID
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		entries, err := db.Query(ctx, store.Filter{AfterID: after, Oldest: true, Limit: 5000})
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			c.Observe(entries)
+			after = entries[len(entries)-1].ID
+		}
+	}
+}
+
+// Serve serves the metrics on addr until ctx is cancelled.
+func (c *Collector) Serve(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET "+Path, c)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var b strings.Builder
+	writeCounter(&b, "jotl_log_lines_total", "Log lines stored, by level.", "level", c.levels)
+	writeCounter(&b, "jotl_log_lines_by_source_total", "Log lines stored, by source.", "source", c.sources)
+	writeCounter(&b, "jotl_http_responses_total", "Log lines carrying an HTTP status code, by status class.", "class", c.statuses)
+
+	b.WriteString("# HELP jotl_request_duration_seconds Request latency extracted from access logs.\n")
+	b.WriteString("# TYPE jotl_request_duration_seconds histogram\n")
+	for _, source := range sortedKeys(c.latency) {
+		h := c.latency[source]
+		label := `source="` + escapeLabel(source) + `"`
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&b, "jotl_request_duration_seconds_bucket{%s,le=%q} %d\n", label, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "jotl_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", label, h.count)
+		fmt.Fprintf(&b, "jotl_request_duration_seconds_sum{%s} %s\n", label, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "jotl_request_duration_seconds_count{%s} %d\n", label, h.count)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func writeCounter(b *strings.Builder, name, help, label string, values map[string]uint64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, k := range sortedKeys(values) {
+		fmt.Fprintf(b, "%s{%s=\"%s\"} %d\n", name, label, escapeLabel(k), values[k])
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapeLabel escapes a label value as the exposition format requires.
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
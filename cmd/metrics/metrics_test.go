@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+func TestWriteTo(t *testing.T) {
+	c := New()
+	c.Observe([]store.Entry{
+		{Level: flags.Info, Source: "file:access.log", StatusCode: 200, Fields: map[string]any{"duration_ms": 3}},
+		{Level: flags.Info, Source: "file:access.log", StatusCode: 201, Fields: map[string]any{"duration": 0.2}},
+		{Level: flags.Error, Source: "file:access.log", StatusCode: 503, Fields: map[string]any{"duration": 30.0}},
+		{Level: flags.Warn, Source: `docker:"web"` + "\n", StatusCode: 404},
+		{Level: flags.Debug, Source: "slog", StatusCode: 42},
+	})
+
+	var b strings.Builder
+	if _, err := c.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP jotl_log_lines_total Log lines stored, by level.
+# TYPE jotl_log_lines_total counter
+jotl_log_lines_total{level="debug"} 1
+jotl_log_lines_total{level="error"} 1
+jotl_log_lines_total{level="info"} 2
+jotl_log_lines_total{level="warn"} 1
+# HELP jotl_log_lines_by_source_total Log lines stored, by source.
+# TYPE jotl_log_lines_by_source_total counter
+jotl_log_lines_by_source_total{source="docker:\"web\"\n"} 1
+jotl_log_lines_by_source_total{source="file:access.log"} 3
+jotl_log_lines_by_source_total{source="slog"} 1
+# HELP jotl_http_responses_total Log lines carrying an HTTP status code, by status class.
+# TYPE jotl_http_responses_total counter
+jotl_http_responses_total{class="2xx"} 2
+jotl_http_responses_total{class="4xx"} 1
+jotl_http_responses_total{class="5xx"} 1
+# HELP jotl_request_duration_seconds Request latency extracted from access logs.
+# TYPE jotl_request_duration_seconds histogram
+jotl_request_duration_seconds_bucket{source="file:access.log",le="0.005"} 1
+jotl_request_duration_seconds_bucket{source="file:access.log",le="0.01"} 1
+jotl_request_duration_seconds_bucket{source="file:access.log",le="0.025"} 1
+jotl_request_duration_seconds_bucket{source="file:access.log",le="0.05"} 1
+jotl_request_duration_seconds_bucket{source="file:access.log",le="0.1"} 1
+jotl_request_duration_seconds_bucket{source="file:access.log",le="0.25"} 2
+jotl_request_duration_seconds_bucket{source="file:access.log",le="0.5"} 2
+jotl_request_duration_seconds_bucket{source="file:access.log",le="1"} 2
+jotl_request_duration_seconds_bucket{source="file:access.log",le="2.5"} 2
+jotl_request_duration_seconds_bucket{source="file:access.log",le="5"} 2
+jotl_request_duration_seconds_bucket{source="file:access.log",le="10"} 2
+jotl_request_duration_seconds_bucket{source="file:access.log",le="+Inf"} 3
+jotl_request_duration_seconds_sum{source="file:access.log"} 30.203
+jotl_request_duration_seconds_count{source="file:access.log"} 3
+`
+	if b.String() != want {
+		t.Errorf("WriteTo wrote\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestWriteToEmpty(t *testing.T) {
+	var b strings.Builder
+	New().WriteTo(&b)
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		if !strings.HasPrefix(line, "# ") {
+			t.Errorf("empty collector wrote sample %q", line)
+		}
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	c := New()
+	c.Observe([]store.Entry{{Level: flags.Info, Source: "slog"}})
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `jotl_log_lines_total{level="info"} 1`) {
+		t.Errorf("body = %s", rec.Body)
+	}
+}
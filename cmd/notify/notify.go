@@ -0,0 +1,210 @@
+// Package notify delivers alerts to webhooks. Requests are written to an
+// outbox table first and retried with exponential backoff, so notifications
+// survive restarts and flaky endpoints.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/alert"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+const (
+	// MaxAttempts is how often a notification is tried before giving up.
+	MaxAttempts = 10
+
+	initialBackoff = 5 * time.Second
+	maxBackoff     = time.Hour
+	pollInterval   = 5 * time.Second
+	requestTimeout = 10 * time.Second
+	batchSize      = 50
+)
+
+// Outbox is the part of store.Store the sender uses.
+type Outbox interface {
+	Enqueue(ctx context.Context, notifications []store.Notification) error
+	DueNotifications(ctx context.Context, limit int) ([]store.Notification, error)
+	UpdateNotification(ctx context.Context, n store.Notification) error
+}
+
+// Sender is an alert.Notifier that queues alerts for their webhooks and
+// delivers them in the background.
+type Sender struct {
+	Outbox Outbox
+	Client *http.Client
+
+	wake chan struct{}
+}
+
+// NewSender returns a sender backed by outbox.
+func NewSender(outbox Outbox) *Sender {
+	return &Sender{
+		Outbox: outbox,
+		Client: &http.Client{Timeout: requestTimeout},
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Notify renders a for each of its webhooks and adds the requests to the
+// outbox.
+func (s *Sender) Notify(ctx context.Context, a alert.Alert) error {
+	if len(a.Webhooks) == 0 {
+		return nil
+	}
+
+	notifications := make([]store.Notification, 0, len(a.Webhooks))
+	for _, w := range a.Webhooks {
+		payload, err := Render(w, a)
+		if err != nil {
+			return fmt.Errorf("webhook %s: %w", Host(w.URL), err)
+		}
+		notifications = append(notifications, store.Notification{URL: w.URL, Headers: w.Headers, Payload: payload})
+	}
+	if err := s.Outbox.Enqueue(ctx, notifications); err != nil {
+		return err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run delivers due notifications until ctx is cancelled, including any left
+// over from a previous run.
+func (s *Sender) Run(ctx context.Context) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Deliver(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("notify: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// Deliver makes one attempt at every due notification. It returns how many
+// were delivered and the errors of those that were not.
+func (s *Sender) Deliver(ctx context.Context) (int, error) {
+	due, err := s.Outbox.DueNotifications(ctx, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	var errs []error
+	for _, n := range due {
+		if ctx.Err() != nil {
+			break
+		}
+
+		n.Attempts++
+		retry, err := s.post(ctx, n)
+		switch {
+		case err == nil:
+			n.Status = store.NotificationDelivered
+			n.LastError = ""
+			delivered++
+		case !retry || n.Attempts >= MaxAttempts:
+			n.Status = store.NotificationFailed
+			n.LastError = err.Error()
+			errs = append(errs, fmt.Errorf("giving up on %s after %d attempt(s): %w", Host(n.URL), n.Attempts, err))
+		default:
+			n.LastError = err.Error()
+			n.NextAttemptAt = time.Now().Add(Backoff(n.Attempts))
+			errs = append(errs, fmt.Errorf("%s (attempt %d, retrying at %s): %w", Host(n.URL), n.Attempts, n.NextAttemptAt.Format(time.TimeOnly), err))
+		}
+
+		// Record the outcome even if ctx was cancelled mid-request.
+		if err := s.Outbox.UpdateNotification(context.Background(), n); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// Test sends a to each of its webhooks once, bypassing the outbox, so a
+// test neither flushes pending notifications nor leaves a failed sample to
+// be retried. It returns how many were delivered and the errors of the
+// rest.
+func (s *Sender) Test(ctx context.Context, a alert.Alert) (int, error) {
+	delivered := 0
+	var errs []error
+	for _, w := range a.Webhooks {
+		payload, err := Render(w, a)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", Host(w.URL), err))
+			continue
+		}
+		if _, err := s.post(ctx, store.Notification{URL: w.URL, Headers: w.Headers, Payload: payload}); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", Host(w.URL), err))
+			continue
+		}
+		delivered++
+	}
+	return delivered, errors.Join(errs...)
+}
+
+// post sends one notification. retry reports whether a failure is worth
+// trying again.
+func (s *Sender) post(ctx context.Context, n store.Notification) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(n.Payload))
+	if err != nil {
+		return false, errors.New("invalid webhook URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "jotl")
+	for k, v := range n.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		// The *url.Error would repeat the URL, and with it the webhook's
+		// secret.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = errors.New(resp.Status)
+	if body = bytes.TrimSpace(body); len(body) > 0 {
+		err = fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	// Client errors won't fix themselves, except for timeouts and rate limits.
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retry, err
+}
+
+// Backoff is the delay before the next attempt after the given number of
+// failed attempts: 5s, 10s, 20s, ... up to an hour.
+func Backoff(attempts int) time.Duration {
+	d := initialBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
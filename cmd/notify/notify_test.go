@@ -0,0 +1,257 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ebarthur/jotl/cmd/alert"
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+// webhook is an httptest stand-in for a webhook endpoint that answers with
+// status and records the requests it gets.
+type webhook struct {
+	*httptest.Server
+	status int
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhook(t *testing.T, status int) *webhook {
+	t.Helper()
+	w := &webhook{status: status}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.mu.Lock()
+		w.requests = append(w.requests, r)
+		w.bodies = append(w.bodies, body)
+		w.mu.Unlock()
+		rw.WriteHeader(w.status)
+	}))
+	t.Cleanup(w.Close)
+	return w
+}
+
+func (w *webhook) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.requests)
+}
+
+// outbox keeps notifications in memory.
+type outbox struct {
+	notifications []store.Notification
+}
+
+func (o *outbox) Enqueue(ctx context.Context, notifications []store.Notification) error {
+	for _, n := range notifications {
+		n.ID = int64(len(o.notifications) + 1)
+		n.Status = store.NotificationPending
+		n.NextAttemptAt = time.Now()
+		o.notifications = append(o.notifications, n)
+	}
+	return nil
+}
+
+func (o *outbox) DueNotifications(ctx context.Context, limit int) ([]store.Notification, error) {
+	var due []store.Notification
+	for _, n := range o.notifications {
+		if n.Status == store.NotificationPending && !n.NextAttemptAt.After(time.Now()) && len(due) < limit {
+			due = append(due, n)
+		}
+	}
+	return due, nil
+}
+
+func (o *outbox) UpdateNotification(ctx context.Context, n store.Notification) error {
+	o.notifications[n.ID-1] = n
+	return nil
+}
+
+func sampleAlert(webhooks ...config.Webhook) alert.Alert {
+	return alert.Alert{
+		Rule:     "errors",
+		Type:     "rate",
+		Title:    "Too many errors",
+		Message:  "12 errors in 1m",
+		Count:    12,
+		Time:     time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		Webhooks: webhooks,
+	}
+}
+
+func TestSenderTest(t *testing.T) {
+	ok := newWebhook(t, http.StatusOK)
+	broken := newWebhook(t, http.StatusInternalServerError)
+
+	pending := &outbox{}
+	pending.Enqueue(context.Background(), []store.Notification{{URL: ok.URL + "/real", Payload: []byte(`{}`)}})
+
+	s := NewSender(pending)
+	a := sampleAlert(
+		config.Webhook{URL: ok.URL, Headers: map[string]string{"Authorization": "Bearer secret"}},
+		config.Webhook{URL: broken.URL + "/services/T0/s3cret"},
+	)
+	delivered, err := s.Test(context.Background(), a)
+	if delivered != 1 {
+		t.Errorf("delivered = %d, want 1", delivered)
+	}
+	if err == nil {
+		t.Error("want an error for the failing webhook")
+	} else if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("error shows the webhook's secret: %v", err)
+	}
+
+	if ok.count() != 1 {
+		t.Fatalf("webhook got %d requests, want 1 (the outbox must not be flushed)", ok.count())
+	}
+	req := ok.requests[0]
+	if got := req.Header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	var body alert.Alert
+	if err := json.Unmarshal(ok.bodies[0], &body); err != nil {
+		t.Fatalf("payload is not an alert: %v", err)
+	}
+	if body.Rule != "errors" || body.Count != 12 {
+		t.Errorf("payload = %+v", body)
+	}
+
+	if broken.count() != 1 {
+		t.Errorf("failing webhook got %d requests, want 1", broken.count())
+	}
+	if n := pending.notifications[0]; n.Status != store.NotificationPending || n.Attempts != 0 {
+		t.Errorf("pending notification was touched: %+v", n)
+	}
+	if len(pending.notifications) != 1 {
+		t.Errorf("outbox has %d notifications, want the 1 it had", len(pending.notifications))
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantStatus   string
+		wantRetry    bool
+		wantDelivery int
+	}{
+		{"success", http.StatusNoContent, store.NotificationDelivered, false, 1},
+		{"server error is retried", http.StatusBadGateway, store.NotificationPending, true, 0},
+		{"rate limit is retried", http.StatusTooManyRequests, store.NotificationPending, true, 0},
+		{"client error gives up", http.StatusNotFound, store.NotificationFailed, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWebhook(t, tt.status)
+			o := &outbox{}
+			s := NewSender(o)
+			if err := s.Notify(context.Background(), sampleAlert(config.Webhook{URL: w.URL})); err != nil {
+				t.Fatal(err)
+			}
+
+			delivered, err := s.Deliver(context.Background())
+			if delivered != tt.wantDelivery {
+				t.Errorf("delivered = %d, want %d", delivered, tt.wantDelivery)
+			}
+			if (err != nil) != (tt.wantDelivery == 0) {
+				t.Errorf("err = %v", err)
+			}
+
+			n := o.notifications[0]
+			if n.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", n.Status, tt.wantStatus)
+			}
+			if n.Attempts != 1 {
+				t.Errorf("attempts = %d, want 1", n.Attempts)
+			}
+			if tt.wantRetry && !n.NextAttemptAt.After(time.Now()) {
+				t.Errorf("next attempt at %s, want a backoff", n.NextAttemptAt)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRenderTruncates(t *testing.T) {
+	a := sampleAlert()
+	a.Title = strings.Repeat("é", 500)
+	a.Message = strings.Repeat("x", 10000)
+
+	tests := []struct {
+		format string
+		// fields returns the limited texts of a payload and their limits.
+		fields func(payload map[string]any) map[string]int
+	}{
+		{FormatSlack, func(p map[string]any) map[string]int {
+			blocks := p["blocks"].([]any)
+			return map[string]int{
+				blocks[0].(map[string]any)["text"].(map[string]any)["text"].(string): 150,
+				blocks[1].(map[string]any)["text"].(map[string]any)["text"].(string): 3000,
+			}
+		}},
+		{FormatDiscord, func(p map[string]any) map[string]int {
+			embed := p["embeds"].([]any)[0].(map[string]any)
+			return map[string]int{
+				embed["title"].(string):       256,
+				embed["description"].(string): 4096,
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			body, err := Render(config.Webhook{URL: "https://example.com/hook", Format: tt.format}, a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var payload map[string]any
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Fatal(err)
+			}
+			for text, limit := range tt.fields(payload) {
+				if n := utf8.RuneCountInString(text); n > limit {
+					t.Errorf("text of %d characters exceeds the limit of %d", n, limit)
+				}
+				if !strings.Contains(text, "…") {
+					t.Errorf("truncated text %.20q... has no ellipsis", text)
+				}
+			}
+		})
+	}
+
+	// Short alerts are sent as they are.
+	body, _ := Render(config.Webhook{URL: "https://example.com/hook", Format: FormatDiscord}, sampleAlert())
+	if !strings.Contains(string(body), `"title":"errors: Too many errors"`) {
+		t.Errorf("short alert was changed: %s", body)
+	}
+}
@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/ebarthur/jotl/cmd/alert"
+	"github.com/ebarthur/jotl/cmd/config"
+)
+
+// Payload formats.
+const (
+	FormatJSON    = "json"    // The alert as JSON
+	FormatSlack   = "slack"   // Slack incoming webhook with Block Kit blocks
+	FormatDiscord = "discord" // Discord webhook with an embed
+)
+
+// Length limits of the fields the Slack and Discord payloads fill; longer
+// text is rejected with a 400.
+const (
+	slackHeaderLimit        = 150
+	slackSectionLimit       = 3000
+	discordTitleLimit       = 256
+	discordDescriptionLimit = 4096
+)
+
+// discordColor is the embed accent colour, the same pink as the terminal banner.
+const discordColor = 0xFF5F87
+
+// Format returns the payload format for w, inferring Slack and Discord from
+// their webhook hosts when none is set.
+func Format(w config.Webhook) string {
+	if w.Format != "" {
+		return w.Format
+	}
+	if u, err := url.Parse(w.URL); err == nil {
+		switch {
+		case u.Host == "hooks.slack.com":
+			return FormatSlack
+		case strings.HasSuffix(u.Host, "discord.com") && strings.HasPrefix(u.Path, "/api/webhooks/"):
+			return FormatDiscord
+		}
+	}
+	return FormatJSON
+}
+
+// Host returns the scheme and host of a webhook URL, which is safe to show:
+// Slack and Discord URLs carry their secret token in the path.
+func Host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "webhook"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// Render builds the request body w receives for a.
+func Render(w config.Webhook, a alert.Alert) ([]byte, error) {
+	if w.Template != "" {
+		return renderTemplate(w.Template, a)
+	}
+
+	switch Format(w) {
+	case FormatJSON:
+		return json.Marshal(a)
+	case FormatSlack:
+		return json.Marshal(slackPayload(a))
+	case FormatDiscord:
+		return json.Marshal(discordPayload(a))
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q (want %s, %s or %s)", w.Format, FormatJSON, FormatSlack, FormatDiscord)
+	}
+}
+
+// renderTemplate executes a user template with the alert as data. The json
+// function quotes a value, e.g. {"text": {{json .Message}}}.
+func renderTemplate(text string, a alert.Alert) ([]byte, error) {
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, a); err != nil {
+		return nil, fmt.Errorf("failed to render webhook template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("webhook template did not produce valid JSON: %s", buf.String())
+	}
+	return buf.Bytes(), nil
+}
+
+// details lists the context shown below the alert message.
+func details(a alert.Alert) []string {
+	var d []string
+	if a.Entry != nil {
+		if a.Entry.Source != "" {
+			d = append(d, "source: "+a.Entry.Source)
+		}
+		if a.Entry.Env != "" {
+			d = append(d, "env: "+a.Entry.Env)
+		}
+		if a.Entry.Service != "" {
+			d = append(d, "service: "+a.Entry.Service)
+		}
+	}
+	if a.Suppressed > 0 {
+		d = append(d, fmt.Sprintf("%d similar alerts suppressed", a.Suppressed))
+	}
+	return d
+}
+
+func slackPayload(a alert.Alert) map[string]any {
+	blocks := []map[string]any{
+		{"type": "header", "text": map[string]any{"type": "plain_text", "text": truncate("🚨 "+a.Rule+": "+a.Title, slackHeaderLimit)}},
+		{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": "```" + truncate(a.Message, slackSectionLimit-6) + "```"}},
+	}
+	if d := details(a); len(d) > 0 {
+		blocks = append(blocks, map[string]any{
+			"type":     "context",
+			"elements": []map[string]any{{"type": "mrkdwn", "text": strings.Join(d, " · ")}},
+		})
+	}
+	return map[string]any{
+		"text":   fmt.Sprintf("[%s] %s: %s", a.Rule, a.Title, a.Message),
+		"blocks": blocks,
+	}
+}
+
+func discordPayload(a alert.Alert) map[string]any {
+	embed := map[string]any{
+		"title":       truncate(a.Rule+": "+a.Title, discordTitleLimit),
+		"description": "```\n" + truncate(a.Message, discordDescriptionLimit-8) + "\n```",
+		"color":       discordColor,
+		"timestamp":   a.Time.UTC().Format("2006-01-02T15:04:05.000Z"),
+	}
+	if d := details(a); len(d) > 0 {
+		embed["footer"] = map[string]any{"text": strings.Join(d, " · ")}
+	}
+	return map[string]any{
+		"content": fmt.Sprintf("🚨 **%s** fired", a.Rule),
+		"embeds":  []map[string]any{embed},
+	}
+}
+
+// truncate shortens s to at most limit characters, ending it with an
+// ellipsis when anything was cut.
+func truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:limit-1]) + "…"
+}
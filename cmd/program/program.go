@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/ebarthur/jotl/cmd/config"
@@ -18,6 +19,7 @@ type Project struct {
 	LogLevel     flags.LogLevel
 	DBDriver     flags.Database
 	GitOptions   flags.Git
+	Postgres     config.PostgresConfig
 	OSCheck      map[string]bool
 }
 
@@ -41,11 +43,9 @@ func (p *Project) CreateJotlProject(currentDir string, cfg config.JotlConfig) er
 	//check if user.email is set.
 	emailSet, _ := utils.CheckGitConfig("user.email")
 
-	// if user.email is not set, prompt user to set before continuing
+	// if user.email is not set, ask the user to set it before continuing
 	if !emailSet && p.GitOptions.IsGitEnabled() {
-		fmt.Println("user.email is not set in git config.")
-		fmt.Println("Please set up git config before trying again.")
-		panic("\nGIT CONFIG ISSUE: user.email is not set in git config.\n")
+		return fmt.Errorf("user.email is not set in git config. Set it with `git config --global user.email` and try again")
 	}
 
 	// if user chose "Yes" to Git options
@@ -76,21 +76,30 @@ func (p *Project) CreateJotlProject(currentDir string, cfg config.JotlConfig) er
 		return err
 	}
 
-	// Install the necessary DB Drivers
-	// `go get -` is used
-	// what if user doesn't have go on their machine? []: TODO: Support npm for installation
-	if err := utils.InstallDatabaseDrivers(string(p.DBDriver)); err != nil {
-		return err
-	}
-
 	// Initialize the `SQLite` db in directory
-	// For `postgres`, do nothing (the connection url is used to connect to external dbs)
-	if err := utils.CreateDatabase(currentDir, string(p.DBDriver)); err != nil {
+	// For `postgres`, write the docker compose file
+	// For `mysql` and `duckdb`, do nothing (the database is created by its server or on first open)
+	if err := utils.CreateDatabase(currentDir, string(p.DBDriver), p.Postgres); err != nil {
 		return err
 	}
 
-	// Create env file for the db and other configs
-	if err := utils.CreateEnvFile(currentDir, &cfg); err != nil {
+	// Create env file for the db and other configs. The PostgreSQL and
+	// pgAdmin passwords are generated per project and only stored there.
+	var secrets map[string]string
+	switch p.DBDriver {
+	case flags.Postgres:
+		var err error
+		if secrets, err = p.Postgres.Secrets(); err != nil {
+			return err
+		}
+	case flags.MySQL:
+		// The password belongs to an existing server, so leave it for the
+		// user to fill in.
+		if strings.Contains(cfg.Database.Path, "${"+config.MySQLPasswordEnv+"}") {
+			secrets = map[string]string{config.MySQLPasswordEnv: ""}
+		}
+	}
+	if err := utils.CreateEnvFile(currentDir, &cfg, secrets); err != nil {
 		return err
 	}
 
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/ingest"
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/ebarthur/jotl/cmd/utils"
+)
+
+// configProfile and configSets hold the --profile and --set flags, and
+// configFlags config values that commands set through their own flags.
+var (
+	configProfile string
+	configSets    []string
+	configFlags   []config.FlagOverride
+)
+
+// projectPaths returns the paths of the Jotl project in the current working
+// directory.
+func projectPaths() (string, utils.ConfigPaths, error) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return "", utils.ConfigPaths{}, fmt.Errorf("could not get current working directory: %w", err)
+	}
+
+	paths := utils.GetConfigPaths(currentDir)
+	if _, err := os.Stat(paths.ConfigFile); os.IsNotExist(err) {
+		return "", paths, fmt.Errorf("no Jotl project found in %s. Run `jotl init` first", currentDir)
+	}
+	return currentDir, paths, nil
+}
+
+// loadProject resolves the effective configuration of the Jotl project in
+// the current working directory.
+func loadProject() (string, utils.ConfigPaths, *config.JotlConfig, error) {
+	currentDir, paths, resolved, err := resolveProject()
+	if err != nil {
+		return "", paths, nil, err
+	}
+	return currentDir, paths, resolved.Config, nil
+}
+
+// resolveProject is loadProject that also reports where each value came from.
+func resolveProject() (string, utils.ConfigPaths, *config.Resolved, error) {
+	currentDir, paths, err := projectPaths()
+	if err != nil {
+		return "", paths, nil, err
+	}
+
+	layers, err := configLayers(paths)
+	if err != nil {
+		return "", paths, nil, err
+	}
+
+	resolved, err := config.Resolve(layers)
+	if err != nil {
+		return "", paths, nil, err
+	}
+	return currentDir, paths, resolved, nil
+}
+
+// configLayers returns the config layers of the project at paths, with the
+// values set by flags of the running command.
+func configLayers(paths utils.ConfigPaths) (config.Layers, error) {
+	layers := config.DefaultLayers(paths.ConfigFile)
+	layers.Profile = configProfile
+	for _, s := range configSets {
+		path, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return layers, fmt.Errorf("--set %s: expected path=value", s)
+		}
+		layers.Flags = append(layers.Flags, config.FlagOverride{Flag: "--set " + path, Path: path, Value: value})
+	}
+	layers.Flags = append(layers.Flags, configFlags...)
+	return layers, nil
+}
+
+// loadProjectFile reads jotl/config.yaml alone, without the layers that
+// override it, for commands that change the file.
+func loadProjectFile() (utils.ConfigPaths, *config.JotlConfig, error) {
+	_, paths, err := projectPaths()
+	if err != nil {
+		return paths, nil, err
+	}
+	cfg, err := config.LoadConfig(paths.ConfigFile)
+	return paths, cfg, err
+}
+
+// openStore connects to the project database and applies pending migrations.
+func openStore(ctx context.Context, paths utils.ConfigPaths, cfg *config.JotlConfig) (store.Store, error) {
+	db, err := store.Open(cfg.Database.Path, paths.ConfigDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// startRun records a run of command in db. The returned function records
+// its end.
+func startRun(ctx context.Context, db store.Store, command, env string, sources []ingest.Source) (string, func(), error) {
+	run := store.Run{
+		ID:        store.NewRunID(),
+		Command:   command,
+		Env:       env,
+		Version:   getJotlVersion(),
+		StartedAt: time.Now(),
+	}
+	run.Hostname, _ = os.Hostname()
+	for _, src := range sources {
+		run.Sources = append(run.Sources, src.Name())
+	}
+
+	if err := db.StartRun(ctx, run); err != nil {
+		return "", nil, err
+	}
+	return run.ID, func() { db.EndRun(context.Background(), run.ID) }, nil
+}
@@ -39,5 +39,7 @@ func Execute() {
 
 func init() {
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.PersistentFlags().StringVar(&configProfile, "profile", "", "Apply this profile from jotl/config.yaml (default $JOTL_PROFILE)")
+	rootCmd.PersistentFlags().StringArrayVar(&configSets, "set", nil, "Override a config value for this command, e.g. --set logging.level=debug (repeatable)")
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
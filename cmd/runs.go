@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var runsLimit int
+
+var runsCommand = &cobra.Command{
+	Use:   "runs",
+	Short: "List recent runs of jotl dev, ingest and import",
+	Args:  cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		_, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		ctx := context.Background()
+		db, err := openStore(ctx, paths, cfg)
+		cobra.CheckErr(err)
+		defer db.Close()
+
+		runs, err := db.Runs(ctx, runsLimit)
+		cobra.CheckErr(err)
+		if len(runs) == 0 {
+			fmt.Println(tipMsgStyle.Render("No runs recorded yet. Start one with `jotl dev`."))
+			return
+		}
+
+		for _, run := range runs {
+			duration := "running"
+			if run.EndedAt != nil {
+				duration = run.EndedAt.Sub(run.StartedAt).Round(1e9).String()
+			}
+			fmt.Printf("%-22s %-7s %-12s %-19s %-9s %s\n",
+				run.ID, run.Command, run.Env, run.StartedAt.Local().Format("2006-01-02 15:04:05"), duration, strings.Join(run.Sources, "; "))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runsCommand)
+	runsCommand.Flags().IntVarP(&runsLimit, "limit", "n", 20, "Number of runs to show")
+}
@@ -0,0 +1,292 @@
+// Package server implements the HTTP API and web interface behind
+// `jotl studio`.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/export"
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/ebarthur/jotl/gui"
+)
+
+const (
+	defaultLimit      = 100
+	maxLimit          = 5000
+	tailPollInterval  = time.Second
+	heartbeatInterval = 15 * time.Second
+)
+
+// Server serves the studio API on top of a store.
+type Server struct {
+	store store.Store
+	mux   *http.ServeMux
+}
+
+// New returns a server for db.
+func New(db store.Store) *Server {
+	s := &Server{store: db, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /api/health", s.handleHealth)
+	s.mux.HandleFunc("GET /api/logs", s.handleLogs)
+	s.mux.HandleFunc("GET /api/tail", s.handleTail)
+	s.mux.HandleFunc("GET /api/aggregates", s.handleAggregates)
+	s.mux.HandleFunc("GET /api/errors", s.handleErrors)
+	s.mux.HandleFunc("GET /api/export", s.handleExport)
+	s.mux.Handle("GET /", http.FileServerFS(gui.Dist()))
+
+	return s
+}
+
+// Handle registers an additional handler, e.g. an ingest endpoint.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Serve serves the studio on ln until ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	server := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Listen listens on the first free port starting at port, trying up to
+// attempts ports.
+func Listen(host string, port, attempts int) (net.Listener, error) {
+	var err error
+	for i := 0; i < attempts; i++ {
+		var ln net.Listener
+		ln, err = net.Listen("tcp", net.JoinHostPort(host, fmt.Sprint(port+i)))
+		if err == nil {
+			return ln, nil
+		}
+	}
+	return nil, fmt.Errorf("no free port between %d and %d: %w", port, port+attempts-1, err)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleLogs returns entries matching the filter, newest first unless
+// order=asc is given.
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	f, ok := parseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := s.store.Query(r.Context(), f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if entries == nil {
+		entries = []store.Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// handleAggregates counts entries by a column, or per time interval when
+// by=time.
+func (s *Server) handleAggregates(w http.ResponseWriter, r *http.Request) {
+	f, ok := parseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	by := r.URL.Query().Get("by")
+	if by == "" {
+		by = "level"
+	}
+
+	if by == "time" {
+		interval := time.Minute
+		if v := r.URL.Query().Get("interval"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid interval: %w", err))
+				return
+			}
+			interval = d
+		}
+		buckets, err := s.store.Histogram(r.Context(), f, interval)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if buckets == nil {
+			buckets = []store.Bucket{}
+		}
+		writeJSON(w, http.StatusOK, buckets)
+		return
+	}
+
+	counts, err := s.store.Aggregate(r.Context(), f, by)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if counts == nil {
+		counts = []store.Count{}
+	}
+	writeJSON(w, http.StatusOK, counts)
+}
+
+func (s *Server) handleErrors(w http.ResponseWriter, r *http.Request) {
+	f, ok := parseFilter(w, r)
+	if !ok {
+		return
+	}
+
+	groups, err := s.store.ErrorGroups(r.Context(), f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if groups == nil {
+		groups = []store.ErrorGroup{}
+	}
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// handleTail streams new entries as server-sent events. Without after_id,
+// only entries stored after the request started are sent.
+func (s *Server) handleTail(w http.ResponseWriter, r *http.Request) {
+	f, ok := parseFilter(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	ctx := r.Context()
+	if f.AfterID == 0 {
+		latest, err := s.store.Query(ctx, store.Filter{Limit: 1})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if len(latest) > 0 {
+			f.AfterID = latest[0].ID
+		}
+	}
+	f.Oldest = true
+	if f.Limit == 0 || f.Limit > maxLimit {
+		f.Limit = maxLimit
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	poll := time.NewTicker(tailPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case <-poll.C:
+			entries, err := s.store.Query(ctx, f)
+			if err != nil {
+				fmt.Fprintf(w, "event: error\ndata: %q\n\n", err.Error())
+				flusher.Flush()
+				return
+			}
+			for _, e := range entries {
+				data, _ := json.Marshal(e)
+				fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.ID, data)
+				f.AfterID = e.ID
+			}
+			if len(entries) > 0 {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// handleExport streams the entries matching the filter as a file download.
+// Unlike /api/logs, there is no default limit.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	f, err := store.ParseFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.NDJSON
+	}
+	compression := r.URL.Query().Get("compress")
+
+	ew, err := export.NewWriter(w, format, compression)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	filename := "jotl-" + time.Now().Format("20060102-150405") + export.Extension(format, compression)
+	w.Header().Set("Content-Type", export.ContentType(format, compression))
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	// Headers are already sent, so a failure can only cut the download short.
+	if _, err := export.Export(r.Context(), s.store, f, ew); err != nil {
+		log.Printf("studio: export failed: %v", err)
+	}
+}
+
+func parseFilter(w http.ResponseWriter, r *http.Request) (store.Filter, bool) {
+	f, err := store.ParseFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return store.Filter{}, false
+	}
+	if f.Limit <= 0 {
+		f.Limit = defaultLimit
+	}
+	if f.Limit > maxLimit {
+		f.Limit = maxLimit
+	}
+	return f, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -2,7 +2,12 @@
 // each step of the CLI
 package steps
 
-import "github.com/ebarthur/jotl/cmd/flags"
+import (
+	"slices"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+)
 
 // A StepSchema contains the data that is used
 // for an individual step of the CLI
@@ -39,6 +44,14 @@ func InitSteps(databaseType flags.Database, logLevel flags.LogLevel) *Steps {
 						Title: "Postgres",
 						Desc:  "Store logs in a robust, production-ready PostgreSQL database",
 					},
+					{
+						Title: "MySQL",
+						Desc:  "Store logs in an existing MySQL or MariaDB database",
+					},
+					{
+						Title: "DuckDB",
+						Desc:  "Store logs in a file-based DuckDB database built for analytics",
+					},
 				},
 				Headers: "What database driver do you want to use in your Jotl project?",
 				Field:   databaseType.String(),
@@ -66,6 +79,56 @@ func InitSteps(databaseType flags.Database, logLevel flags.LogLevel) *Steps {
 				},
 				Field: logLevel.String(),
 			},
+			"pg_version": {
+				StepName: "PostgreSQL Version",
+				Headers:  "Which PostgreSQL version should the container run?",
+				Options: []Item{
+					{
+						Title: "17",
+						Desc:  "Current stable release (recommended)",
+					},
+					{
+						Title: "16",
+						Desc:  "Previous release, supported until 2028",
+					},
+					{
+						Title: "15",
+						Desc:  "Older release, supported until 2027",
+					},
+					{
+						Title: "Latest",
+						Desc:  "Whatever postgres:latest points to when the image is pulled",
+					},
+				},
+			},
+			"pgadmin": {
+				StepName: "pgAdmin",
+				Headers:  "Add a pgAdmin container to browse the database?",
+				Options: []Item{
+					{
+						Title: "Yes",
+						Desc:  "Run pgAdmin next to PostgreSQL, with a generated login in jotl/.env",
+					},
+					{
+						Title: "Skip",
+						Desc:  "Only run PostgreSQL",
+					},
+				},
+			},
+			"compose": {
+				StepName: "Docker Compose File",
+				Headers:  "Your project already has a compose file. Where should the PostgreSQL service go?",
+				Options: []Item{
+					{
+						Title: "Separate",
+						Desc:  "Write jotl/docker-compose.yml and leave your compose file alone",
+					},
+					{
+						Title: "Merge",
+						Desc:  "Add jotl-postgres to your compose file so one `docker compose up` starts everything",
+					},
+				},
+			},
 			"git": {
 				StepName: "Git Repository",
 				Headers:  "Initialize a Git Repository for your Jotl project.",
@@ -83,5 +146,14 @@ func InitSteps(databaseType flags.Database, logLevel flags.LogLevel) *Steps {
 		},
 	}
 
+	// DuckDB is only compiled into builds made with -tags duckdb.
+	if !store.DuckDBAvailable {
+		driver := steps.Steps["driver"]
+		driver.Options = slices.DeleteFunc(driver.Options, func(item Item) bool {
+			return item.Title == "DuckDB"
+		})
+		steps.Steps["driver"] = driver
+	}
+
 	return steps
 }
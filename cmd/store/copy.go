@@ -0,0 +1,246 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgreSQL backups are plain SQL in the format of `pg_dump --data-only`:
+// a COPY ... FROM stdin block per table, so psql can load them as well.
+const copyHeader = "-- Jotl PostgreSQL backup"
+
+// serialTables have an id column backed by a sequence.
+var serialTables = []string{"logs", "notifications"}
+
+// postgresBackup writes every table to path in COPY text format, from a
+// single snapshot.
+func postgresBackup(ctx context.Context, db *sql.DB, path string) error {
+	// Backups hold the logs, so only the owner may read them.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var version sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	fmt.Fprintf(w, "%s\n-- Schema version: %d\n-- Created: %s\n", copyHeader, version.Int64, time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(w, "-- Restore with `jotl db restore`, or with psql into a database migrated by jotl.\n\n")
+	fmt.Fprintf(w, "BEGIN;\nTRUNCATE %s RESTART IDENTITY;\n", strings.Join(Tables, ", "))
+
+	for _, table := range Tables {
+		if err := copyOut(ctx, tx, w, table); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", table, err)
+		}
+	}
+
+	w.WriteString("\n")
+	for _, table := range serialTables {
+		fmt.Fprintf(w, "SELECT pg_catalog.setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 1), MAX(id) IS NOT NULL) FROM %s;\n", table, table)
+	}
+	w.WriteString("COMMIT;\n")
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// copyOut writes the rows of table as a COPY block.
+func copyOut(ctx context.Context, tx *sql.Tx, w *bufio.Writer, table string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT * FROM `+table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nCOPY %s (%s) FROM stdin;\n", table, strings.Join(columns, ", "))
+
+	values := make([]any, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			if i > 0 {
+				w.WriteByte('\t')
+			}
+			w.WriteString(copyValue(v))
+		}
+		w.WriteByte('\n')
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = w.WriteString("\\.\n")
+	return err
+}
+
+// copyValue renders v in COPY text format.
+func copyValue(v any) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return `\N`
+	case time.Time:
+		s = v.Format("2006-01-02 15:04:05.999999Z07:00")
+	case []byte:
+		s = string(v)
+	case bool:
+		s = "f"
+		if v {
+			s = "t"
+		}
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	default:
+		s = fmt.Sprint(v)
+	}
+	return copyEscaper.Replace(s)
+}
+
+var (
+	copyEscaper   = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+	copyUnescaper = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n", `\r`, "\r")
+)
+
+// postgresRestore loads a backup written by postgresBackup in a single
+// transaction, replacing the data in the database.
+func postgresRestore(ctx context.Context, db *sql.DB, path string, latest int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	if !scanner.Scan() || scanner.Text() != copyHeader {
+		return fmt.Errorf("not a Jotl PostgreSQL backup")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `TRUNCATE `+strings.Join(Tables, ", ")+` RESTART IDENTITY`); err != nil {
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if v, ok := strings.CutPrefix(line, "-- Schema version: "); ok {
+			if version, _ := strconv.Atoi(v); version > latest {
+				return fmt.Errorf("the backup has schema version %d; this jotl only knows %d", version, latest)
+			}
+			continue
+		}
+		table, columns, ok := parseCopy(line)
+		if !ok {
+			continue
+		}
+		if !slices.Contains(Tables, table) {
+			return fmt.Errorf("unexpected table %q", table)
+		}
+		if err := copyIn(ctx, tx, scanner, table, columns); err != nil {
+			return fmt.Errorf("failed to load %s: %w", table, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if err := postgresResetSequences(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// postgresResetSequences moves the sequences of serialTables past the ids
+// that were loaded with explicit values.
+func postgresResetSequences(ctx context.Context, tx *sql.Tx) error {
+	for _, table := range serialTables {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 1), MAX(id) IS NOT NULL) FROM %s`, table, table)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseCopy parses `COPY table (a, b) FROM stdin;`.
+func parseCopy(line string) (string, []string, bool) {
+	rest, ok := strings.CutPrefix(line, "COPY ")
+	if !ok {
+		return "", nil, false
+	}
+	rest, ok = strings.CutSuffix(rest, ") FROM stdin;")
+	if !ok {
+		return "", nil, false
+	}
+	table, list, ok := strings.Cut(rest, " (")
+	if !ok {
+		return "", nil, false
+	}
+	return table, strings.Split(list, ", "), true
+}
+
+// copyIn loads the rows of a COPY block up to its terminating `\.`.
+func copyIn(ctx context.Context, tx *sql.Tx, scanner *bufio.Scanner, table string, columns []string) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == `\.` {
+			_, err := stmt.ExecContext(ctx)
+			return err
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != len(columns) {
+			return fmt.Errorf("expected %d columns, got %d", len(columns), len(fields))
+		}
+		args := make([]any, len(fields))
+		for i, field := range fields {
+			if field != `\N` {
+				args[i] = copyUnescaper.Replace(field)
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("missing end of data")
+}
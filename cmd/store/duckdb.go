@@ -0,0 +1,293 @@
+//go:build duckdb
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	_ "github.com/marcboeker/go-duckdb"
+)
+
+// duckdbDialect holds the DuckDB schema. DuckDB scans columns with min-max
+// zone maps, so the logs get no secondary indexes; ART indexes would only
+// slow down inserts and make updates of indexed columns fail. Ids come from
+// sequences. Append new migrations; never edit one that has already shipped.
+var duckdbDialect = dialect{
+	name: "duckdb",
+	migrations: []migration{
+		{
+			`CREATE SEQUENCE logs_id_seq`,
+			`CREATE TABLE logs (
+				id BIGINT PRIMARY KEY DEFAULT nextval('logs_id_seq'),
+				timestamp TIMESTAMP NOT NULL,
+				level VARCHAR NOT NULL,
+				message VARCHAR NOT NULL,
+				source VARCHAR NOT NULL DEFAULT '',
+				env VARCHAR NOT NULL DEFAULT '',
+				status_code INTEGER,
+				fields VARCHAR
+			)`,
+			`CREATE TABLE checkpoints (
+				source_key VARCHAR PRIMARY KEY,
+				position BIGINT NOT NULL,
+				identity VARCHAR NOT NULL DEFAULT '',
+				updated_at TIMESTAMP NOT NULL
+			)`,
+		},
+		// Columns added later can't be NOT NULL in DuckDB.
+		{
+			`ALTER TABLE logs ADD COLUMN service VARCHAR DEFAULT ''`,
+			`ALTER TABLE logs ADD COLUMN trace_id VARCHAR`,
+			`ALTER TABLE logs ADD COLUMN span_id VARCHAR`,
+		},
+		{
+			`ALTER TABLE logs ADD COLUMN fingerprint VARCHAR`,
+		},
+		{
+			`CREATE SEQUENCE notifications_id_seq`,
+			`CREATE TABLE notifications (
+				id BIGINT PRIMARY KEY DEFAULT nextval('notifications_id_seq'),
+				url VARCHAR NOT NULL,
+				headers VARCHAR,
+				payload VARCHAR NOT NULL,
+				status VARCHAR NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				next_attempt_at TIMESTAMP NOT NULL,
+				last_error VARCHAR NOT NULL DEFAULT '',
+				created_at TIMESTAMP NOT NULL,
+				delivered_at TIMESTAMP
+			)`,
+		},
+		{
+			`CREATE TABLE runs (
+				id VARCHAR PRIMARY KEY,
+				command VARCHAR NOT NULL,
+				env VARCHAR NOT NULL DEFAULT '',
+				sources VARCHAR NOT NULL DEFAULT '',
+				hostname VARCHAR NOT NULL DEFAULT '',
+				version VARCHAR NOT NULL DEFAULT '',
+				started_at TIMESTAMP NOT NULL,
+				ended_at TIMESTAMP
+			)`,
+			`ALTER TABLE logs ADD COLUMN run_id VARCHAR`,
+		},
+	},
+	like: "ILIKE",
+	timeBucket: func(seconds int64) string {
+		return fmt.Sprintf("(CAST(FLOOR(epoch(timestamp) / %d) AS BIGINT) * %d)", seconds, seconds)
+	},
+	hasTable:       `SELECT COUNT(*) FROM information_schema.tables WHERE table_catalog = current_database() AND table_schema = current_schema() AND table_name = ?`,
+	upsert:         upsertCheckpoint,
+	sizes:          duckdbSizes,
+	vacuum:         []string{`ANALYZE`, `CHECKPOINT`},
+	backup:         duckdbBackup,
+	restore:        duckdbRestore,
+	resetSequences: duckdbResetSequences,
+}
+
+// DuckDBAvailable reports whether this build of jotl supports DuckDB.
+const DuckDBAvailable = true
+
+// openDuckDB opens the DuckDB database in file. Only one process can have
+// it open for writing, so stop jotl dev before running other commands.
+func openDuckDB(file string) (Store, error) {
+	db, err := sql.Open("duckdb", file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DuckDB database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to DuckDB database: %w", err)
+	}
+
+	return &sqlStore{db: db, dialect: duckdbDialect}, nil
+}
+
+// duckdbSizes reports the size of the database file in use. DuckDB keeps
+// no sizes per table.
+func duckdbSizes(ctx context.Context, db *sql.DB) (map[string]TableStats, int64, error) {
+	var total int64
+	err := db.QueryRowContext(ctx, `SELECT used_blocks * block_size FROM pragma_database_size() WHERE database_name = current_database()`).Scan(&total)
+	return map[string]TableStats{}, total, err
+}
+
+// duckdbBackup copies the tables of the database into a new DuckDB file at
+// path.
+func duckdbBackup(ctx context.Context, db *sql.DB, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	// Attached databases belong to the connection pool as a whole, so use
+	// one connection to keep the statements together.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var name string
+	if err := conn.QueryRowContext(ctx, `SELECT current_database()`).Scan(&name); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, `ATTACH `+duckdbString(path)+` AS jotl_backup`); err != nil {
+		return err
+	}
+	err = duckdbCopyTables(ctx, conn, duckdbIdent(name))
+	if _, detachErr := conn.ExecContext(ctx, `DETACH jotl_backup`); err == nil {
+		err = detachErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	// Backups hold the logs, so only the owner may read them.
+	return os.Chmod(path, 0600)
+}
+
+// duckdbCopyTables copies the tables of catalog into jotl_backup in one
+// transaction, so they come from a single snapshot. COPY FROM DATABASE
+// would be shorter, but it can't copy the defaults taken from sequences.
+func duckdbCopyTables(ctx context.Context, conn *sql.Conn, catalog string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, table := range append([]string{"schema_migrations"}, Tables...) {
+		if _, err := tx.ExecContext(ctx, `CREATE TABLE jotl_backup.`+table+` AS SELECT * FROM `+catalog+`.`+table); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// duckdbRestore replaces the data in the database with the tables of the
+// DuckDB backup at path.
+func duckdbRestore(ctx context.Context, db *sql.DB, path string, latest int) error {
+	header := make([]byte, 12)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.ReadFull(f, header)
+	f.Close()
+	if err != nil || string(header[8:]) != "DUCK" {
+		return fmt.Errorf("not a DuckDB backup")
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `ATTACH `+duckdbString(path)+` AS jotl_restore (READ_ONLY)`); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `DETACH jotl_restore`)
+
+	var version sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT MAX(version) FROM jotl_restore.schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("not a Jotl database: %w", err)
+	}
+	if int(version.Int64) > latest {
+		return fmt.Errorf("the backup has schema version %d; this jotl only knows %d", version.Int64, latest)
+	}
+
+	// DuckDB rejects keys that were deleted earlier in the same
+	// transaction, so the old data goes first. Should loading fail, the
+	// database stays empty until the restore is repeated.
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, table := range Tables {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	tx, err = conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, table := range Tables {
+		// Backups of older schema versions lack the tables and columns
+		// added since, which keep their defaults.
+		var columns []string
+		rows, err := tx.QueryContext(ctx, `SELECT column_name FROM information_schema.columns
+			WHERE table_catalog = 'jotl_restore' AND table_name = ? ORDER BY ordinal_position`, table)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var column string
+			if err := rows.Scan(&column); err != nil {
+				rows.Close()
+				return err
+			}
+			columns = append(columns, column)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		list := strings.Join(columns, ", ")
+		if _, err := tx.ExecContext(ctx, `INSERT INTO `+table+` (`+list+`) SELECT `+list+` FROM jotl_restore.`+table); err != nil {
+			return fmt.Errorf("failed to load %s: %w", table, err)
+		}
+	}
+	if err := duckdbResetSequences(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// duckdbResetSequences advances the id sequences past the ids that were
+// loaded with explicit values. DuckDB can't set a sequence, so this draws
+// values until it gets there.
+func duckdbResetSequences(ctx context.Context, tx *sql.Tx) error {
+	for _, table := range serialTables {
+		var max sql.NullInt64
+		if err := tx.QueryRowContext(ctx, `SELECT MAX(id) FROM `+table).Scan(&max); err != nil {
+			return err
+		}
+		if !max.Valid {
+			continue
+		}
+		seq := duckdbString(table + "_id_seq")
+		var next int64
+		if err := tx.QueryRowContext(ctx, `SELECT nextval(`+seq+`)`).Scan(&next); err != nil {
+			return err
+		}
+		if next < max.Int64 {
+			if _, err := tx.ExecContext(ctx, `SELECT MAX(nextval(`+seq+`)) FROM range(?)`, max.Int64-next); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// duckdbString quotes s as a string literal.
+func duckdbString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// duckdbIdent quotes s as an identifier.
+func duckdbIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -0,0 +1,12 @@
+//go:build !duckdb
+
+package store
+
+// DuckDBAvailable reports whether this build of jotl supports DuckDB.
+const DuckDBAvailable = false
+
+// DuckDB links a C++ library through cgo, so it is left out of default
+// builds.
+func openDuckDB(file string) (Store, error) {
+	return nil, ErrNoDuckDB
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// Variable parts of an error message that should not split one error into
+// many groups. Order matters: the most specific patterns run first.
+var fingerprintNormalizers = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<time>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<hex>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{12,}\b`), "<hex>"},
+	{regexp.MustCompile(`\b\d+(\.\d+)*\b`), "<n>"},
+	{regexp.MustCompile(`"[^"]*"|'[^']*'`), "<str>"},
+}
+
+// Fingerprint groups error messages that differ only in IDs, numbers,
+// timestamps or quoted values. Only the first line is used, so the same
+// panic with different stack traces lands in one group.
+func Fingerprint(message string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	for _, n := range fingerprintNormalizers {
+		line = n.pattern.ReplaceAllString(line, n.replacement)
+	}
+	sum := sha1.Sum([]byte(strings.Join(strings.Fields(line), " ")))
+	return hex.EncodeToString(sum[:8])
+}
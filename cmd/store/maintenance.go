@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Tables lists the tables holding data, in the order they are copied.
+// schema_migrations is left out; it belongs to the database, not the data.
+var Tables = []string{"runs", "logs", "checkpoints", "notifications"}
+
+// Stats describes the contents and size of a database.
+type Stats struct {
+	Size   int64        `json:"size"` // Bytes used by the whole database
+	Tables []TableStats `json:"tables"`
+	Levels []Count      `json:"levels"`           // Log entries per level
+	Oldest *time.Time   `json:"oldest,omitempty"` // Timestamp of the oldest entry
+	Newest *time.Time   `json:"newest,omitempty"` // Timestamp of the newest entry
+}
+
+// TableStats describes one table. Sizes are 0 where the database doesn't
+// report them.
+type TableStats struct {
+	Name      string `json:"name"`
+	Rows      int64  `json:"rows"`
+	Size      int64  `json:"size"`       // Bytes used by the rows
+	IndexSize int64  `json:"index_size"` // Bytes used by the table's indexes
+}
+
+// Stats reports row counts, sizes and the time range of the logs.
+func (s *sqlStore) Stats(ctx context.Context) (Stats, error) {
+	var st Stats
+	sizes, total, err := s.dialect.sizes(ctx, s.db)
+	if err != nil {
+		return st, fmt.Errorf("failed to read database size: %w", err)
+	}
+	st.Size = total
+
+	for _, table := range Tables {
+		ts := sizes[table]
+		ts.Name = table
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&ts.Rows); err != nil {
+			return st, fmt.Errorf("failed to count rows of %s: %w", table, err)
+		}
+		st.Tables = append(st.Tables, ts)
+	}
+
+	if st.Levels, err = s.Aggregate(ctx, Filter{}, "level"); err != nil {
+		return st, err
+	}
+
+	// MIN and MAX would lose the column type SQLite drivers parse times by.
+	for _, order := range []string{"ASC", "DESC"} {
+		var t time.Time
+		err := s.db.QueryRowContext(ctx, `SELECT timestamp FROM logs ORDER BY timestamp `+order+` LIMIT 1`).Scan(&t)
+		if errors.Is(err, sql.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			return st, fmt.Errorf("failed to read time range: %w", err)
+		}
+		if order == "ASC" {
+			st.Oldest = &t
+		} else {
+			st.Newest = &t
+		}
+	}
+	return st, nil
+}
+
+// Vacuum compacts the database, returning unused space to the operating
+// system, and refreshes the statistics of the query planner.
+func (s *sqlStore) Vacuum(ctx context.Context) error {
+	for _, stmt := range s.dialect.vacuum {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to vacuum database: %w", err)
+		}
+	}
+	return nil
+}
+
+// Backup writes a consistent copy of the database to the file at path.
+func (s *sqlStore) Backup(ctx context.Context, path string) error {
+	if err := s.dialect.backup(ctx, s.db, path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the data in the database with the backup at path and
+// migrates it to the current schema.
+func (s *sqlStore) Restore(ctx context.Context, path string) error {
+	latest := len(s.dialect.migrations)
+	if err := s.dialect.restore(ctx, s.db, path, latest); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", path, err)
+	}
+	return s.Migrate(ctx)
+}
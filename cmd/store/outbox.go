@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Notification statuses.
+const (
+	NotificationPending   = "pending"
+	NotificationDelivered = "delivered"
+	NotificationFailed    = "failed" // Gave up after too many attempts
+)
+
+// Notification is an outbound webhook request kept in the outbox until it
+// is delivered, so alerts survive a restart.
+type Notification struct {
+	ID            int64
+	URL           string
+	Headers       map[string]string
+	Payload       []byte
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+}
+
+// Enqueue adds notifications to the outbox, due immediately.
+func (s *sqlStore) Enqueue(ctx context.Context, notifications []Notification) error {
+	now := time.Now().UTC()
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, n := range notifications {
+			var headers sql.NullString
+			if len(n.Headers) > 0 {
+				data, err := json.Marshal(n.Headers)
+				if err != nil {
+					return err
+				}
+				headers = sql.NullString{String: string(data), Valid: true}
+			}
+			if _, err := tx.ExecContext(ctx, s.q(`INSERT INTO notifications
+				(url, headers, payload, status, attempts, next_attempt_at, created_at)
+				VALUES (?, ?, ?, ?, 0, ?, ?)`),
+				n.URL, headers, string(n.Payload), NotificationPending, now, now,
+			); err != nil {
+				return fmt.Errorf("failed to enqueue notification: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// DueNotifications returns pending notifications whose next attempt is due.
+func (s *sqlStore) DueNotifications(ctx context.Context, limit int) ([]Notification, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`SELECT id, url, headers, payload, status, attempts, next_attempt_at, last_error, created_at
+		FROM notifications WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`),
+		NotificationPending, time.Now().UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Notification
+	for rows.Next() {
+		var n Notification
+		var headers sql.NullString
+		var payload string
+		if err := rows.Scan(&n.ID, &n.URL, &headers, &payload, &n.Status, &n.Attempts, &n.NextAttemptAt, &n.LastError, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		n.Payload = []byte(payload)
+		if headers.Valid && headers.String != "" {
+			if err := json.Unmarshal([]byte(headers.String), &n.Headers); err != nil {
+				return nil, fmt.Errorf("failed to decode headers of notification %d: %w", n.ID, err)
+			}
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// UpdateNotification records the outcome of a delivery attempt.
+func (s *sqlStore) UpdateNotification(ctx context.Context, n Notification) error {
+	var delivered any
+	if n.Status == NotificationDelivered {
+		delivered = time.Now().UTC()
+	}
+	_, err := s.db.ExecContext(ctx, s.q(`UPDATE notifications
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, delivered_at = ?
+		WHERE id = ?`),
+		n.Status, n.Attempts, n.NextAttemptAt.UTC(), n.LastError, delivered, n.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update notification %d: %w", n.ID, err)
+	}
+	return nil
+}
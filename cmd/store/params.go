@@ -0,0 +1,106 @@
+package store
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+)
+
+// Values encodes f as URL query parameters, the form used by the studio API.
+func (f Filter) Values() url.Values {
+	v := url.Values{}
+	for _, l := range f.Levels {
+		v.Add("level", string(l))
+	}
+	set := func(key, value string) {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	set("min_level", string(f.MinLevel))
+	if !f.Since.IsZero() {
+		v.Set("since", f.Since.Format(time.RFC3339Nano))
+	}
+	if !f.Until.IsZero() {
+		v.Set("until", f.Until.Format(time.RFC3339Nano))
+	}
+	set("q", f.Search)
+	set("source", f.Source)
+	set("env", f.Env)
+	set("service", f.Service)
+	set("trace_id", f.TraceID)
+	set("fingerprint", f.Fingerprint)
+	set("run_id", f.RunID)
+	if f.AfterID > 0 {
+		v.Set("after_id", strconv.FormatInt(f.AfterID, 10))
+	}
+	if f.Limit > 0 {
+		v.Set("limit", strconv.Itoa(f.Limit))
+	}
+	if f.Oldest {
+		v.Set("order", "asc")
+	}
+	return v
+}
+
+// ParseFilter decodes query parameters produced by Filter.Values. Times may
+// be RFC 3339 or a duration relative to now, e.g. since=15m.
+func ParseFilter(v url.Values) (Filter, error) {
+	f := Filter{
+		Search:      v.Get("q"),
+		Source:      v.Get("source"),
+		Env:         v.Get("env"),
+		Service:     v.Get("service"),
+		TraceID:     v.Get("trace_id"),
+		Fingerprint: v.Get("fingerprint"),
+		RunID:       v.Get("run_id"),
+		Oldest:      v.Get("order") == "asc",
+	}
+
+	for _, l := range v["level"] {
+		var level flags.LogLevel
+		if err := level.Set(l); err != nil {
+			return Filter{}, err
+		}
+		f.Levels = append(f.Levels, level)
+	}
+	if l := v.Get("min_level"); l != "" {
+		if err := f.MinLevel.Set(l); err != nil {
+			return Filter{}, err
+		}
+	}
+
+	var err error
+	if f.Since, err = ParseTime(v.Get("since")); err != nil {
+		return Filter{}, fmt.Errorf("invalid since: %w", err)
+	}
+	if f.Until, err = ParseTime(v.Get("until")); err != nil {
+		return Filter{}, fmt.Errorf("invalid until: %w", err)
+	}
+	if s := v.Get("after_id"); s != "" {
+		if f.AfterID, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return Filter{}, fmt.Errorf("invalid after_id: %w", err)
+		}
+	}
+	if s := v.Get("limit"); s != "" {
+		if f.Limit, err = strconv.Atoi(s); err != nil {
+			return Filter{}, fmt.Errorf("invalid limit: %w", err)
+		}
+	}
+	return f, nil
+}
+
+// ParseTime accepts an RFC 3339 timestamp or a duration meaning "that long
+// ago". An empty string yields the zero time.
+func ParseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+)
+
+// postgresDialect holds the PostgreSQL schema. Append new migrations; never
+// edit one that has already shipped.
+var postgresDialect = dialect{
+	name: "postgres",
+	migrations: []migration{
+		{
+			`CREATE TABLE logs (
+				id BIGSERIAL PRIMARY KEY,
+				timestamp TIMESTAMPTZ NOT NULL,
+				level TEXT NOT NULL,
+				message TEXT NOT NULL,
+				source TEXT NOT NULL DEFAULT '',
+				env TEXT NOT NULL DEFAULT '',
+				status_code INTEGER,
+				fields JSONB
+			)`,
+			`CREATE INDEX idx_logs_timestamp ON logs (timestamp)`,
+			`CREATE INDEX idx_logs_level ON logs (level)`,
+			`CREATE TABLE checkpoints (
+				source_key TEXT PRIMARY KEY,
+				position BIGINT NOT NULL,
+				identity TEXT NOT NULL DEFAULT '',
+				updated_at TIMESTAMPTZ NOT NULL
+			)`,
+		},
+		{
+			`ALTER TABLE logs ADD COLUMN service TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE logs ADD COLUMN trace_id TEXT`,
+			`ALTER TABLE logs ADD COLUMN span_id TEXT`,
+			`CREATE INDEX idx_logs_trace_id ON logs (trace_id)`,
+		},
+		{
+			`ALTER TABLE logs ADD COLUMN fingerprint TEXT`,
+			`CREATE INDEX idx_logs_fingerprint ON logs (fingerprint)`,
+		},
+		{
+			`CREATE TABLE notifications (
+				id BIGSERIAL PRIMARY KEY,
+				url TEXT NOT NULL,
+				headers TEXT,
+				payload TEXT NOT NULL,
+				status TEXT NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				next_attempt_at TIMESTAMPTZ NOT NULL,
+				last_error TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL,
+				delivered_at TIMESTAMPTZ
+			)`,
+			`CREATE INDEX idx_notifications_pending ON notifications (status, next_attempt_at)`,
+		},
+		{
+			`CREATE TABLE runs (
+				id TEXT PRIMARY KEY,
+				command TEXT NOT NULL,
+				env TEXT NOT NULL DEFAULT '',
+				sources TEXT NOT NULL DEFAULT '',
+				hostname TEXT NOT NULL DEFAULT '',
+				version TEXT NOT NULL DEFAULT '',
+				started_at TIMESTAMPTZ NOT NULL,
+				ended_at TIMESTAMPTZ
+			)`,
+			`ALTER TABLE logs ADD COLUMN run_id TEXT`,
+			`CREATE INDEX idx_logs_run_id ON logs (run_id)`,
+		},
+	},
+	rebind: rebindDollar,
+	like:   "ILIKE",
+	timeBucket: func(seconds int64) string {
+		return fmt.Sprintf("(FLOOR(EXTRACT(EPOCH FROM timestamp) / %d) * %d)::BIGINT", seconds, seconds)
+	},
+	hasTable: `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`,
+	upsert:   upsertCheckpoint,
+	sizes:    postgresSizes,
+	// FULL rewrites the tables to give space back; it locks them meanwhile.
+	vacuum:         []string{`VACUUM (FULL, ANALYZE)`},
+	backup:         postgresBackup,
+	restore:        postgresRestore,
+	resetSequences: postgresResetSequences,
+}
+
+func openPostgres(dsn string) (Store, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to PostgreSQL database: %w", err)
+	}
+
+	return &sqlStore{db: db, dialect: postgresDialect}, nil
+}
+
+func postgresSizes(ctx context.Context, db *sql.DB) (map[string]TableStats, int64, error) {
+	var total int64
+	if err := db.QueryRowContext(ctx, `SELECT pg_database_size(current_database())`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	sizes := map[string]TableStats{}
+	for _, table := range Tables {
+		var ts TableStats
+		if err := db.QueryRowContext(ctx, `SELECT pg_table_size($1::regclass), pg_indexes_size($1::regclass)`, table).Scan(&ts.Size, &ts.IndexSize); err != nil {
+			return nil, 0, err
+		}
+		sizes[table] = ts
+	}
+	return sizes, total, nil
+}
+
+// rebindDollar rewrites `?` placeholders into PostgreSQL's `$1, $2, ...`.
+func rebindDollar(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
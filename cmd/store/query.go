@@ -0,0 +1,334 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+)
+
+// Filter selects log entries. Zero-valued fields don't constrain the query.
+type Filter struct {
+	Levels      []flags.LogLevel // Match any of these levels
+	MinLevel    flags.LogLevel   // Match this level and anything more severe
+	Since       time.Time        // Entries at or after this time
+	Until       time.Time        // Entries before this time
+	Search      string           // Case-insensitive substring of the message
+	Source      string
+	Env         string
+	Service     string
+	TraceID     string
+	Fingerprint string
+	RunID       string
+	AfterID     int64 // Entries inserted after this ID, for tailing
+	Limit       int   // Maximum number of entries; 0 means no limit
+	Oldest      bool  // Return entries in insertion order instead of newest first
+}
+
+// Count is the number of entries sharing one value of an aggregated column.
+type Count struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// Bucket is the number of entries in one interval of a histogram.
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+// ErrorGroup is a set of error entries sharing a fingerprint.
+type ErrorGroup struct {
+	Fingerprint string    `json:"fingerprint"`
+	Message     string    `json:"message"` // A representative message
+	Count       int64     `json:"count"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// AggregateColumns lists the columns entries can be counted by.
+var AggregateColumns = map[string]string{
+	"level":   "level",
+	"source":  "source",
+	"env":     "env",
+	"service": "service",
+	"status":  "status_code", // Scanned as text, so entries without one count under ""
+}
+
+const logColumns = `id, timestamp, level, message, source, env, status_code, service, trace_id, span_id, fingerprint, run_id, fields`
+
+// where builds the WHERE clause and arguments for f.
+func (s *sqlStore) where(f Filter) (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, values ...any) {
+		conds = append(conds, cond)
+		args = append(args, values...)
+	}
+
+	levels := f.Levels
+	if f.MinLevel != "" {
+		levels = nil
+		for _, l := range flags.AllowedLogLevels {
+			if flags.LogLevel(l).AtLeast(f.MinLevel) && (len(f.Levels) == 0 || containsLevel(f.Levels, flags.LogLevel(l))) {
+				levels = append(levels, flags.LogLevel(l))
+			}
+		}
+		if len(levels) == 0 {
+			add("1 = 0")
+		}
+	}
+	if len(levels) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(levels)), ", ")
+		values := make([]any, len(levels))
+		for i, l := range levels {
+			values[i] = string(l)
+		}
+		add("level IN ("+placeholders+")", values...)
+	}
+
+	if !f.Since.IsZero() {
+		add("timestamp >= ?", f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		add("timestamp < ?", f.Until.UTC())
+	}
+	if f.Search != "" {
+		add("message "+s.dialect.like+` ? ESCAPE '!'`, "%"+escapeLike(f.Search)+"%")
+	}
+	if f.Source != "" {
+		add("source = ?", f.Source)
+	}
+	if f.Env != "" {
+		add("env = ?", f.Env)
+	}
+	if f.Service != "" {
+		add("service = ?", f.Service)
+	}
+	if f.TraceID != "" {
+		add("trace_id = ?", f.TraceID)
+	}
+	if f.Fingerprint != "" {
+		add("fingerprint = ?", f.Fingerprint)
+	}
+	if f.RunID != "" {
+		add("run_id = ?", f.RunID)
+	}
+	if f.AfterID > 0 {
+		add("id > ?", f.AfterID)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// Each calls fn for every entry matching f without loading them all into
+// memory. Returning an error from fn stops the iteration.
+func (s *sqlStore) Each(ctx context.Context, f Filter, fn func(Entry) error) error {
+	where, args := s.where(f)
+	query := "SELECT " + logColumns + " FROM logs" + where
+	if f.Oldest {
+		query += " ORDER BY id ASC"
+	} else {
+		query += " ORDER BY id DESC"
+	}
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.q(query), args...)
+	if err != nil {
+		return fmt.Errorf("failed to query logs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Query returns the entries matching f.
+func (s *sqlStore) Query(ctx context.Context, f Filter) ([]Entry, error) {
+	var entries []Entry
+	err := s.Each(ctx, f, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// Aggregate counts the entries matching f by one of AggregateColumns.
+func (s *sqlStore) Aggregate(ctx context.Context, f Filter, by string) ([]Count, error) {
+	column, ok := AggregateColumns[by]
+	if !ok {
+		return nil, fmt.Errorf("cannot aggregate by %q", by)
+	}
+
+	where, args := s.where(f)
+	query := "SELECT " + column + " AS k, COUNT(*) FROM logs" + where + " GROUP BY k ORDER BY COUNT(*) DESC, k"
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.q(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate logs: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []Count
+	for rows.Next() {
+		var c Count
+		var key sql.NullString
+		if err := rows.Scan(&key, &c.Count); err != nil {
+			return nil, err
+		}
+		c.Key = key.String
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// Histogram counts the entries matching f per interval.
+func (s *sqlStore) Histogram(ctx context.Context, f Filter, interval time.Duration) ([]Bucket, error) {
+	seconds := int64(interval / time.Second)
+	if seconds < 1 {
+		return nil, fmt.Errorf("histogram interval must be at least one second")
+	}
+
+	where, args := s.where(f)
+	bucket := s.dialect.timeBucket(seconds)
+	query := "SELECT " + bucket + " AS b, COUNT(*) FROM logs" + where + " GROUP BY b ORDER BY b"
+
+	rows, err := s.db.QueryContext(ctx, s.q(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build histogram: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []Bucket
+	for rows.Next() {
+		var start, count int64
+		if err := rows.Scan(&start, &count); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, Bucket{Start: time.Unix(start, 0).UTC(), Count: count})
+	}
+	return buckets, rows.Err()
+}
+
+// ErrorGroups groups the error entries matching f by fingerprint, most
+// recently seen first.
+func (s *sqlStore) ErrorGroups(ctx context.Context, f Filter) ([]ErrorGroup, error) {
+	where, args := s.where(f)
+	if where == "" {
+		where = " WHERE fingerprint IS NOT NULL"
+	} else {
+		where += " AND fingerprint IS NOT NULL"
+	}
+
+	query := `SELECT fingerprint, MIN(message), COUNT(*), MIN(timestamp), MAX(timestamp)
+		FROM logs` + where + ` GROUP BY fingerprint ORDER BY MAX(timestamp) DESC`
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.q(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query error groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []ErrorGroup
+	for rows.Next() {
+		var g ErrorGroup
+		var first, last any
+		if err := rows.Scan(&g.Fingerprint, &g.Message, &g.Count, &first, &last); err != nil {
+			return nil, err
+		}
+		if g.FirstSeen, err = parseTimeValue(first); err != nil {
+			return nil, err
+		}
+		if g.LastSeen, err = parseTimeValue(last); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func scanEntry(rows *sql.Rows) (Entry, error) {
+	var e Entry
+	var level string
+	var status sql.NullInt64
+	var traceID, spanID, fingerprint, runID, fields sql.NullString
+	if err := rows.Scan(&e.ID, &e.Timestamp, &level, &e.Message, &e.Source, &e.Env, &status,
+		&e.Service, &traceID, &spanID, &fingerprint, &runID, &fields); err != nil {
+		return Entry{}, fmt.Errorf("failed to read log entry: %w", err)
+	}
+
+	e.Level = flags.LogLevel(level)
+	e.StatusCode = int(status.Int64)
+	e.TraceID = traceID.String
+	e.SpanID = spanID.String
+	e.Fingerprint = fingerprint.String
+	e.RunID = runID.String
+	if fields.Valid && fields.String != "" {
+		if err := json.Unmarshal([]byte(fields.String), &e.Fields); err != nil {
+			return Entry{}, fmt.Errorf("failed to decode fields of entry %d: %w", e.ID, err)
+		}
+	}
+	return e, nil
+}
+
+// parseTimeValue converts a timestamp returned by an aggregate, which some
+// drivers hand back as text rather than time.Time.
+func parseTimeValue(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case []byte:
+		return parseTimeValue(string(t))
+	case string:
+		for _, layout := range []string{"2006-01-02 15:04:05.999999999-07:00", time.RFC3339Nano, "2006-01-02 15:04:05.999999999"} {
+			if ts, err := time.Parse(layout, t); err == nil {
+				return ts, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("unrecognised timestamp %q", t)
+	case nil:
+		return time.Time{}, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %v", v)
+}
+
+func containsLevel(levels []flags.LogLevel, l flags.LogLevel) bool {
+	for _, x := range levels {
+		if x == l {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeLike escapes LIKE wildcards so Search matches literally. The escape
+// character is ! because MySQL reads backslashes in string literals too.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`).Replace(s)
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Run is one invocation of a jotl command that captures logs, such as
+// `jotl dev`. Entries record the run that stored them.
+type Run struct {
+	ID        string     `json:"id"`
+	Command   string     `json:"command"`           // e.g. "dev"
+	Env       string     `json:"env"`               // Default environment of the run
+	Sources   []string   `json:"sources,omitempty"` // Names of the sources read
+	Hostname  string     `json:"hostname,omitempty"`
+	Version   string     `json:"version,omitempty"` // Version of jotl
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"` // Nil while running, or if jotl was killed
+}
+
+// NewRunID returns a sortable, unique run ID such as 20261018-153012-3f9a.
+func NewRunID() string {
+	var b [2]byte
+	rand.Read(b[:])
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(b[:])
+}
+
+// StartRun records the start of a run.
+func (s *sqlStore) StartRun(ctx context.Context, run Run) error {
+	_, err := s.db.ExecContext(ctx, s.q(`INSERT INTO runs (id, command, env, sources, hostname, version, started_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		run.ID, run.Command, run.Env, strings.Join(run.Sources, "\n"), run.Hostname, run.Version, run.StartedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+	return nil
+}
+
+// EndRun records the end of the run with id.
+func (s *sqlStore) EndRun(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, s.q(`UPDATE runs SET ended_at = ? WHERE id = ?`), time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to record end of run %s: %w", id, err)
+	}
+	return nil
+}
+
+const runColumns = `id, command, env, sources, hostname, version, started_at, ended_at`
+
+// Run returns the run with id.
+func (s *sqlStore) Run(ctx context.Context, id string) (Run, bool, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`SELECT `+runColumns+` FROM runs WHERE id = ?`), id)
+	if err != nil {
+		return Run{}, false, fmt.Errorf("failed to read run %s: %w", id, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return Run{}, false, rows.Err()
+	}
+	run, err := scanRun(rows)
+	return run, err == nil, err
+}
+
+// Runs returns up to limit runs, most recent first.
+func (s *sqlStore) Runs(ctx context.Context, limit int) ([]Run, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`SELECT `+runColumns+` FROM runs ORDER BY started_at DESC LIMIT ?`), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []Run
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func scanRun(rows *sql.Rows) (Run, error) {
+	var run Run
+	var sources string
+	var ended sql.NullTime
+	if err := rows.Scan(&run.ID, &run.Command, &run.Env, &sources, &run.Hostname, &run.Version, &run.StartedAt, &ended); err != nil {
+		return Run{}, fmt.Errorf("failed to read run: %w", err)
+	}
+	if sources != "" {
+		run.Sources = strings.Split(sources, "\n")
+	}
+	if ended.Valid {
+		run.EndedAt = &ended.Time
+	}
+	return run, nil
+}
+
+// ErrNoRuns is returned when the latest run is requested but none exist.
+var ErrNoRuns = errors.New("no runs recorded yet")
@@ -0,0 +1,246 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+)
+
+// A migration is a list of statements applied together in one transaction.
+type migration []string
+
+// dialect captures the differences between the SQL databases Jotl supports.
+type dialect struct {
+	name       string
+	migrations []migration
+	// rebind rewrites `?` placeholders into the driver's native style.
+	rebind func(query string) string
+	// like is the case-insensitive LIKE operator.
+	like string
+	// timeBucket returns an expression truncating timestamp to a multiple
+	// of seconds, as unix seconds.
+	timeBucket func(seconds int64) string
+	// hasTable counts the tables named by its single parameter.
+	hasTable string
+	// upsert saves a checkpoint, replacing the one with the same key.
+	upsert string
+	// sizes returns the table and index sizes of each table and the size
+	// of the whole database, in bytes.
+	sizes func(ctx context.Context, db *sql.DB) (map[string]TableStats, int64, error)
+	// vacuum compacts the database.
+	vacuum []string
+	// backup writes the database to a new file at path, which restore
+	// loads back if it was taken at most at schema version latest.
+	backup  func(ctx context.Context, db *sql.DB, path string) error
+	restore func(ctx context.Context, db *sql.DB, path string, latest int) error
+	// applied, if set, reports whether a migration statement already took
+	// effect. Databases whose DDL commits implicitly use it to resume a
+	// migration that failed halfway, instead of failing on what it had
+	// already created.
+	applied func(ctx context.Context, tx *sql.Tx, stmt string) (bool, error)
+	// resetSequences, if set, moves id sequences past rows inserted with
+	// explicit ids.
+	resetSequences func(ctx context.Context, tx *sql.Tx) error
+}
+
+// upsertCheckpoint is the upsert of the dialects that support ON CONFLICT.
+const upsertCheckpoint = `INSERT INTO checkpoints (source_key, position, identity, updated_at)
+	VALUES (?, ?, ?, ?)
+	ON CONFLICT (source_key) DO UPDATE SET
+		position = excluded.position,
+		identity = excluded.identity,
+		updated_at = excluded.updated_at`
+
+// sqlStore implements Store on top of database/sql for any dialect.
+type sqlStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func (s *sqlStore) q(query string) string {
+	if s.dialect.rebind == nil {
+		return query
+	}
+	return s.dialect.rebind(query)
+}
+
+// Migrate applies every migration newer than the recorded schema version.
+func (s *sqlStore) Migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var current sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := int(current.Int64); i < len(s.dialect.migrations); i++ {
+		version := i + 1
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			for _, stmt := range s.dialect.migrations[i] {
+				if s.dialect.applied != nil {
+					if done, err := s.dialect.applied(ctx, tx, stmt); err != nil {
+						return err
+					} else if done {
+						continue
+					}
+				}
+				if _, err := tx.ExecContext(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			_, err := tx.ExecContext(ctx, s.q(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`), version, time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the applied schema version, 0 for a database that
+// was never migrated, and the latest version this build knows. Unlike
+// Migrate it doesn't change the database.
+func (s *sqlStore) SchemaVersion(ctx context.Context) (int, int, error) {
+	latest := len(s.dialect.migrations)
+
+	var tables int
+	if err := s.db.QueryRowContext(ctx, s.q(s.dialect.hasTable), "schema_migrations").Scan(&tables); err != nil {
+		return 0, latest, fmt.Errorf("failed to look up schema_migrations table: %w", err)
+	}
+	if tables == 0 {
+		return 0, latest, nil
+	}
+
+	var current sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&current); err != nil {
+		return 0, latest, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(current.Int64), latest, nil
+}
+
+// Insert writes entries and checkpoints atomically, so a checkpoint is
+// never ahead of (or behind) the lines it accounts for. Fingerprints of
+// error entries are filled in on entries.
+func (s *sqlStore) Insert(ctx context.Context, entries []Entry, checkpoints []Checkpoint) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if len(entries) > 0 {
+			stmt, err := tx.PrepareContext(ctx, s.q(`INSERT INTO logs
+				(timestamp, level, message, source, env, status_code, service, trace_id, span_id, fingerprint, run_id, fields)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`))
+			if err != nil {
+				return fmt.Errorf("failed to prepare insert: %w", err)
+			}
+			defer stmt.Close()
+
+			for i := range entries {
+				e := &entries[i]
+				fields, err := encodeFields(e.Fields)
+				if err != nil {
+					return err
+				}
+				if e.Fingerprint == "" && e.Level == flags.Error {
+					e.Fingerprint = Fingerprint(e.Message)
+				}
+				if _, err := stmt.ExecContext(ctx,
+					e.Timestamp.UTC(), string(e.Level), e.Message, e.Source, e.Env,
+					nullInt(e.StatusCode), e.Service, nullString(e.TraceID), nullString(e.SpanID), nullString(e.Fingerprint), nullString(e.RunID), fields,
+				); err != nil {
+					return fmt.Errorf("failed to insert log entry: %w", err)
+				}
+			}
+		}
+
+		for _, cp := range checkpoints {
+			if _, err := tx.ExecContext(ctx, s.q(s.dialect.upsert),
+				cp.Key, cp.Offset, cp.Identity, time.Now().UTC(),
+			); err != nil {
+				return fmt.Errorf("failed to save checkpoint for %s: %w", cp.Key, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Checkpoint returns the stored checkpoint for key.
+func (s *sqlStore) Checkpoint(ctx context.Context, key string) (Checkpoint, bool, error) {
+	cp := Checkpoint{Key: key}
+	err := s.db.QueryRowContext(ctx, s.q(`SELECT position, identity FROM checkpoints WHERE source_key = ?`), key).
+		Scan(&cp.Offset, &cp.Identity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Checkpoint{}, false, nil
+	}
+	if err != nil {
+		return Checkpoint{}, false, fmt.Errorf("failed to read checkpoint for %s: %w", key, err)
+	}
+	return cp, true, nil
+}
+
+// CheckpointByIdentity returns the latest checkpoint recorded for identity.
+func (s *sqlStore) CheckpointByIdentity(ctx context.Context, identity string) (Checkpoint, bool, error) {
+	cp := Checkpoint{Identity: identity}
+	err := s.db.QueryRowContext(ctx, s.q(`SELECT source_key, position FROM checkpoints
+		WHERE identity = ? ORDER BY updated_at DESC LIMIT 1`), identity).
+		Scan(&cp.Key, &cp.Offset)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Checkpoint{}, false, nil
+	}
+	if err != nil {
+		return Checkpoint{}, false, fmt.Errorf("failed to read checkpoint for %s: %w", identity, err)
+	}
+	return cp, true, nil
+}
+
+// Close closes the database connection.
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqlStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func encodeFields(fields map[string]any) (any, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode fields: %w", err)
+	}
+	return string(data), nil
+}
+
+func nullInt(v int) any {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+func nullString(v string) any {
+	if v == "" {
+		return nil
+	}
+	return v
+}
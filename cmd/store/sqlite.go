@@ -0,0 +1,259 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"modernc.org/sqlite"
+)
+
+// sqliteDialect holds the SQLite schema. Append new migrations; never edit
+// one that has already shipped.
+var sqliteDialect = dialect{
+	name: "sqlite",
+	migrations: []migration{
+		{
+			`CREATE TABLE logs (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				timestamp TIMESTAMP NOT NULL,
+				level TEXT NOT NULL,
+				message TEXT NOT NULL,
+				source TEXT NOT NULL DEFAULT '',
+				env TEXT NOT NULL DEFAULT '',
+				status_code INTEGER,
+				fields TEXT
+			)`,
+			`CREATE INDEX idx_logs_timestamp ON logs (timestamp)`,
+			`CREATE INDEX idx_logs_level ON logs (level)`,
+			`CREATE TABLE checkpoints (
+				source_key TEXT PRIMARY KEY,
+				position INTEGER NOT NULL,
+				identity TEXT NOT NULL DEFAULT '',
+				updated_at TIMESTAMP NOT NULL
+			)`,
+		},
+		{
+			`ALTER TABLE logs ADD COLUMN service TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE logs ADD COLUMN trace_id TEXT`,
+			`ALTER TABLE logs ADD COLUMN span_id TEXT`,
+			`CREATE INDEX idx_logs_trace_id ON logs (trace_id)`,
+		},
+		{
+			`ALTER TABLE logs ADD COLUMN fingerprint TEXT`,
+			`CREATE INDEX idx_logs_fingerprint ON logs (fingerprint)`,
+		},
+		{
+			`CREATE TABLE notifications (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				url TEXT NOT NULL,
+				headers TEXT,
+				payload TEXT NOT NULL,
+				status TEXT NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				next_attempt_at TIMESTAMP NOT NULL,
+				last_error TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMP NOT NULL,
+				delivered_at TIMESTAMP
+			)`,
+			`CREATE INDEX idx_notifications_pending ON notifications (status, next_attempt_at)`,
+		},
+		{
+			`CREATE TABLE runs (
+				id TEXT PRIMARY KEY,
+				command TEXT NOT NULL,
+				env TEXT NOT NULL DEFAULT '',
+				sources TEXT NOT NULL DEFAULT '',
+				hostname TEXT NOT NULL DEFAULT '',
+				version TEXT NOT NULL DEFAULT '',
+				started_at TIMESTAMP NOT NULL,
+				ended_at TIMESTAMP
+			)`,
+			`ALTER TABLE logs ADD COLUMN run_id TEXT`,
+			`CREATE INDEX idx_logs_run_id ON logs (run_id)`,
+		},
+	},
+	like: "LIKE",
+	timeBucket: func(seconds int64) string {
+		return fmt.Sprintf("(CAST(strftime('%%s', timestamp) AS INTEGER) / %d) * %d", seconds, seconds)
+	},
+	hasTable: `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`,
+	upsert:   upsertCheckpoint,
+	sizes:    sqliteSizes,
+	// VACUUM rewrites the file; the checkpoint shrinks the WAL it grew.
+	vacuum:  []string{`ANALYZE`, `VACUUM`, `PRAGMA wal_checkpoint(TRUNCATE)`},
+	backup:  sqliteBackup,
+	restore: sqliteRestore,
+}
+
+// legacySQLiteParams maps the DSN parameters of go-sqlite3, which jotl used
+// before, to the pragmas they set.
+var legacySQLiteParams = map[string]string{
+	"_journal_mode": "journal_mode",
+	"_busy_timeout": "busy_timeout",
+	"_foreign_keys": "foreign_keys",
+	"_synchronous":  "synchronous",
+}
+
+func openSQLite(dsn string) (Store, error) {
+	dsn, err := sqliteDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SQLite database path: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to SQLite database: %w", err)
+	}
+
+	return &sqlStore{db: db, dialect: sqliteDialect}, nil
+}
+
+// sqliteDSN adds the connection settings jotl relies on to dsn. Paths
+// without parameters get WAL mode and a busy timeout, so jotl dev can write
+// while other commands read. Times are always written in SQLite's own
+// format, which strftime and comparisons with stored values understand.
+func sqliteDSN(dsn string) (string, error) {
+	name, query, _ := strings.Cut(dsn, "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return "", err
+	}
+	if query == "" {
+		params["_pragma"] = []string{"journal_mode(WAL)", "busy_timeout(5000)"}
+	}
+	for param, pragma := range legacySQLiteParams {
+		if value := params.Get(param); value != "" {
+			params.Add("_pragma", pragma+"("+value+")")
+			params.Del(param)
+		}
+	}
+	params.Set("_time_format", "sqlite")
+	return name + "?" + params.Encode(), nil
+}
+
+// sqliteSizes reports sizes from the dbstat virtual table. SQLite builds
+// without it only report the size of the whole file.
+func sqliteSizes(ctx context.Context, db *sql.DB) (map[string]TableStats, int64, error) {
+	var pages, pageSize int64
+	if err := db.QueryRowContext(ctx, `PRAGMA page_count`).Scan(&pages); err != nil {
+		return nil, 0, err
+	}
+	if err := db.QueryRowContext(ctx, `PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return nil, 0, err
+	}
+
+	sizes := map[string]TableStats{}
+	rows, err := db.QueryContext(ctx, `SELECT m.tbl_name, m.type, SUM(s.pgsize)
+		FROM dbstat s JOIN sqlite_master m ON m.name = s.name
+		GROUP BY m.tbl_name, m.type`)
+	if err != nil {
+		return sizes, pages * pageSize, nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, kind string
+		var size int64
+		if err := rows.Scan(&table, &kind, &size); err != nil {
+			return nil, 0, err
+		}
+		ts := sizes[table]
+		if kind == "index" {
+			ts.IndexSize += size
+		} else {
+			ts.Size += size
+		}
+		sizes[table] = ts
+	}
+	return sizes, pages * pageSize, rows.Err()
+}
+
+// sqliteBackup copies the database into a new SQLite file at path with
+// the online backup API, so writers can carry on meanwhile.
+func sqliteBackup(ctx context.Context, db *sql.DB, path string) error {
+	// Backups hold the logs, so only the owner may read them.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	return copySQLite(ctx, db, func(c sqliteConn) (*sqlite.Backup, error) {
+		return c.NewBackup(path)
+	})
+}
+
+// sqliteRestore copies the SQLite backup at path over the database.
+func sqliteRestore(ctx context.Context, db *sql.DB, path string, latest int) error {
+	header := make([]byte, 16)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.ReadFull(f, header)
+	f.Close()
+	if err != nil || string(header) != "SQLite format 3\x00" {
+		return fmt.Errorf("not a SQLite backup")
+	}
+
+	uri := "file:" + path + "?mode=ro"
+	src, err := sql.Open("sqlite", uri)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if version, err := backupVersion(ctx, src); err != nil {
+		return err
+	} else if version > latest {
+		return fmt.Errorf("the backup has schema version %d; this jotl only knows %d", version, latest)
+	}
+	return copySQLite(ctx, db, func(c sqliteConn) (*sqlite.Backup, error) {
+		return c.NewRestore(uri)
+	})
+}
+
+// backupVersion returns the schema version of a SQLite backup.
+func backupVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("not a Jotl database: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// sqliteConn is the driver connection of modernc.org/sqlite, which runs
+// the online backup API.
+type sqliteConn interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// copySQLite runs the backup that start sets up on a connection of db,
+// copying all pages at once.
+func copySQLite(ctx context.Context, db *sql.DB, start func(sqliteConn) (*sqlite.Backup, error)) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(c any) error {
+		backup, err := start(c.(sqliteConn))
+		if err != nil {
+			return err
+		}
+		if _, err := backup.Step(-1); err != nil {
+			backup.Finish()
+			return err
+		}
+		return backup.Finish()
+	})
+}
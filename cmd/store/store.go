@@ -0,0 +1,156 @@
+// Package store persists captured log entries into the database
+// configured for a Jotl project.
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+)
+
+// Entry is a single structured log line as stored in the logs table.
+type Entry struct {
+	ID          int64          `json:"id"`
+	Timestamp   time.Time      `json:"timestamp"`
+	Level       flags.LogLevel `json:"level"`
+	Message     string         `json:"message"`
+	Source      string         `json:"source"`                // Where the line came from, e.g. file:logs/app.log
+	Env         string         `json:"env"`                   // Environment the line was captured in
+	StatusCode  int            `json:"status,omitempty"`      // HTTP status code, if the line carried one
+	Service     string         `json:"service,omitempty"`     // Service that emitted the line, when known
+	TraceID     string         `json:"trace_id,omitempty"`    // Hex-encoded trace ID, when known
+	SpanID      string         `json:"span_id,omitempty"`     // Hex-encoded span ID, when known
+	Fingerprint string         `json:"fingerprint,omitempty"` // Error group of error entries, see Fingerprint
+	RunID       string         `json:"run_id,omitempty"`      // Run of jotl that captured the line
+	Fields      map[string]any `json:"fields,omitempty"`      // Any remaining structured data
+}
+
+// Checkpoint records how far an ingest source has read, so a restart
+// can resume without re-ingesting or skipping lines.
+type Checkpoint struct {
+	Key      string // Unique source key, e.g. file:/abs/path/app.log
+	Offset   int64  // Byte offset of the next unread line
+	Identity string // Identity of the underlying object (device/inode for files)
+}
+
+// Store is implemented by every supported database driver.
+type Store interface {
+	// Migrate applies any pending schema migrations.
+	Migrate(ctx context.Context) error
+	// SchemaVersion returns the applied and the latest known schema version.
+	SchemaVersion(ctx context.Context) (applied, latest int, err error)
+	// Insert writes entries and checkpoints in a single transaction.
+	Insert(ctx context.Context, entries []Entry, checkpoints []Checkpoint) error
+	// Checkpoint returns the stored checkpoint for key, if any.
+	Checkpoint(ctx context.Context, key string) (Checkpoint, bool, error)
+	// CheckpointByIdentity returns the most recent checkpoint recorded for
+	// identity under any key, e.g. a log file that has since been renamed.
+	CheckpointByIdentity(ctx context.Context, identity string) (Checkpoint, bool, error)
+	// Query returns the entries matching f.
+	Query(ctx context.Context, f Filter) ([]Entry, error)
+	// Each streams the entries matching f to fn.
+	Each(ctx context.Context, f Filter, fn func(Entry) error) error
+	// Aggregate counts the entries matching f by one of AggregateColumns.
+	Aggregate(ctx context.Context, f Filter, by string) ([]Count, error)
+	// Histogram counts the entries matching f per interval.
+	Histogram(ctx context.Context, f Filter, interval time.Duration) ([]Bucket, error)
+	// ErrorGroups groups the error entries matching f by fingerprint.
+	ErrorGroups(ctx context.Context, f Filter) ([]ErrorGroup, error)
+	// Enqueue adds notifications to the outbox.
+	Enqueue(ctx context.Context, notifications []Notification) error
+	// DueNotifications returns up to limit pending notifications that are due.
+	DueNotifications(ctx context.Context, limit int) ([]Notification, error)
+	// UpdateNotification records the outcome of a delivery attempt.
+	UpdateNotification(ctx context.Context, n Notification) error
+	// StartRun records the start of a run.
+	StartRun(ctx context.Context, run Run) error
+	// EndRun records the end of the run with id.
+	EndRun(ctx context.Context, id string) error
+	// Run returns the run with id.
+	Run(ctx context.Context, id string) (Run, bool, error)
+	// Runs returns up to limit runs, most recent first.
+	Runs(ctx context.Context, limit int) ([]Run, error)
+	// Stats reports row counts, sizes and the time range of the logs.
+	Stats(ctx context.Context) (Stats, error)
+	// Vacuum compacts the database.
+	Vacuum(ctx context.Context) error
+	// Backup writes a consistent copy of the database to a new file.
+	Backup(ctx context.Context, path string) error
+	// Restore replaces the data with a backup taken by Backup.
+	Restore(ctx context.Context, path string) error
+	// Close releases the underlying database connection.
+	Close() error
+}
+
+// DriverFromPath infers the database driver from a configured Database.Path.
+func DriverFromPath(path string) (flags.Database, error) {
+	switch {
+	case strings.HasPrefix(path, "postgres://"), strings.HasPrefix(path, "postgresql://"):
+		return flags.Postgres, nil
+	case strings.HasPrefix(path, "mysql://"), strings.HasPrefix(path, "mariadb://"):
+		return flags.MySQL, nil
+	case strings.HasPrefix(path, "duckdb:"), strings.HasSuffix(path, ".duckdb"):
+		return flags.DuckDB, nil
+	case strings.HasPrefix(path, "file:"), strings.HasSuffix(path, ".db"):
+		return flags.Sqlite, nil
+	default:
+		return "", fmt.Errorf("cannot determine database driver from path %q", path)
+	}
+}
+
+// Open connects to the database at path. Relative SQLite paths are
+// resolved against baseDir, which is normally the project's jotl directory.
+func Open(path, baseDir string) (Store, error) {
+	driver, err := DriverFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch driver {
+	case flags.Sqlite:
+		return openSQLite(resolveSQLitePath(path, baseDir))
+	case flags.Postgres:
+		return openPostgres(path)
+	case flags.MySQL:
+		return openMySQL(path)
+	case flags.DuckDB:
+		return openDuckDB(DuckDBFile(path, baseDir))
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", driver)
+	}
+}
+
+// SQLiteFile returns the absolute file name of the SQLite database at path.
+func SQLiteFile(path, baseDir string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(resolveSQLitePath(path, baseDir), "file:"), "?")
+	return name
+}
+
+// ErrNoDuckDB is returned for DuckDB databases by builds without DuckDB.
+var ErrNoDuckDB = errors.New("this jotl was built without DuckDB; build it with `go build -tags duckdb` to use DuckDB databases")
+
+// DuckDBFile returns the absolute file name of the DuckDB database at path.
+func DuckDBFile(path, baseDir string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(path, "duckdb:"), "?")
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(baseDir, name)
+	}
+	return name
+}
+
+// resolveSQLitePath turns a relative `file:` DSN into an absolute one.
+func resolveSQLitePath(path, baseDir string) string {
+	name, query, _ := strings.Cut(strings.TrimPrefix(path, "file:"), "?")
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(baseDir, name)
+	}
+	if query != "" {
+		return "file:" + name + "?" + query
+	}
+	return "file:" + name
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultBatchSize is the number of rows Transfer writes per transaction.
+const DefaultBatchSize = 1000
+
+// TransferOptions configures Transfer.
+type TransferOptions struct {
+	BatchSize int  // Rows per transaction; DefaultBatchSize if 0
+	Replace   bool // Delete the rows already in the target instead of refusing to copy
+	// Progress, if set, is called after every batch with the table and the
+	// number of its rows copied so far.
+	Progress func(table string, copied int64)
+}
+
+// ErrNotEmpty is returned by Transfer when the target already holds data.
+var ErrNotEmpty = errors.New("the target database is not empty")
+
+// Transfer copies every table of src into dst, keeping ids, so logs, runs,
+// checkpoints, notifications and the error groups derived from the logs
+// carry over unchanged. Both stores must be migrated to the same schema
+// version. Rows are streamed from src and written to dst in batches.
+func Transfer(ctx context.Context, dst, src Store, opts TransferOptions) error {
+	from, ok := src.(*sqlStore)
+	if !ok {
+		return fmt.Errorf("cannot transfer from %T", src)
+	}
+	to, ok := dst.(*sqlStore)
+	if !ok {
+		return fmt.Errorf("cannot transfer to %T", dst)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	srcVersion, _, err := from.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	dstVersion, _, err := to.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if srcVersion != dstVersion {
+		return fmt.Errorf("the source is at schema version %d, the target at %d", srcVersion, dstVersion)
+	}
+
+	for _, table := range Tables {
+		var rows int64
+		if err := to.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&rows); err != nil {
+			return fmt.Errorf("failed to count rows of %s: %w", table, err)
+		}
+		if rows == 0 {
+			continue
+		}
+		if !opts.Replace {
+			return fmt.Errorf("%w: %s holds %d rows", ErrNotEmpty, table, rows)
+		}
+		if _, err := to.db.ExecContext(ctx, `DELETE FROM `+table); err != nil {
+			return fmt.Errorf("failed to empty %s: %w", table, err)
+		}
+	}
+
+	for _, table := range Tables {
+		if err := to.copyTable(ctx, from, table, opts); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", table, err)
+		}
+	}
+
+	if to.dialect.resetSequences != nil {
+		return to.inTx(ctx, func(tx *sql.Tx) error {
+			return to.dialect.resetSequences(ctx, tx)
+		})
+	}
+	return nil
+}
+
+// copyTable streams the rows of table from src into s.
+func (s *sqlStore) copyTable(ctx context.Context, src *sqlStore, table string, opts TransferOptions) error {
+	rows, err := src.db.QueryContext(ctx, `SELECT * FROM `+table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	insert := s.q(fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, table,
+		strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")))
+
+	var copied int64
+	batch := make([][]any, 0, opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := s.inTx(ctx, func(tx *sql.Tx) error {
+			stmt, err := tx.PrepareContext(ctx, insert)
+			if err != nil {
+				return err
+			}
+			defer stmt.Close()
+			for _, values := range batch {
+				if _, err := stmt.ExecContext(ctx, values...); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		copied += int64(len(batch))
+		batch = batch[:0]
+		if opts.Progress != nil {
+			opts.Progress(table, copied)
+		}
+		return nil
+	}
+
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		// Text and JSON come back as bytes from some drivers; SQLite would
+		// store those as blobs.
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		batch = append(batch, values)
+		if len(batch) == opts.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
@@ -1,7 +1,22 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
 	"github.com/charmbracelet/glamour"
+	"github.com/ebarthur/jotl/cmd/bundle"
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/metrics"
+	"github.com/ebarthur/jotl/cmd/server"
 	"github.com/spf13/cobra"
 )
 
@@ -16,25 +31,95 @@ It provides a modern, user-friendly interface to:
 The dashboard automatically starts on port 8080 and will increment
 until it finds an available port if 8080 is in use.
 
+The dashboard is backed by a JSON API that scripts can use directly:
+- ` + "`GET /api/logs`" + ` returns entries, filtered by ` + "`level`" + `, ` + "`min_level`" + `, ` + "`since`" + `, ` + "`until`" + `, ` + "`q`" + `, ` + "`source`" + `, ` + "`env`" + `, ` + "`service`" + `, ` + "`trace_id`" + ` and ` + "`limit`" + `
+- ` + "`GET /api/tail`" + ` streams new entries as server-sent events
+- ` + "`GET /api/aggregates?by=level`" + ` counts entries by level, source, env, service or status, or per interval with ` + "`by=time&interval=1m`" + `
+- ` + "`GET /api/errors`" + ` lists error groups
+- ` + "`GET /api/export?format=csv|ndjson|parquet`" + ` downloads the filtered entries, optionally with ` + "`compress=gzip|zstd`" + `
+- ` + "`GET /metrics`" + ` exposes Prometheus metrics derived from logs stored while the studio runs
+
+A bundle shared with ` + "`jotl bundle create`" + ` opens read-only, without a project:
+jotl studio --bundle checkout-bug.tar.zst
+
 Note: The studio dashboard requires the project to be initialized with 'jotl init'
 and have a valid database connection configured in the jotl directory.`)
 
 var studioCommand = &cobra.Command{
 	Use:   "studio",
-	Short: "Start logging console output to database with optional real-time display",
+	Short: "Launch the web dashboard for browsing stored logs",
 	Long: func() string {
 		out, _ := glamour.Render(lngMessage, "dark")
 		return out
 	}(),
 
 	Run: func(cmd *cobra.Command, args []string) {
-		//
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if studioBundle != "" {
+			serveBundle(ctx, studioBundle)
+			return
+		}
+
+		if cmd.Flags().Changed("port") {
+			configFlags = append(configFlags, config.FlagOverride{Flag: "--port", Path: "dashboard.port", Value: strconv.Itoa(port)})
+		}
+		_, paths, cfg, err := loadProject()
+		cobra.CheckErr(err)
+
+		db, err := openStore(ctx, paths, cfg)
+		cobra.CheckErr(err)
+		defer db.Close()
+
+		ln, err := server.Listen("localhost", cfg.Dashboard.Port, 100)
+		cobra.CheckErr(err)
+
+		fmt.Println(endingMsgStyle.Render(fmt.Sprintf("Jotl studio is running at http://localhost:%d", ln.Addr().(*net.TCPAddr).Port)))
+		fmt.Println(tipMsgStyle.Render("Press Ctrl+C to stop."))
+
+		collector := metrics.New()
+		go func() {
+			if err := collector.Follow(ctx, db, time.Second); err != nil && ctx.Err() == nil {
+				fmt.Fprintln(os.Stderr, "metrics: stopped updating:", err)
+			}
+		}()
+
+		srv := server.New(db)
+		srv.Handle("GET "+metrics.Path, collector)
+		cobra.CheckErr(srv.Serve(ctx, ln))
 	},
 }
 
+// serveBundle opens a bundle read-only in the studio. No project is needed.
+func serveBundle(ctx context.Context, path string) {
+	b, err := bundle.Open(ctx, path)
+	cobra.CheckErr(err)
+	defer b.Close()
+
+	ln, err := server.Listen("localhost", port, 100)
+	cobra.CheckErr(err)
+
+	m := b.Manifest
+	fmt.Println(endingMsgStyle.Render(fmt.Sprintf("Jotl studio is showing run %s of %s (%d entries, read-only) at http://localhost:%d",
+		m.Run.ID, m.Project, m.Entries, ln.Addr().(*net.TCPAddr).Port)))
+	fmt.Println(tipMsgStyle.Render("Press Ctrl+C to stop."))
+
+	srv := server.New(b.Store)
+	srv.Handle("GET /api/bundle", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"manifest": m, "config": string(b.Config)})
+	}))
+	cobra.CheckErr(srv.Serve(ctx, ln))
+}
+
 func init() {
 	rootCmd.AddCommand(studioCommand)
-	studioCommand.Flags().IntVarP(&port, "port", "p", 8080, "Port to run the dashboard server")
+	studioCommand.Flags().IntVarP(&port, "port", "p", 8080, "Port to run the dashboard server, overriding dashboard.port")
+	studioCommand.Flags().StringVar(&studioBundle, "bundle", "", "Open a bundle created by \"jotl bundle create\" read-only instead of the project database")
 }
 
-var port int
+var (
+	port         int
+	studioBundle string
+)
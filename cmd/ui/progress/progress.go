@@ -0,0 +1,67 @@
+// Package progress draws a progress bar for long-running commands that
+// don't run a full bubbletea program
+package progress
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"golang.org/x/term"
+)
+
+// Bar redraws a progress bar in place on a terminal. On anything else it
+// prints a line whenever a step finishes.
+type Bar struct {
+	out   *os.File
+	bar   progress.Model
+	tty   bool
+	total int64
+	drawn time.Time
+}
+
+// New returns a bar for total units of work written to out.
+func New(out *os.File, total int64) *Bar {
+	return &Bar{
+		out:   out,
+		bar:   progress.New(progress.WithDefaultGradient(), progress.WithWidth(40)),
+		tty:   term.IsTerminal(int(out.Fd())),
+		total: total,
+	}
+}
+
+// Update shows done of the total units, labelled with the current step.
+// Redraws are limited to ten a second.
+func (b *Bar) Update(label string, done int64) {
+	if !b.tty || time.Since(b.drawn) < 100*time.Millisecond {
+		return
+	}
+	b.drawn = time.Now()
+	fmt.Fprintf(b.out, "\r\033[K%s %s (%d/%d)", b.bar.ViewAs(b.percent(done)), label, done, b.total)
+}
+
+// Step reports that a step finished, leaving its line on screen.
+func (b *Bar) Step(line string, done int64) {
+	if b.tty {
+		fmt.Fprintf(b.out, "\r\033[K%s\n", line)
+		b.drawn = time.Time{}
+		b.Update("", done)
+		return
+	}
+	fmt.Fprintln(b.out, line)
+}
+
+// Done clears the bar.
+func (b *Bar) Done() {
+	if b.tty {
+		fmt.Fprint(b.out, "\r\033[K")
+	}
+}
+
+func (b *Bar) percent(done int64) float64 {
+	if b.total == 0 {
+		return 1
+	}
+	return float64(done) / float64(b.total)
+}
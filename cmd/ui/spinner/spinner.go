@@ -53,7 +53,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 var messages = []string{
 	"Initializing project...",
 	"Setting up environment...",
-	"Loading dependencies...",
+	"Creating database...",
 	"Almost done...",
 }
 
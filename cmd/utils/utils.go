@@ -3,10 +3,12 @@ package utils
 import (
 	"bytes"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/ebarthur/jotl/cmd/config"
@@ -29,11 +31,6 @@ type ConfigPaths struct {
 	DBDir      string
 }
 
-var (
-	postgresDriver = []string{"github.com/lib/pq"}
-	sqliteDriver   = []string{"github.com/mattn/go-sqlite3"}
-)
-
 // GetConfigPaths returns the necessary paths for configuration
 func GetConfigPaths(currentDir string) ConfigPaths {
 	return ConfigPaths{
@@ -52,6 +49,13 @@ func ValidateModuleName(moduleName string) bool {
 	return matched
 }
 
+// ValidateProfileName checks if name can be used as a config profile: one
+// or more letters, digits, underscores and hyphens.
+func ValidateProfileName(name string) bool {
+	matched, _ := regexp.MatchString("^[a-zA-Z0-9_-]+$", name)
+	return matched
+}
+
 func CheckGitConfig(key string) (bool, error) {
 	cmd := exec.Command("git", "config", "--get", key)
 	if err := cmd.Run(); err != nil {
@@ -107,9 +111,15 @@ func NonInteractiveCommand(use string, flagSet *pflag.FlagSet) string {
 					}
 				}
 				nonInteractiveCommand += featureFlagsString
+			} else if slice, ok := flag.Value.(pflag.SliceValue); ok {
+				for _, v := range slice.GetSlice() {
+					nonInteractiveCommand = fmt.Sprintf("%s --%s %s", nonInteractiveCommand, flag.Name, v)
+				}
 			} else if flag.Value.Type() == "bool" {
 				if flag.Value.String() == "true" {
 					nonInteractiveCommand = fmt.Sprintf("%s --%s", nonInteractiveCommand, flag.Name)
+				} else if flag.DefValue == "true" {
+					nonInteractiveCommand = fmt.Sprintf("%s --%s=false", nonInteractiveCommand, flag.Name)
 				}
 			} else {
 				nonInteractiveCommand = fmt.Sprintf("%s --%s %s", nonInteractiveCommand, flag.Name, flag.Value.String())
@@ -123,6 +133,33 @@ func NonInteractiveCommand(use string, flagSet *pflag.FlagSet) string {
 	return nonInteractiveCommand
 }
 
+// FreePort returns port if nothing listens on it yet, or else the next free
+// port after it.
+func FreePort(port int) (int, error) {
+	for p := port; p < port+100 && p <= 65535; p++ {
+		l, err := net.Listen("tcp", fmt.Sprintf(":%d", p))
+		if err == nil {
+			l.Close()
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("no free port found between %d and %d", port, port+99)
+}
+
+// FormatBytes renders a size in bytes with a binary unit, e.g. 1.5 MiB.
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 // ExecuteCmd provides a shorthand way to run a shell command
 func ExecuteCmd(name string, args []string, dir string) error {
 	command := exec.Command(name, args...)
@@ -172,31 +209,12 @@ func EnsureGitignore(dir string) error {
 	return nil
 }
 
-// InstallDatabaseDrivers installs the specified database driver package using the `go get` command.
-// Supported database drivers are "postgres" and "sqlite".
-func InstallDatabaseDrivers(dbDriver string) error {
-	var driverPackage string
-
-	switch dbDriver {
-	case "postgres":
-		driverPackage = sqliteDriver[0]
-	case "sqlite":
-		driverPackage = postgresDriver[0]
-	default:
-		return fmt.Errorf("unsupported database driver: %s", dbDriver)
-	}
-
-	if err := ExecuteCmd("go", []string{"get", "-u", driverPackage}, ""); err != nil {
-		return fmt.Errorf("failed to install database driver %s: %w", driverPackage, err)
-	}
-
-	return nil
-}
-
 // CreateDatabase sets up the database for the jotl project based on the specified driver.
 // For SQLite, it creates a database file in the jotl directory.
-// For PostgreSQL, it assumes the database is managed externally and does not create any files.
-func CreateDatabase(currentDir string, dbDriver string) error {
+// For PostgreSQL, it writes the docker compose services described by pg.
+// MySQL databases live on an existing server and DuckDB creates its file on
+// first use, so neither needs anything here.
+func CreateDatabase(currentDir string, dbDriver string, pg config.PostgresConfig) error {
 	paths := GetConfigPaths(currentDir)
 
 	switch dbDriver {
@@ -210,10 +228,10 @@ func CreateDatabase(currentDir string, dbDriver string) error {
 		// For PostgreSQL, we don't need to create any db file
 		// The connection string will be used when connecting to the database
 		// Touch docker-compose.yml for the postgres
-		config := config.DefaultPostgresConfig()
-		if err := config.CreateDockerCompose(currentDir, config); err != nil {
+		if err := pg.CreateDockerCompose(currentDir, pg); err != nil {
 			return fmt.Errorf("failed to create docker-compose configuration: %w", err)
 		}
+	case "mysql", "duckdb":
 	default:
 		return fmt.Errorf("unsupported database driver: %s", dbDriver)
 	}
@@ -231,7 +249,8 @@ func CreateDatabase(currentDir string, dbDriver string) error {
 //	    		│   └── jotl.db
 //	    		└── jotl.config.yaml
 //
-// This structure is exclusive of postgres driver
+// The db directory is only created for the file-based sqlite and duckdb
+// drivers
 func InitializeJotlDirectory(currentDir, dbDriver string) error {
 	paths := GetConfigPaths(currentDir)
 
@@ -240,8 +259,8 @@ func InitializeJotlDirectory(currentDir, dbDriver string) error {
 		return fmt.Errorf("failed to create jotl directory: %w", err)
 	}
 
-	// Create db directory for `sqlite`
-	if dbDriver != "postgres" {
+	// Create db directory for `sqlite` and `duckdb`
+	if dbDriver == "sqlite" || dbDriver == "duckdb" {
 		if err := os.MkdirAll(paths.DBDir, 0755); err != nil {
 			return fmt.Errorf("failed to create db directory: %w", err)
 		}
@@ -272,8 +291,9 @@ func IsJotlInitialized(currentDir string) bool {
 	return true
 }
 
-// CreateEnvFile creates a .env file with the configuration
-func CreateEnvFile(currentDir string, cfg *config.JotlConfig) error {
+// CreateEnvFile creates a .env file with the configuration and the secrets
+// referenced from it. The file is only readable by its owner.
+func CreateEnvFile(currentDir string, cfg *config.JotlConfig, secrets map[string]string) error {
 	envPath := GetConfigPaths(currentDir).ConfigDir + "/.env"
 
 	envContent := fmt.Sprintf(
@@ -282,7 +302,19 @@ func CreateEnvFile(currentDir string, cfg *config.JotlConfig) error {
 		cfg.Project.Name,
 	)
 
-	if err := os.WriteFile(envPath, []byte(envContent), 0644); err != nil {
+	if len(secrets) > 0 {
+		envContent += "\n# Secrets (keep this file out of version control)\n"
+		keys := make([]string, 0, len(secrets))
+		for key := range secrets {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			envContent += fmt.Sprintf("%s=%s\n", key, secrets[key])
+		}
+	}
+
+	if err := os.WriteFile(envPath, []byte(envContent), 0600); err != nil {
 		return fmt.Errorf("failed to create .env file: %w", err)
 	}
 
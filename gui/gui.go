@@ -0,0 +1,19 @@
+// Package gui embeds the built studio web interface.
+package gui
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:build/dist
+var files embed.FS
+
+// Dist returns the built web interface, rooted at index.html.
+func Dist() fs.FS {
+	dist, err := fs.Sub(files, "build/dist")
+	if err != nil {
+		panic(err)
+	}
+	return dist
+}
@@ -0,0 +1,220 @@
+// Package client reads logs captured by Jotl, either straight from a
+// project's database or from a running `jotl studio`.
+//
+//	c, err := client.Open("")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer c.Close()
+//
+//	entries, err := c.Query(ctx, client.Filter{MinLevel: "error", Since: time.Now().Add(-time.Hour)})
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+// DefaultPollInterval is how often Tail checks a local store for new entries.
+const DefaultPollInterval = time.Second
+
+// Limits on the entries, counts or error groups one call returns, the same
+// as the studio API's.
+const (
+	DefaultLimit = 100  // Used when Filter.Limit is 0
+	MaxLimit     = 5000 // Larger limits are lowered to this
+)
+
+// Entry is one stored log entry.
+type Entry struct {
+	ID          int64          `json:"id"`
+	Timestamp   time.Time      `json:"timestamp"`
+	Level       string         `json:"level"`
+	Message     string         `json:"message"`
+	Source      string         `json:"source"`
+	Env         string         `json:"env"`
+	StatusCode  int            `json:"status,omitempty"`
+	Service     string         `json:"service,omitempty"`
+	TraceID     string         `json:"trace_id,omitempty"`
+	SpanID      string         `json:"span_id,omitempty"`
+	Fingerprint string         `json:"fingerprint,omitempty"`
+	RunID       string         `json:"run_id,omitempty"`
+	Fields      map[string]any `json:"fields,omitempty"`
+}
+
+// Filter selects log entries. Zero-valued fields don't constrain the query.
+type Filter struct {
+	Levels      []string  // Match any of these levels
+	MinLevel    string    // Match this level and anything more severe
+	Since       time.Time // Entries at or after this time
+	Until       time.Time // Entries before this time
+	Search      string    // Case-insensitive substring of the message
+	Source      string
+	Env         string
+	Service     string
+	TraceID     string
+	Fingerprint string
+	RunID       string
+	AfterID     int64 // Entries stored after this ID
+	Limit       int   // Maximum number of results; 0 means DefaultLimit, and at most MaxLimit
+	Oldest      bool  // Return entries oldest first instead of newest first
+}
+
+// Count is the number of entries sharing one value of an aggregated field.
+type Count struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// Bucket is the number of entries in one interval of a histogram.
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+// ErrorGroup is a set of error entries sharing a fingerprint.
+type ErrorGroup struct {
+	Fingerprint string    `json:"fingerprint"`
+	Message     string    `json:"message"`
+	Count       int64     `json:"count"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// backend is implemented by the local store and the studio API.
+type backend interface {
+	query(ctx context.Context, f store.Filter) ([]Entry, error)
+	tail(ctx context.Context, f store.Filter, out chan<- Entry) error
+	aggregate(ctx context.Context, f store.Filter, by string) ([]Count, error)
+	histogram(ctx context.Context, f store.Filter, interval time.Duration) ([]Bucket, error)
+	errorGroups(ctx context.Context, f store.Filter) ([]ErrorGroup, error)
+	close() error
+}
+
+// Client reads logs from a Jotl store. It is safe for concurrent use.
+type Client struct {
+	backend backend
+}
+
+// Query returns the entries matching f, newest first unless f.Oldest is set.
+func (c *Client) Query(ctx context.Context, f Filter) ([]Entry, error) {
+	sf, err := f.store()
+	if err != nil {
+		return nil, err
+	}
+	return c.backend.query(ctx, sf)
+}
+
+// Tail sends entries matching f as they are stored, oldest first. Unless
+// f.AfterID is set, only entries stored after Tail is called are sent.
+//
+// Both channels are closed when ctx is cancelled. If tailing fails, the error
+// is sent on the error channel first.
+func (c *Client) Tail(ctx context.Context, f Filter) (<-chan Entry, <-chan error) {
+	entries := make(chan Entry)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(entries)
+
+		sf, err := f.store()
+		if err == nil {
+			err = c.backend.tail(ctx, sf, entries)
+		}
+		if err != nil && ctx.Err() == nil {
+			errs <- err
+		}
+	}()
+
+	return entries, errs
+}
+
+// Aggregate counts the entries matching f by level, source, env, service or
+// status.
+func (c *Client) Aggregate(ctx context.Context, f Filter, by string) ([]Count, error) {
+	sf, err := f.store()
+	if err != nil {
+		return nil, err
+	}
+	return c.backend.aggregate(ctx, sf, by)
+}
+
+// Histogram counts the entries matching f per interval.
+func (c *Client) Histogram(ctx context.Context, f Filter, interval time.Duration) ([]Bucket, error) {
+	sf, err := f.store()
+	if err != nil {
+		return nil, err
+	}
+	return c.backend.histogram(ctx, sf, interval)
+}
+
+// ErrorGroups groups the error entries matching f by fingerprint, most
+// recently seen first.
+func (c *Client) ErrorGroups(ctx context.Context, f Filter) ([]ErrorGroup, error) {
+	sf, err := f.store()
+	if err != nil {
+		return nil, err
+	}
+	return c.backend.errorGroups(ctx, sf)
+}
+
+// Close releases the database connection, if any.
+func (c *Client) Close() error {
+	return c.backend.close()
+}
+
+// store converts f to the filter used by the store and the studio API.
+func (f Filter) store() (store.Filter, error) {
+	sf := store.Filter{
+		Since:       f.Since,
+		Until:       f.Until,
+		Search:      f.Search,
+		Source:      f.Source,
+		Env:         f.Env,
+		Service:     f.Service,
+		TraceID:     f.TraceID,
+		Fingerprint: f.Fingerprint,
+		RunID:       f.RunID,
+		AfterID:     f.AfterID,
+		Limit:       min(f.Limit, MaxLimit),
+		Oldest:      f.Oldest,
+	}
+	if sf.Limit <= 0 {
+		sf.Limit = DefaultLimit
+	}
+	for _, l := range f.Levels {
+		var level flags.LogLevel
+		if err := level.Set(l); err != nil {
+			return store.Filter{}, err
+		}
+		sf.Levels = append(sf.Levels, level)
+	}
+	if f.MinLevel != "" {
+		if err := sf.MinLevel.Set(f.MinLevel); err != nil {
+			return store.Filter{}, err
+		}
+	}
+	return sf, nil
+}
+
+func fromStoreEntry(e store.Entry) Entry {
+	return Entry{
+		ID:          e.ID,
+		Timestamp:   e.Timestamp,
+		Level:       string(e.Level),
+		Message:     e.Message,
+		Source:      e.Source,
+		Env:         e.Env,
+		StatusCode:  e.StatusCode,
+		Service:     e.Service,
+		TraceID:     e.TraceID,
+		SpanID:      e.SpanID,
+		Fingerprint: e.Fingerprint,
+		RunID:       e.RunID,
+		Fields:      e.Fields,
+	}
+}
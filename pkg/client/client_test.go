@@ -0,0 +1,304 @@
+package client
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/server"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+var base = time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+// newProject writes a project with a SQLite database to a temporary
+// directory and returns the path of its config file and its store.
+func newProject(t *testing.T, migrate bool) (string, store.Store) {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "jotl", "config.yaml")
+	if err := config.NewConfig("shop", "info", "jotl.db").SaveConfig(configPath); err != nil {
+		t.Fatal(err)
+	}
+	db, err := store.Open("jotl.db", filepath.Dir(configPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if migrate {
+		if err := db.Migrate(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return configPath, db
+}
+
+func insert(t *testing.T, db store.Store, entries ...store.Entry) {
+	t.Helper()
+	if err := db.Insert(context.Background(), entries, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sampleEntries() []store.Entry {
+	return []store.Entry{
+		{Timestamp: base, Level: flags.Info, Message: "server started", Source: "file:app.log", Service: "api", RunID: "run-1"},
+		{Timestamp: base.Add(time.Second), Level: flags.Error, Message: "db query 17 timed out", Source: "file:app.log", Service: "api", TraceID: "4bf92f3577b34da6", Fields: map[string]any{"query": "select"}},
+		{Timestamp: base.Add(time.Minute), Level: flags.Error, Message: "db query 18 timed out", Source: "docker:worker", Service: "worker"},
+		{Timestamp: base.Add(2 * time.Minute), Level: flags.Warn, Message: "slow request", Source: "docker:worker", Service: "worker", StatusCode: 200},
+	}
+}
+
+// clients returns a local and a remote client reading the same sample
+// project.
+func clients(t *testing.T) (map[string]*Client, store.Store) {
+	t.Helper()
+
+	configPath, db := newProject(t, true)
+	insert(t, db, sampleEntries()...)
+
+	lc, err := Open(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { lc.Close() })
+	lc.backend.(*local).pollInterval = 10 * time.Millisecond
+
+	srv := httptest.NewServer(server.New(db))
+	t.Cleanup(srv.Close)
+	remote, err := Connect(srv.URL+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]*Client{"local": lc, "remote": remote}, db
+}
+
+func messages(entries []Entry) string {
+	var m []string
+	for _, e := range entries {
+		m = append(m, e.Message)
+	}
+	return strings.Join(m, ", ")
+}
+
+func TestQuery(t *testing.T) {
+	all, _ := clients(t)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   string
+	}{
+		{"newest first", Filter{}, "slow request, db query 18 timed out, db query 17 timed out, server started"},
+		{"oldest first", Filter{Oldest: true, Limit: 2}, "server started, db query 17 timed out"},
+		{"levels", Filter{Levels: []string{"info", "warn"}}, "slow request, server started"},
+		{"min level", Filter{MinLevel: "error"}, "db query 18 timed out, db query 17 timed out"},
+		{"since until", Filter{Since: base.Add(time.Second), Until: base.Add(2 * time.Minute)}, "db query 18 timed out, db query 17 timed out"},
+		{"search", Filter{Search: "QUERY 17"}, "db query 17 timed out"},
+		{"source", Filter{Source: "docker:worker"}, "slow request, db query 18 timed out"},
+		{"service", Filter{Service: "api", Limit: 1}, "db query 17 timed out"},
+		{"trace", Filter{TraceID: "4bf92f3577b34da6"}, "db query 17 timed out"},
+		{"run", Filter{RunID: "run-1"}, "server started"},
+	}
+	for name, c := range all {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				got, err := c.Query(context.Background(), tt.filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if messages(got) != tt.want {
+					t.Errorf("Query = %s, want %s", messages(got), tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestLocalAndRemoteAgree(t *testing.T) {
+	all, _ := clients(t)
+	ctx := context.Background()
+
+	results := map[string]string{}
+	for name, c := range all {
+		entries, err := c.Query(ctx, Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts, err := c.Aggregate(ctx, Filter{}, "level")
+		if err != nil {
+			t.Fatal(err)
+		}
+		buckets, err := c.Histogram(ctx, Filter{}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		groups, err := c.ErrorGroups(ctx, Filter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := json.MarshalIndent(map[string]any{
+			"entries": entries, "counts": counts, "buckets": buckets, "groups": groups,
+		}, "", "  ")
+		results[name] = string(data)
+	}
+	if results["local"] != results["remote"] {
+		t.Errorf("local client returned\n%s\nremote client returned\n%s", results["local"], results["remote"])
+	}
+
+	var got struct {
+		Entries []Entry
+		Counts  []Count
+		Buckets []Bucket
+		Groups  []ErrorGroup
+	}
+	json.Unmarshal([]byte(results["local"]), &got)
+	if len(got.Entries) != 4 || got.Entries[2].Fields["query"] != "select" || got.Entries[0].StatusCode != 200 {
+		t.Errorf("entries = %+v", got.Entries)
+	}
+	if len(got.Counts) != 3 || got.Counts[0] != (Count{Key: "error", Count: 2}) {
+		t.Errorf("counts by level = %+v", got.Counts)
+	}
+	if len(got.Buckets) != 3 || got.Buckets[0].Count != 2 {
+		t.Errorf("buckets = %+v", got.Buckets)
+	}
+	if len(got.Groups) != 1 || got.Groups[0].Count != 2 {
+		t.Errorf("error groups = %+v", got.Groups)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	all, db := clients(t)
+
+	var many []store.Entry
+	for i := range 150 {
+		many = append(many, store.Entry{Timestamp: base.Add(time.Hour + time.Duration(i)*time.Second), Level: flags.Info, Message: fmt.Sprint("request ", i)})
+	}
+	insert(t, db, many...)
+
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{0, DefaultLimit},
+		{120, 120},
+		{MaxLimit + 1, 154},
+	}
+	for name, c := range all {
+		for _, tt := range tests {
+			got, err := c.Query(context.Background(), Filter{Limit: tt.limit})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("%s: Query with limit %d returned %d entries, want %d", name, tt.limit, len(got), tt.want)
+			}
+		}
+	}
+}
+
+func TestTail(t *testing.T) {
+	all, db := clients(t)
+
+	for name, c := range all {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			entries, errs := c.Tail(ctx, Filter{MinLevel: "warn"})
+			// Give Tail time to note the latest stored entry.
+			time.Sleep(100 * time.Millisecond)
+			insert(t, db,
+				store.Entry{Timestamp: base.Add(time.Hour), Level: flags.Debug, Message: name + " debug"},
+				store.Entry{Timestamp: base.Add(time.Hour), Level: flags.Error, Message: name + " failed"},
+			)
+
+			select {
+			case e := <-entries:
+				if e.Message != name+" failed" || e.Level != "error" {
+					t.Errorf("Tail sent %+v, want the new error", e)
+				}
+			case err := <-errs:
+				t.Fatalf("Tail failed: %v", err)
+			case <-ctx.Done():
+				t.Fatal("timed out waiting for the new entry")
+			}
+
+			cancel()
+			for range entries {
+			}
+			if err, ok := <-errs; ok {
+				t.Errorf("Tail sent %v after being cancelled", err)
+			}
+		})
+	}
+}
+
+func TestInvalidFilter(t *testing.T) {
+	all, _ := clients(t)
+	for name, c := range all {
+		if _, err := c.Query(context.Background(), Filter{MinLevel: "loud"}); err == nil {
+			t.Errorf("%s: Query accepted an unknown level", name)
+		}
+		if _, err := c.Aggregate(context.Background(), Filter{}, "message"); err == nil {
+			t.Errorf("%s: Aggregate accepted an unknown column", name)
+		}
+	}
+}
+
+func TestOpenChecksSchema(t *testing.T) {
+	t.Run("not migrated", func(t *testing.T) {
+		configPath, db := newProject(t, false)
+		if _, err := Open(configPath); err == nil || !strings.Contains(err.Error(), "no Jotl schema") {
+			t.Errorf("Open = %v, want a missing schema error", err)
+		}
+		if applied, _, _ := db.SchemaVersion(context.Background()); applied != 0 {
+			t.Errorf("Open migrated the database to version %d", applied)
+		}
+	})
+
+	t.Run("outdated", func(t *testing.T) {
+		configPath, db := newProject(t, true)
+		_, latest, _ := db.SchemaVersion(context.Background())
+
+		// Forget the last migration, as if the database predated it.
+		raw, err := sql.Open("sqlite", filepath.Join(filepath.Dir(configPath), "jotl.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer raw.Close()
+		if _, err := raw.Exec(`DELETE FROM schema_migrations WHERE version = ?`, latest); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := Open(configPath); err == nil || !strings.Contains(err.Error(), "jotl db up") {
+			t.Errorf("Open = %v, want a pending migrations error", err)
+		}
+		if applied, _, _ := db.SchemaVersion(context.Background()); applied != latest-1 {
+			t.Errorf("schema version = %d after Open, want %d", applied, latest-1)
+		}
+	})
+
+	t.Run("newer", func(t *testing.T) {
+		if err := checkSchema(8, 7); err == nil || !strings.Contains(err.Error(), "newer") {
+			t.Errorf("checkSchema(8, 7) = %v, want a newer schema error", err)
+		}
+	})
+}
+
+func TestConnectInvalidURL(t *testing.T) {
+	for _, u := range []string{"localhost:8080", "ftp://localhost", "http://[::1"} {
+		if _, err := Connect(u, nil); err == nil {
+			t.Errorf("Connect(%q) succeeded, want an error", u)
+		}
+	}
+}
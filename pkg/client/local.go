@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/ebarthur/jotl/cmd/utils"
+)
+
+// Open connects to the database of the project whose config.yaml is at
+// configPath, which defaults to jotl/config.yaml in the current directory.
+// Both SQLite and Postgres projects are supported.
+//
+// The client only reads: it returns an error rather than migrating a
+// database whose schema doesn't match this version of Jotl.
+func Open(configPath string) (*Client, error) {
+	if configPath == "" {
+		configPath = utils.GetConfigPaths(".").ConfigFile
+	}
+	resolved, err := config.Resolve(config.DefaultLayers(configPath))
+	if err != nil {
+		return nil, err
+	}
+	cfg := resolved.Config
+
+	db, err := store.Open(cfg.Database.Path, filepath.Dir(configPath))
+	if err != nil {
+		return nil, err
+	}
+	applied, latest, err := db.SchemaVersion(context.Background())
+	if err == nil {
+		err = checkSchema(applied, latest)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Client{backend: &local{db: db, pollInterval: DefaultPollInterval}}, nil
+}
+
+// checkSchema reports whether a database at schema version applied can be
+// read by a build that knows latest versions.
+func checkSchema(applied, latest int) error {
+	switch {
+	case applied == 0:
+		return errors.New("the database has no Jotl schema yet; run `jotl dev` or `jotl db up` first")
+	case applied < latest:
+		return fmt.Errorf("the database is at schema version %d of %d; run `jotl db up` to migrate it", applied, latest)
+	case applied > latest:
+		return fmt.Errorf("the database is at schema version %d, newer than the %d this client knows; upgrade github.com/ebarthur/jotl", applied, latest)
+	}
+	return nil
+}
+
+// local reads straight from a store.
+type local struct {
+	db           store.Store
+	pollInterval time.Duration
+}
+
+func (l *local) query(ctx context.Context, f store.Filter) ([]Entry, error) {
+	var entries []Entry
+	err := l.db.Each(ctx, f, func(e store.Entry) error {
+		entries = append(entries, fromStoreEntry(e))
+		return nil
+	})
+	return entries, err
+}
+
+// tail polls the store for entries newer than the last one sent.
+func (l *local) tail(ctx context.Context, f store.Filter, out chan<- Entry) error {
+	if f.AfterID == 0 {
+		latest, err := l.db.Query(ctx, store.Filter{Limit: 1})
+		if err != nil {
+			return err
+		}
+		if len(latest) > 0 {
+			f.AfterID = latest[0].ID
+		}
+	}
+	f.Oldest = true
+
+	ticker := time.NewTicker(l.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		entries, err := l.db.Query(ctx, f)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			select {
+			case out <- fromStoreEntry(e):
+				f.AfterID = e.ID
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if len(entries) == f.Limit {
+			// A full batch; there may be more waiting.
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *local) aggregate(ctx context.Context, f store.Filter, by string) ([]Count, error) {
+	counts, err := l.db.Aggregate(ctx, f, by)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Count, len(counts))
+	for i, c := range counts {
+		out[i] = Count(c)
+	}
+	return out, nil
+}
+
+func (l *local) histogram(ctx context.Context, f store.Filter, interval time.Duration) ([]Bucket, error) {
+	buckets, err := l.db.Histogram(ctx, f, interval)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Bucket, len(buckets))
+	for i, b := range buckets {
+		out[i] = Bucket(b)
+	}
+	return out, nil
+}
+
+func (l *local) errorGroups(ctx context.Context, f store.Filter) ([]ErrorGroup, error) {
+	groups, err := l.db.ErrorGroups(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ErrorGroup, len(groups))
+	for i, g := range groups {
+		out[i] = ErrorGroup(g)
+	}
+	return out, nil
+}
+
+func (l *local) close() error {
+	return l.db.Close()
+}
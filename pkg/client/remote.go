@@ -0,0 +1,146 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+// Connect returns a client for the API of a running `jotl studio`, e.g.
+// "http://localhost:8080". If httpClient is nil, http.DefaultClient is used.
+func Connect(baseURL string, httpClient *http.Client) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid studio URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid studio URL %q: scheme must be http or https", baseURL)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{backend: &remote{base: u, http: httpClient}}, nil
+}
+
+// remote reads through the studio API.
+type remote struct {
+	base *url.URL
+	http *http.Client
+}
+
+func (r *remote) get(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	u := *r.base
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return nil, fmt.Errorf("studio %s: %s (%s)", path, resp.Status, body.Error)
+	}
+	return resp, nil
+}
+
+func (r *remote) getJSON(ctx context.Context, path string, query url.Values, v any) error {
+	resp, err := r.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (r *remote) query(ctx context.Context, f store.Filter) ([]Entry, error) {
+	var entries []Entry
+	err := r.getJSON(ctx, "/api/logs", f.Values(), &entries)
+	return entries, err
+}
+
+// tail reads the server-sent events of /api/tail.
+func (r *remote) tail(ctx context.Context, f store.Filter, out chan<- Entry) error {
+	resp, err := r.get(ctx, "/api/tail", f.Values())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 10<<20)
+
+	var event string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data := strings.TrimPrefix(line, "data: ")
+			if event == "error" {
+				var msg string
+				json.Unmarshal([]byte(data), &msg)
+				return fmt.Errorf("studio: %s", msg)
+			}
+
+			var e Entry
+			if err := json.Unmarshal([]byte(data), &e); err != nil {
+				return fmt.Errorf("invalid tail event: %w", err)
+			}
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case line == "":
+			event = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("studio closed the tail stream")
+}
+
+func (r *remote) aggregate(ctx context.Context, f store.Filter, by string) ([]Count, error) {
+	query := f.Values()
+	query.Set("by", by)
+	var counts []Count
+	err := r.getJSON(ctx, "/api/aggregates", query, &counts)
+	return counts, err
+}
+
+func (r *remote) histogram(ctx context.Context, f store.Filter, interval time.Duration) ([]Bucket, error) {
+	query := f.Values()
+	query.Set("by", "time")
+	query.Set("interval", interval.String())
+	var buckets []Bucket
+	err := r.getJSON(ctx, "/api/aggregates", query, &buckets)
+	return buckets, err
+}
+
+func (r *remote) errorGroups(ctx context.Context, f store.Filter) ([]ErrorGroup, error) {
+	var groups []ErrorGroup
+	err := r.getJSON(ctx, "/api/errors", f.Values(), &groups)
+	return groups, err
+}
+
+func (r *remote) close() error {
+	return nil
+}
@@ -0,0 +1,383 @@
+// Package jotlslog provides a log/slog Handler that writes records straight
+// into a Jotl project's database, using the same schema as `jotl dev`.
+//
+//	h, err := jotlslog.New(nil)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer h.Close()
+//	slog.SetDefault(slog.New(h))
+package jotlslog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/flags"
+	"github.com/ebarthur/jotl/cmd/ingest"
+	"github.com/ebarthur/jotl/cmd/store"
+	"github.com/ebarthur/jotl/cmd/utils"
+)
+
+// Options configures a Handler. The zero value reads jotl/config.yaml from
+// the current directory.
+type Options struct {
+	// ConfigPath is the project's config.yaml. Defaults to jotl/config.yaml
+	// in the current working directory.
+	ConfigPath string
+	// Level overrides Logging.Level from the config file.
+	Level slog.Leveler
+	// Env is recorded on every entry. Defaults to "development".
+	Env string
+	// Service is recorded on every entry. Defaults to the project name.
+	Service string
+	// AddSource records the file and line of the log call as a field.
+	AddSource bool
+}
+
+// Handler is a slog.Handler backed by a Jotl store. Records are written
+// asynchronously in batches; call Close to flush them before exiting.
+type Handler struct {
+	core   *core
+	opts   Options
+	level  slog.Leveler
+	groups []groupOrAttrs
+}
+
+// groupOrAttrs is either a group opened with WithGroup or attributes added
+// with WithAttrs, in the order they were applied.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// core is shared between a Handler and every handler derived from it.
+// entries is never closed, so Handle can't send on a closed channel; quit
+// tells the writer to finish instead, and stopped is closed once it has.
+type core struct {
+	once    sync.Once
+	entries chan store.Entry
+	quit    chan struct{}
+	stopped chan struct{}
+	err     error // set by the writer before stopped is closed
+	db      store.Store
+}
+
+// errClosed is returned for records logged after Close.
+var errClosed = errors.New("jotlslog: handler is closed")
+
+// New opens the project database, applies pending migrations and starts the
+// background writer.
+func New(opts *Options) (*Handler, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.ConfigPath == "" {
+		o.ConfigPath = utils.GetConfigPaths(".").ConfigFile
+	}
+	resolved, err := config.Resolve(config.DefaultLayers(o.ConfigPath))
+	if err != nil {
+		return nil, err
+	}
+	cfg := resolved.Config
+
+	if o.Env == "" {
+		o.Env = "development"
+	}
+	if o.Service == "" {
+		o.Service = cfg.Project.Name
+	}
+
+	level := o.Level
+	if level == nil {
+		level = slogLevel(flags.LogLevel(cfg.Logging.Level))
+	}
+
+	db, err := store.Open(cfg.Database.Path, filepath.Dir(o.ConfigPath))
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	c := &core{
+		entries: make(chan store.Entry, 1024),
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		db:      db,
+	}
+	pipeline := &ingest.Pipeline{Store: db, Env: o.Env}
+	go func() {
+		defer close(c.stopped)
+		c.err = pipeline.Run(context.Background(), &channelSource{entries: c.entries, quit: c.quit})
+	}()
+
+	return &Handler{core: c, opts: o, level: level}, nil
+}
+
+// Close flushes buffered records and closes the database. Records logged
+// after Close are dropped. It returns the error that stopped the writer, if
+// any.
+func (h *Handler) Close() error {
+	c := h.core
+	var err error
+	c.once.Do(func() {
+		close(c.quit)
+		<-c.stopped
+		err = errors.Join(c.err, c.db.Close())
+	})
+	return err
+}
+
+// Enabled reports whether records at level are stored.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle converts r into an entry and queues it for writing. Once the
+// writer has stopped on a database error, records are dropped and Handle
+// returns that error instead of waiting for room in the buffer.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	entry := store.Entry{
+		Timestamp: r.Time,
+		Level:     jotlLevel(r.Level),
+		Message:   r.Message,
+		Source:    "slog",
+		Service:   h.opts.Service,
+		Fields:    map[string]any{},
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	// Walk the groups and attributes added through With*, nesting each
+	// group's attributes (and finally the record's own) under it.
+	fields := entry.Fields
+	groups := h.groups
+	// Trailing groups without attributes are dropped if the record has none.
+	if r.NumAttrs() == 0 {
+		for len(groups) > 0 && groups[len(groups)-1].group != "" {
+			groups = groups[:len(groups)-1]
+		}
+	}
+	for _, g := range groups {
+		if g.group != "" {
+			nested := map[string]any{}
+			fields[g.group] = nested
+			fields = nested
+			continue
+		}
+		for _, a := range g.attrs {
+			addAttr(fields, a)
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(fields, a)
+		return true
+	})
+
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		entry.Fields[slog.SourceKey] = map[string]any{"function": frame.Function, "file": frame.File, "line": frame.Line}
+	}
+	promoteFields(&entry)
+
+	c := h.core
+	select {
+	case <-c.quit:
+		return errClosed
+	case <-c.stopped:
+		return c.stopErr()
+	default:
+	}
+	select {
+	case c.entries <- entry:
+		return nil
+	case <-c.quit:
+		return errClosed
+	case <-c.stopped:
+		return c.stopErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopErr explains why a record was dropped after the writer stopped.
+func (c *core) stopErr() error {
+	if c.err != nil {
+		return fmt.Errorf("jotlslog: writer stopped, record dropped: %w", c.err)
+	}
+	return errClosed
+}
+
+// WithAttrs returns a handler that adds attrs to every record.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup returns a handler that nests subsequent attributes under name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *Handler) with(g groupOrAttrs) *Handler {
+	h2 := *h
+	h2.groups = append(append([]groupOrAttrs{}, h.groups...), g)
+	return &h2
+}
+
+// addAttr stores a resolved attribute in fields, expanding groups into
+// nested maps. Empty attributes are ignored, as slog handlers should.
+func addAttr(fields map[string]any, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return
+		}
+		target := fields
+		if a.Key != "" {
+			target = map[string]any{}
+			fields[a.Key] = target
+		}
+		for _, ga := range attrs {
+			addAttr(target, ga)
+		}
+		return
+	}
+
+	fields[a.Key] = attrValue(a.Value)
+}
+
+func attrValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+	}
+	return v.Any()
+}
+
+// promoteFields moves top-level attributes that have dedicated columns
+// (status, trace_id, span_id) out of the fields.
+func promoteFields(e *store.Entry) {
+	if v, ok := e.Fields["status"]; ok {
+		switch n := v.(type) {
+		case int64:
+			e.StatusCode = int(n)
+			delete(e.Fields, "status")
+		case uint64:
+			e.StatusCode = int(n)
+			delete(e.Fields, "status")
+		}
+	}
+	if v, ok := e.Fields["trace_id"].(string); ok {
+		e.TraceID = v
+		delete(e.Fields, "trace_id")
+	}
+	if v, ok := e.Fields["span_id"].(string); ok {
+		e.SpanID = v
+		delete(e.Fields, "span_id")
+	}
+}
+
+// slogLevel maps a Jotl level onto the equivalent slog level.
+func slogLevel(level flags.LogLevel) slog.Level {
+	switch level {
+	case flags.Debug:
+		return slog.LevelDebug
+	case flags.Warn:
+		return slog.LevelWarn
+	case flags.Error:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// jotlLevel maps a slog level onto the Jotl level it falls within.
+func jotlLevel(level slog.Level) flags.LogLevel {
+	switch {
+	case level >= slog.LevelError:
+		return flags.Error
+	case level >= slog.LevelWarn:
+		return flags.Warn
+	case level >= slog.LevelInfo:
+		return flags.Info
+	default:
+		return flags.Debug
+	}
+}
+
+// channelSource feeds entries queued by the handler into the ingest
+// pipeline, so they are batched exactly like `jotl dev` input.
+type channelSource struct {
+	entries <-chan store.Entry
+	quit    <-chan struct{}
+}
+
+func (s *channelSource) Name() string {
+	return "slog"
+}
+
+// Run forwards entries until the handler is closed, then forwards the ones
+// still buffered and returns.
+func (s *channelSource) Run(ctx context.Context, out chan<- ingest.Event) error {
+	for {
+		var entry store.Entry
+		select {
+		case entry = <-s.entries:
+		case <-s.quit:
+			for {
+				select {
+				case entry = <-s.entries:
+					if err := s.send(ctx, out, entry); err != nil {
+						return err
+					}
+				default:
+					return nil
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := s.send(ctx, out, entry); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *channelSource) send(ctx context.Context, out chan<- ingest.Event, entry store.Entry) error {
+	select {
+	case out <- ingest.Event{Entry: &entry}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
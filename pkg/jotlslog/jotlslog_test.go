@@ -0,0 +1,199 @@
+package jotlslog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/ebarthur/jotl/cmd/config"
+	"github.com/ebarthur/jotl/cmd/store"
+)
+
+// newProject writes a project with a SQLite database to a temporary
+// directory and returns the path of its config file.
+func newProject(t *testing.T, level string) string {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "jotl", "config.yaml")
+	if err := config.NewConfig("shop", level, "jotl.db").SaveConfig(configPath); err != nil {
+		t.Fatal(err)
+	}
+	return configPath
+}
+
+// stored returns the entries in the project's database, oldest first.
+func stored(t *testing.T, configPath string) []store.Entry {
+	t.Helper()
+	db, err := store.Open("jotl.db", filepath.Dir(configPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	entries, err := db.Query(context.Background(), store.Filter{Oldest: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestSlogtest(t *testing.T) {
+	configPath := newProject(t, "info")
+	h, err := New(&Options{ConfigPath: configPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = slogtest.TestHandler(h, func() []map[string]any {
+		if err := h.Close(); err != nil {
+			t.Fatal(err)
+		}
+		var results []map[string]any
+		for _, e := range stored(t, configPath) {
+			m := map[string]any{
+				slog.TimeKey:    e.Timestamp,
+				slog.LevelKey:   e.Level,
+				slog.MessageKey: e.Message,
+			}
+			for k, v := range e.Fields {
+				m[k] = v
+			}
+			results = append(results, m)
+		}
+		return results
+	})
+
+	// Every entry has a timestamp: records without one are stored with the
+	// time they were handled.
+	var errs []error
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			if !strings.Contains(err.Error(), "zero Record.Time") {
+				errs = append(errs, err)
+			}
+		}
+	} else if err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHandlerStoresEntries(t *testing.T) {
+	configPath := newProject(t, "warn")
+	h, err := New(&Options{ConfigPath: configPath, Env: "test", AddSource: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(h).With("request", "r-1")
+	logger.Info("not stored below the configured level")
+	logger.Error("payment failed",
+		slog.Int("status", 502),
+		slog.String("trace_id", "4bf92f3577b34da6"),
+		slog.String("span_id", "00f067aa0ba902b7"),
+		slog.Duration("took", 1500*time.Millisecond),
+		slog.Any("err", errors.New("card declined")),
+	)
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := stored(t, configPath)
+	if len(entries) != 1 {
+		t.Fatalf("stored %d entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Level != "error" || e.Message != "payment failed" || e.Source != "slog" || e.Env != "test" || e.Service != "shop" {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.StatusCode != 502 || e.TraceID != "4bf92f3577b34da6" || e.SpanID != "00f067aa0ba902b7" {
+		t.Errorf("status, trace, span = %d, %q, %q", e.StatusCode, e.TraceID, e.SpanID)
+	}
+	if e.Fields["request"] != "r-1" || e.Fields["took"] != "1.5s" || e.Fields["err"] != "card declined" {
+		t.Errorf("fields = %v", e.Fields)
+	}
+	if src, _ := e.Fields[slog.SourceKey].(map[string]any); !strings.HasSuffix(src["file"].(string), "jotlslog_test.go") {
+		t.Errorf("source = %v", e.Fields[slog.SourceKey])
+	}
+}
+
+func TestPromoteFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		fields     map[string]any
+		want       store.Entry
+		wantFields map[string]any
+	}{
+		{
+			name:       "int status",
+			fields:     map[string]any{"status": int64(404), "path": "/"},
+			want:       store.Entry{StatusCode: 404},
+			wantFields: map[string]any{"path": "/"},
+		},
+		{
+			name:       "uint status",
+			fields:     map[string]any{"status": uint64(200)},
+			want:       store.Entry{StatusCode: 200},
+			wantFields: map[string]any{},
+		},
+		{
+			name:       "non-numeric status is kept",
+			fields:     map[string]any{"status": "ok"},
+			wantFields: map[string]any{"status": "ok"},
+		},
+		{
+			name:       "trace and span",
+			fields:     map[string]any{"trace_id": "abc", "span_id": "def"},
+			want:       store.Entry{TraceID: "abc", SpanID: "def"},
+			wantFields: map[string]any{},
+		},
+		{
+			name:       "non-string ids are kept",
+			fields:     map[string]any{"trace_id": int64(1), "span_id": true},
+			wantFields: map[string]any{"trace_id": int64(1), "span_id": true},
+		},
+		{
+			name:       "nested keys are not promoted",
+			fields:     map[string]any{"http": map[string]any{"status": int64(500)}},
+			wantFields: map[string]any{"http": map[string]any{"status": int64(500)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := store.Entry{Fields: tt.fields}
+			promoteFields(&e)
+			tt.want.Fields = tt.wantFields
+			if !reflect.DeepEqual(e, tt.want) {
+				t.Errorf("entry = %+v, want %+v", e, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleAfterClose(t *testing.T) {
+	h, err := New(&Options{ConfigPath: newProject(t, "info")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	derived := h.WithAttrs([]slog.Attr{slog.String("k", "v")}).WithGroup("g")
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "late", 0)
+	for name, handler := range map[string]slog.Handler{"handler": h, "derived": derived} {
+		if err := handler.Handle(context.Background(), r); !errors.Is(err, errClosed) {
+			t.Errorf("%s: Handle after Close = %v, want %v", name, err, errClosed)
+		}
+	}
+}